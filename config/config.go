@@ -7,6 +7,48 @@ import (
 	"github.com/spf13/viper"
 )
 
+// JWTKeyConfig JWT 签名密钥配置
+type JWTKeyConfig struct {
+	Kid            string `mapstructure:"kid"`
+	Alg            string `mapstructure:"alg"` // HS256、RS256 或 EdDSA
+	Secret         string `mapstructure:"secret"`
+	SecretEnv      string `mapstructure:"secret_env"` // 从该环境变量读取 secret，优先于 secret
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+// RateLimitPolicyConfig 限流策略配置
+type RateLimitPolicyConfig struct {
+	Limit         int    `mapstructure:"limit"`          // 每个周期补充的令牌数
+	PeriodSeconds int    `mapstructure:"period_seconds"` // 补充周期（秒）
+	Burst         int    `mapstructure:"burst"`          // 桶容量，为空时等于 limit
+	KeyBy         string `mapstructure:"key_by"`         // user 或 ip，默认 user
+}
+
+// OAuthProviderConfig 第三方登录配置
+type OAuthProviderConfig struct {
+	Type         string   `mapstructure:"type"` // oidc、github 或 oauth2
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	Scopes       []string `mapstructure:"scopes"`
+	RedirectURL  string   `mapstructure:"redirect_url"` // 为空时使用 oauth.redirect_base_url/{名称}
+
+	Issuer string `mapstructure:"issuer"` // oidc
+
+	BaseURL string `mapstructure:"base_url"` // github，为空时使用 github.com
+	APIURL  string `mapstructure:"api_url"`  // github，为空时使用 api.github.com
+
+	// oauth2 端点和用户信息字段映射
+	AuthURL            string `mapstructure:"auth_url"`
+	TokenURL           string `mapstructure:"token_url"`
+	UserInfoURL        string `mapstructure:"userinfo_url"`
+	SubjectField       string `mapstructure:"subject_field"`
+	EmailField         string `mapstructure:"email_field"`
+	EmailVerifiedField string `mapstructure:"email_verified_field"`
+	NameField          string `mapstructure:"name_field"`
+	AvatarField        string `mapstructure:"avatar_field"`
+}
+
 type Config struct {
 	App struct {
 		Name string `mapstructure:"name"`
@@ -14,9 +56,16 @@ type Config struct {
 	} `mapstructure:"app"`
 	
 	Wechat struct {
-		AppID     string `mapstructure:"app_id"`
-		AppSecret string `mapstructure:"app_secret"`
+		AppID           string `mapstructure:"app_id"`
+		AppSecret       string `mapstructure:"app_secret"`
+		SessionURL      string `mapstructure:"session_url"`       // 为空时使用微信官方 code2session 地址
+		WatermarkMaxAge int    `mapstructure:"watermark_max_age"` // 加密数据水印有效期（秒），0 表示不校验时间戳
 	} `mapstructure:"wechat"`
+
+	OAuth struct {
+		RedirectBaseURL string                         `mapstructure:"redirect_base_url"` // 前端回调页地址前缀
+		Providers       map[string]OAuthProviderConfig `mapstructure:"providers"`
+	} `mapstructure:"oauth"`
 	
 	Database struct {
 		Dsn           string `mapstructure:"dsn"`
@@ -35,6 +84,88 @@ type Config struct {
 		BucketName string `mapstructure:"bucket_name"`
 		UseSSL     bool   `mapstructure:"use_ssl"`
 	} `mapstructure:"minio"`
+	Moderation struct {
+		Provider      string  `mapstructure:"provider"`       // noop 或 http
+		Endpoint      string  `mapstructure:"endpoint"`       // 审核服务地址
+		Timeout       int     `mapstructure:"timeout"`        // 请求超时（秒）
+		HoldThreshold float64 `mapstructure:"hold_threshold"` // 最高得分达到该值时转人工复核
+		HideThreshold float64 `mapstructure:"hide_threshold"` // 最高得分达到该值时直接隐藏
+	} `mapstructure:"moderation"`
+	JWT struct {
+		Issuer          string         `mapstructure:"issuer"`
+		AccessTokenTTL  int            `mapstructure:"access_token_ttl"`  // 访问令牌有效期（分钟）
+		RefreshTokenTTL int            `mapstructure:"refresh_token_ttl"` // 刷新令牌有效期（小时）
+		AdminTokenTTL   int            `mapstructure:"admin_token_ttl"`   // 管理员令牌有效期（分钟）
+		ActiveKid       string         `mapstructure:"active_kid"`        // 当前签发使用的密钥
+		Keys            []JWTKeyConfig `mapstructure:"keys"`              // 全部密钥，轮换后旧密钥保留用于验证
+	} `mapstructure:"jwt"`
+	LoginGuard struct {
+		WindowMinutes      int `mapstructure:"window_minutes"`        // 失败次数统计窗口（分钟）
+		MaxFailuresPerUser int `mapstructure:"max_failures_per_user"` // 窗口内同一用户名允许的失败次数
+		MaxFailuresPerIP   int `mapstructure:"max_failures_per_ip"`   // 窗口内同一IP允许的失败次数
+		BaseLockoutSeconds int `mapstructure:"base_lockout_seconds"`  // 首次锁定时长（秒），之后每次翻倍
+		MaxLockoutSeconds  int `mapstructure:"max_lockout_seconds"`   // 锁定时长上限（秒）
+		CaptchaAfter       int `mapstructure:"captcha_after"`         // 失败达到该次数后要求验证码，0 表示不启用
+		RegisterPerHour    int `mapstructure:"register_per_hour"`     // 同一IP每小时允许的注册次数，0 表示不限制
+	} `mapstructure:"login_guard"`
+	Captcha struct {
+		Provider string `mapstructure:"provider"` // none 或 http
+		Endpoint string `mapstructure:"endpoint"` // siteverify 地址
+		Secret   string `mapstructure:"secret"`
+		Timeout  int    `mapstructure:"timeout"` // 请求超时（秒）
+	} `mapstructure:"captcha"`
+	Mail struct {
+		Provider  string `mapstructure:"provider"` // smtp、file 或 log
+		Host      string `mapstructure:"host"`
+		Port      int    `mapstructure:"port"`
+		Username  string `mapstructure:"username"`
+		Password  string `mapstructure:"password"`
+		From      string `mapstructure:"from"`
+		OutboxDir string `mapstructure:"outbox_dir"` // file 模式下邮件写入的目录
+	} `mapstructure:"mail"`
+	Account struct {
+		BaseURL              string `mapstructure:"base_url"`               // 邮件中链接指向的前端地址
+		RequireVerifiedEmail bool   `mapstructure:"require_verified_email"` // 邮箱验证后才能登录
+		VerifyTokenTTL       int    `mapstructure:"verify_token_ttl"`       // 邮箱验证链接有效期（小时）
+		ResetTokenTTL        int    `mapstructure:"reset_token_ttl"`        // 找回密码链接有效期（分钟）
+
+		DeletionGraceDays     int    `mapstructure:"deletion_grace_days"`     // 申请注销后的冷静期（天），期间可撤销
+		DeletionContentPolicy string `mapstructure:"deletion_content_policy"` // anonymize 保留内容显示为已注销用户，purge 一并删除
+		ExportTTL             int    `mapstructure:"export_ttl"`              // 数据导出文件保留时间（小时）
+		WorkerIntervalMinutes int    `mapstructure:"worker_interval_minutes"` // 注销和导出清理任务的执行间隔（分钟），0 表示不启用
+	} `mapstructure:"account"`
+	Profile struct {
+		NicknameMinLength  int  `mapstructure:"nickname_min_length"`
+		NicknameMaxLength  int  `mapstructure:"nickname_max_length"`
+		NicknameUnique     bool `mapstructure:"nickname_unique"`      // 昵称是否全站唯一
+		NicknameChanges    int  `mapstructure:"nickname_changes"`     // 窗口内允许修改昵称的次数，0 表示不限制
+		NicknameWindowDays int  `mapstructure:"nickname_window_days"` // 昵称修改次数统计窗口（天）
+		EmailChanges       int  `mapstructure:"email_changes"`        // 窗口内允许修改邮箱的次数，0 表示不限制
+		EmailWindowDays    int  `mapstructure:"email_window_days"`    // 邮箱修改次数统计窗口（天）
+	} `mapstructure:"profile"`
+	TwoFactor struct {
+		Issuer          string `mapstructure:"issuer"`            // 验证器应用中显示的名称
+		ChallengeTTL    int    `mapstructure:"challenge_ttl"`     // 登录第二步的有效期（秒）
+		RequireForAdmin bool   `mapstructure:"require_for_admin"` // 管理员登录是否必须两步验证
+		AdminSecret     string `mapstructure:"admin_secret"`      // 管理员 TOTP 密钥（base32）
+	} `mapstructure:"two_factor"`
+	RateLimit struct {
+		Enabled  bool                             `mapstructure:"enabled"`
+		Policies map[string]RateLimitPolicyConfig `mapstructure:"policies"` // 按名称配置，路由中引用
+	} `mapstructure:"rate_limit"`
+	Trash struct {
+		RetentionDays      int `mapstructure:"retention_days"`       // 回收站保留天数，超过后彻底删除
+		PurgeIntervalHours int `mapstructure:"purge_interval_hours"` // 定时清理间隔（小时），0 表示不启用
+	} `mapstructure:"trash"`
+	Stream struct {
+		PubSubChannel    string   `mapstructure:"pubsub_channel"`    // 多实例间转发事件的 Redis 频道
+		HistoryKey       string   `mapstructure:"history_key"`       // 保存最近事件的 Redis Stream，用于断线续传
+		HistorySize      int64    `mapstructure:"history_size"`      // 最多保留的事件数
+		HeartbeatSeconds int      `mapstructure:"heartbeat_seconds"` // 心跳间隔（秒）
+		MaxArticles      int      `mapstructure:"max_articles"`      // 每个连接最多同时订阅的文章数
+		ResumeLimit      int      `mapstructure:"resume_limit"`      // 重连时最多补发的事件数
+		AllowedOrigins   []string `mapstructure:"allowed_origins"`   // 允许建立 WebSocket 连接的页面来源，为空时只允许同源
+	} `mapstructure:"stream"`
 }
 
 var AppConfig *Config
@@ -57,6 +188,11 @@ func InitConfig() {
 	log.Println("配置文件加载成功")
 	initDB()
 	InitRedis()
+	initJWT()
+	initLoginGuard()
+	initRateLimit()
+	initMailer()
+	initOAuth()
 }
 
 // 在包外使用时添加打印示例
@@ -28,7 +28,18 @@ func initDB() {
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
 	// 执行自动迁移
-	err = db.AutoMigrate(
+	err = AutoMigrate(db)
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	global.Db = db
+
+}
+
+// AutoMigrate 创建或更新所有模型对应的表
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(
 		&models.User{},
 		&models.UserFollow{},
 		&models.Article{},
@@ -39,11 +50,28 @@ func initDB() {
 		&models.ArticlePicture{},
 		&models.Favorite{},
 		&models.CommentLike{},
+		&models.ModerationRecord{},
+		&models.UserActivity{},
+		&models.TagFollow{},
+		&models.Announcement{},
+		&models.AnnouncementRead{},
+		&models.UserSession{},
+		&models.LoginAttempt{},
+		&models.ProfileChange{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.DataExport{},
+		&models.FollowRequest{},
+		&models.UserBlock{},
+		&models.UserMute{},
+		&models.SuggestionDismissal{},
+		&models.Notification{},
+		&models.NotificationActor{},
+		&models.NotificationPreference{},
+		&models.Conversation{},
+		&models.ConversationMember{},
+		&models.Message{},
+		&models.MessageDeletion{},
+		&models.Mention{},
 	)
-	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
-	}
-
-	global.Db = db
-
 }
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/appabin/greenbook/utils"
+)
+
+// jwtPlaceholderSecret 早期示例配置中的占位密钥，已经公开，不能用于签名
+const jwtPlaceholderSecret = "change-me-to-a-random-secret-of-32-bytes-or-more"
+
+func initJWT() {
+	keys := make([]utils.JWTKeyConfig, 0, len(AppConfig.JWT.Keys))
+	for _, key := range AppConfig.JWT.Keys {
+		secret := key.Secret
+		if key.SecretEnv != "" {
+			if value := os.Getenv(key.SecretEnv); value != "" {
+				secret = value
+			}
+		}
+		if key.Alg == "HS256" {
+			if secret == jwtPlaceholderSecret {
+				log.Fatalf("JWT key %s uses the placeholder secret, set a random secret via %s", key.Kid, key.SecretEnv)
+			}
+			if len(secret) < 32 {
+				log.Fatalf("JWT key %s: HS256 secret must be at least 32 bytes, set it via %s", key.Kid, key.SecretEnv)
+			}
+		}
+		keys = append(keys, utils.JWTKeyConfig{
+			Kid:            key.Kid,
+			Alg:            key.Alg,
+			Secret:         secret,
+			PrivateKeyFile: key.PrivateKeyFile,
+			PublicKeyFile:  key.PublicKeyFile,
+		})
+	}
+
+	err := utils.InitJWT(utils.JWTSettings{
+		Issuer:          AppConfig.JWT.Issuer,
+		AccessTokenTTL:  time.Duration(AppConfig.JWT.AccessTokenTTL) * time.Minute,
+		RefreshTokenTTL: time.Duration(AppConfig.JWT.RefreshTokenTTL) * time.Hour,
+		AdminTokenTTL:   time.Duration(AppConfig.JWT.AdminTokenTTL) * time.Minute,
+		ActiveKid:       AppConfig.JWT.ActiveKid,
+		Keys:            keys,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"log"
+	"time"
+
+	"github.com/appabin/greenbook/utils"
+)
+
+func initLoginGuard() {
+	cfg := AppConfig.LoginGuard
+	if cfg.WindowMinutes > 0 {
+		utils.LoginGuard.Window = time.Duration(cfg.WindowMinutes) * time.Minute
+	}
+	if cfg.MaxFailuresPerUser > 0 {
+		utils.LoginGuard.MaxFailuresPerUser = cfg.MaxFailuresPerUser
+	}
+	if cfg.MaxFailuresPerIP > 0 {
+		utils.LoginGuard.MaxFailuresPerIP = cfg.MaxFailuresPerIP
+	}
+	if cfg.BaseLockoutSeconds > 0 {
+		utils.LoginGuard.BaseLockout = time.Duration(cfg.BaseLockoutSeconds) * time.Second
+	}
+	if cfg.MaxLockoutSeconds > 0 {
+		utils.LoginGuard.MaxLockout = time.Duration(cfg.MaxLockoutSeconds) * time.Second
+	}
+	utils.LoginGuard.CaptchaAfter = cfg.CaptchaAfter
+	utils.LoginGuard.RegisterMaxPerIP = cfg.RegisterPerHour
+
+	captcha, err := utils.NewCaptchaVerifier(
+		AppConfig.Captcha.Provider,
+		AppConfig.Captcha.Endpoint,
+		AppConfig.Captcha.Secret,
+		time.Duration(AppConfig.Captcha.Timeout)*time.Second,
+	)
+	if err != nil {
+		log.Fatalf("Failed to init captcha: %v", err)
+	}
+	utils.Captcha = captcha
+}
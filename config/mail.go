@@ -0,0 +1,24 @@
+package config
+
+import (
+	"log"
+
+	"github.com/appabin/greenbook/utils"
+)
+
+func initMailer() {
+	mailer, err := utils.NewMailer(utils.MailerConfig{
+		Provider:  AppConfig.Mail.Provider,
+		Host:      AppConfig.Mail.Host,
+		Port:      AppConfig.Mail.Port,
+		Username:  AppConfig.Mail.Username,
+		Password:  AppConfig.Mail.Password,
+		From:      AppConfig.Mail.From,
+		OutboxDir: AppConfig.Mail.OutboxDir,
+	})
+	if err != nil {
+		log.Fatalf("Failed to init mailer: %v", err)
+	}
+	utils.MailSender = mailer
+	log.Printf("邮件发送方式: %s", mailer.Name())
+}
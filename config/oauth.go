@@ -0,0 +1,78 @@
+package config
+
+import (
+	"log"
+	"time"
+
+	"github.com/appabin/greenbook/utils"
+)
+
+func initOAuth() {
+	providers := map[string]utils.AuthProvider{}
+
+	if AppConfig.Wechat.AppID != "" {
+		providers["wechat"] = &utils.WeChatMiniProvider{
+			AppID:      AppConfig.Wechat.AppID,
+			AppSecret:  AppConfig.Wechat.AppSecret,
+			SessionURL: AppConfig.Wechat.SessionURL,
+
+			WatermarkMaxAge: time.Duration(AppConfig.Wechat.WatermarkMaxAge) * time.Second,
+		}
+	}
+
+	for name, cfg := range AppConfig.OAuth.Providers {
+		if name == "wechat" {
+			log.Fatalf("OAuth provider name %q is reserved", name)
+		}
+		if cfg.ClientID == "" {
+			log.Fatalf("OAuth provider %q: client_id is required", name)
+		}
+
+		switch cfg.Type {
+		case "oidc":
+			if cfg.Issuer == "" {
+				log.Fatalf("OAuth provider %q: issuer is required", name)
+			}
+			providers[name] = &utils.OIDCProvider{
+				ProviderName: name,
+				Issuer:       cfg.Issuer,
+				ClientID:     cfg.ClientID,
+				ClientSecret: cfg.ClientSecret,
+				Scopes:       cfg.Scopes,
+			}
+		case "github":
+			provider := utils.NewGitHubProvider(cfg.ClientID, cfg.ClientSecret, cfg.BaseURL, cfg.APIURL, nil)
+			provider.ProviderName = name
+			if len(cfg.Scopes) > 0 {
+				provider.Scopes = cfg.Scopes
+			}
+			providers[name] = provider
+		case "oauth2":
+			if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "" {
+				log.Fatalf("OAuth provider %q: auth_url, token_url and userinfo_url are required", name)
+			}
+			subjectField := cfg.SubjectField
+			if subjectField == "" {
+				subjectField = "id"
+			}
+			providers[name] = &utils.OAuth2Provider{
+				ProviderName:       name,
+				ClientID:           cfg.ClientID,
+				ClientSecret:       cfg.ClientSecret,
+				AuthURL:            cfg.AuthURL,
+				TokenURL:           cfg.TokenURL,
+				UserInfoURL:        cfg.UserInfoURL,
+				Scopes:             cfg.Scopes,
+				SubjectField:       subjectField,
+				EmailField:         cfg.EmailField,
+				EmailVerifiedField: cfg.EmailVerifiedField,
+				NameField:          cfg.NameField,
+				AvatarField:        cfg.AvatarField,
+			}
+		default:
+			log.Fatalf("OAuth provider %q: unknown type %q", name, cfg.Type)
+		}
+	}
+
+	utils.AuthProviders = providers
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"log"
+	"time"
+
+	"github.com/appabin/greenbook/utils"
+)
+
+func initRateLimit() {
+	policies := make(map[string]utils.RateLimitPolicy, len(AppConfig.RateLimit.Policies))
+	for name, policy := range AppConfig.RateLimit.Policies {
+		if policy.Limit <= 0 || policy.PeriodSeconds <= 0 {
+			log.Fatalf("Invalid rate limit policy %q: limit and period_seconds must be positive", name)
+		}
+		keyBy := policy.KeyBy
+		if keyBy == "" {
+			keyBy = utils.RateLimitByUser
+		}
+		if keyBy != utils.RateLimitByUser && keyBy != utils.RateLimitByIP {
+			log.Fatalf("Invalid rate limit policy %q: unknown key_by %q", name, keyBy)
+		}
+		policies[name] = utils.RateLimitPolicy{
+			Limit:  policy.Limit,
+			Period: time.Duration(policy.PeriodSeconds) * time.Second,
+			Burst:  policy.Burst,
+			KeyBy:  keyBy,
+		}
+	}
+
+	utils.RateLimitEnabled = AppConfig.RateLimit.Enabled
+	utils.RateLimitPolicies = policies
+}
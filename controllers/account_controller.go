@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// accountLink 生成邮件中指向前端页面的链接
+func accountLink(path, token string) string {
+	base := strings.TrimRight(config.AppConfig.Account.BaseURL, "/")
+	return fmt.Sprintf("%s%s?token=%s", base, path, url.QueryEscape(token))
+}
+
+// verifyTokenTTL 邮箱验证链接有效期
+func verifyTokenTTL() time.Duration {
+	if hours := config.AppConfig.Account.VerifyTokenTTL; hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return 48 * time.Hour
+}
+
+// resetTokenTTL 找回密码链接有效期
+func resetTokenTTL() time.Duration {
+	if minutes := config.AppConfig.Account.ResetTokenTTL; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+// sendMailAsync 异步发送邮件，失败只记录日志
+func sendMailAsync(mail utils.Mail) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := utils.MailSender.Send(ctx, mail); err != nil {
+			log.Printf("发送邮件失败 to=%s subject=%q: %v", mail.To, mail.Subject, err)
+		}
+	}()
+}
+
+// sendVerificationEmail 向用户邮箱发送验证链接，令牌与当前邮箱绑定
+func sendVerificationEmail(user *models.User) error {
+	if user.Email == "" {
+		return errors.New("user has no email")
+	}
+	token, err := utils.GenerateActionToken(user.ID, utils.TokenTypeEmailVerify, strings.ToLower(user.Email), verifyTokenTTL())
+	if err != nil {
+		return err
+	}
+
+	sendMailAsync(utils.Mail{
+		To:      user.Email,
+		Subject: "请验证你的邮箱",
+		Text: fmt.Sprintf("你好 %s：\n\n请点击以下链接完成邮箱验证（%d 小时内有效）：\n%s\n\n如果这不是你本人的操作，请忽略本邮件。",
+			user.Nickname, int(verifyTokenTTL().Hours()), accountLink("/verify-email", token)),
+	})
+	return nil
+}
+
+// EmailRequest 仅包含邮箱的请求
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResendVerificationEmail 重新发送验证邮件，无论邮箱是否存在都返回成功，避免泄露注册信息
+func ResendVerificationEmail(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.Where("email = ?", req.Email).First(&user).Error; err == nil && user.EmailVerifiedAt == nil {
+		if err := sendVerificationEmail(&user); err != nil {
+			log.Printf("生成邮箱验证令牌失败 user=%d: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册且未验证，验证邮件将很快送达"})
+}
+
+// TokenRequest 一次性令牌请求
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail 使用邮件中的令牌完成邮箱验证
+func VerifyEmail(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	claims, err := utils.ConsumeActionToken(req.Token, utils.TokenTypeEmailVerify)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证链接无效或已过期"})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	// 邮箱修改后旧链接失效
+	if strings.ToLower(user.Email) != claims.Binding {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证链接与当前邮箱不匹配"})
+		return
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := global.Db.Model(&user).Update("email_verified_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "邮箱验证失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "邮箱验证成功"})
+}
+
+// ForgotPassword 发送找回密码邮件，无论邮箱是否存在都返回成功
+func ForgotPassword(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.Where("email = ?", req.Email).First(&user).Error; err == nil {
+		// 令牌绑定当前密码指纹，密码修改后旧链接自动失效
+		token, err := utils.GenerateActionToken(user.ID, utils.TokenTypePasswordReset, utils.PasswordFingerprint(user.Password), resetTokenTTL())
+		if err != nil {
+			log.Printf("生成找回密码令牌失败 user=%d: %v", user.ID, err)
+		} else {
+			sendMailAsync(utils.Mail{
+				To:      user.Email,
+				Subject: "重置你的密码",
+				Text: fmt.Sprintf("你好 %s：\n\n我们收到了重置密码的请求，请点击以下链接设置新密码（%d 分钟内有效，仅可使用一次）：\n%s\n\n如果这不是你本人的操作，请忽略本邮件，你的密码不会改变。",
+					user.Nickname, int(resetTokenTTL().Minutes()), accountLink("/reset-password", token)),
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册，重置密码邮件将很快送达"})
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ResetPassword 使用邮件中的令牌重置密码，成功后吊销所有已登录会话
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	claims, err := utils.ConsumeActionToken(req.Token, utils.TokenTypePasswordReset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "重置链接无效或已过期"})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if utils.PasswordFingerprint(user.Password) != claims.Binding {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "重置链接已失效"})
+		return
+	}
+
+	hashedPassword, err := utils.HassPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码加密失败"})
+		return
+	}
+
+	// 能收到邮件说明邮箱可用，顺便标记为已验证
+	updates := map[string]interface{}{"password": hashedPassword}
+	if user.EmailVerifiedAt == nil {
+		updates["email_verified_at"] = time.Now()
+	}
+	if err := global.Db.Model(&user).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重置密码失败"})
+		return
+	}
+
+	if err := RevokeUserSessions(user.ID, ""); err != nil {
+		log.Printf("吊销登录会话失败 user=%d: %v", user.ID, err)
+	}
+	loginSucceeded(LoginScopeUser, user.Username)
+
+	c.JSON(http.StatusOK, gin.H{"message": "密码已重置，请使用新密码登录"})
+}
@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 注销后内容的处理方式
+const (
+	DeletionAnonymize = "anonymize" // 保留文章和评论，作者显示为已注销用户
+	DeletionPurge     = "purge"     // 文章、评论等全部删除
+)
+
+// deletedUserNickname 注销后保留内容的显示名称
+const deletedUserNickname = "已注销用户"
+
+// accountWorkerLockKey 注销和导出清理任务的分布式锁
+const accountWorkerLockKey = "account:worker:lock"
+
+func deletionGracePeriod() time.Duration {
+	if days := config.AppConfig.Account.DeletionGraceDays; days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return 15 * 24 * time.Hour
+}
+
+// DeleteAccountRequest 申请注销，设置了密码的账号需要密码，开启两步验证的账号还需要验证码
+type DeleteAccountRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// DeleteAccount 申请注销账号，冷静期结束后执行
+func DeleteAccount(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.First(&user, c.GetUint("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if user.DeletionScheduledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "已申请注销", "deletion_scheduled_at": user.DeletionScheduledAt})
+		return
+	}
+	if user.Password != "" && !utils.CheckPassword(req.Password, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+		return
+	}
+	if user.TwoFactorEnabled && !verifySecondFactor(&user, strings.TrimSpace(req.Code), strings.TrimSpace(req.RecoveryCode)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误", "two_factor_required": true})
+		return
+	}
+
+	scheduledAt := time.Now().Add(deletionGracePeriod())
+	if err := global.Db.Model(&user).Update("deletion_scheduled_at", scheduledAt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "申请注销失败"})
+		return
+	}
+
+	// 登录本身不会撤销注销，需要在冷静期内调用 CancelAccountDeletion（POST /api/user/me/cancel-deletion）
+	if user.Email != "" {
+		sendMailAsync(utils.Mail{
+			To:      user.Email,
+			Subject: "账号注销申请已提交",
+			Text: fmt.Sprintf("你好 %s：\n\n你的账号将于 %s 注销。在此之前，你可以登录后在「账号设置 - 注销账号」中点击「撤销注销」保留账号。\n\n如果这不是你本人的操作，请立即登录，撤销注销申请并修改密码。",
+				user.Nickname, scheduledAt.Format("2006-01-02 15:04")),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":               "已申请注销，冷静期内可随时撤销",
+		"deletion_scheduled_at": scheduledAt,
+	})
+}
+
+// CancelAccountDeletion 撤销注销申请
+func CancelAccountDeletion(c *gin.Context) {
+	result := global.Db.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", c.GetUint("userID")).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "撤销注销失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未申请注销"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已撤销注销申请"})
+}
+
+// recountFollows 按现存关注关系重新计算用户的关注数和粉丝数
+func recountFollows(tx *gorm.DB, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return tx.Exec(`UPDATE users SET
+		following_count = (SELECT COUNT(*) FROM user_follows WHERE user_follows.follower_id = users.id AND user_follows.deleted_at IS NULL),
+		followers_count = (SELECT COUNT(*) FROM user_follows WHERE user_follows.followed_id = users.id AND user_follows.deleted_at IS NULL)
+		WHERE id IN ?`, userIDs).Error
+}
+
+// deletePersonalData 删除与内容无关的个人数据，返回需要从 MinIO 删除的导出文件
+func deletePersonalData(tx *gorm.DB, user *models.User) ([]string, error) {
+	var exports []string
+	tx.Model(&models.DataExport{}).Where("user_id = ? AND object_name <> ''", user.ID).Pluck("object_name", &exports)
+
+	steps := []*gorm.DB{
+		tx.Where("user_id = ?", user.ID).Delete(&models.UserSession{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.UserIdentity{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.ProfileChange{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.AnnouncementRead{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.UserActivity{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.TagFollow{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.DataExport{}),
+		tx.Where("requester_id = ? OR target_id = ?", user.ID, user.ID).Delete(&models.FollowRequest{}),
+		tx.Where("blocker_id = ? OR blocked_id = ?", user.ID, user.ID).Delete(&models.UserBlock{}),
+		tx.Where("muter_id = ? OR muted_id = ?", user.ID, user.ID).Delete(&models.UserMute{}),
+		tx.Where("user_id = ? OR dismissed_id = ?", user.ID, user.ID).Delete(&models.SuggestionDismissal{}),
+		tx.Where("actor_id = ? OR notification_id IN (?)", user.ID, tx.Model(&models.Notification{}).Select("id").Where("user_id = ?", user.ID)).Delete(&models.NotificationActor{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.Notification{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.NotificationPreference{}),
+		tx.Where("user_id = ? OR message_id IN (?)", user.ID, tx.Model(&models.Message{}).Select("id").Where("sender_id = ?", user.ID)).Delete(&models.MessageDeletion{}),
+		tx.Where("sender_id = ?", user.ID).Delete(&models.Message{}),
+		tx.Where("user_id = ?", user.ID).Delete(&models.ConversationMember{}),
+		tx.Where("mentioned_id = ?", user.ID).Delete(&models.Mention{}),
+	}
+	if user.Username != "" {
+		steps = append(steps, tx.Where("username = ?", user.Username).Delete(&models.LoginAttempt{}))
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return nil, step.Error
+		}
+	}
+	return exports, nil
+}
+
+// anonymizeUser 清除用户的个人信息和互动记录，保留文章和评论
+func anonymizeUser(tx *gorm.DB, user *models.User) ([]string, error) {
+	// 受影响的文章、评论和用户，删除后需要重新计数
+	var touchedArticles, touchedComments, touchedUsers []uint
+	tx.Unscoped().Model(&models.Like{}).Where("user_id = ?", user.ID).Distinct().Pluck("article_id", &touchedArticles)
+	var more []uint
+	tx.Unscoped().Model(&models.Favorite{}).Where("user_id = ?", user.ID).Distinct().Pluck("article_id", &more)
+	touchedArticles = append(touchedArticles, more...)
+	tx.Unscoped().Model(&models.CommentLike{}).Where("user_id = ?", user.ID).Distinct().Pluck("comment_id", &touchedComments)
+	tx.Unscoped().Model(&models.UserFollow{}).Where("follower_id = ?", user.ID).Pluck("followed_id", &touchedUsers)
+	more = nil
+	tx.Unscoped().Model(&models.UserFollow{}).Where("followed_id = ?", user.ID).Pluck("follower_id", &more)
+	touchedUsers = append(touchedUsers, more...)
+
+	likes := likePairs(tx, &models.Like{}, "user_id = ? AND deleted_at IS NULL", user.ID)
+	favorites := likePairs(tx, &models.Favorite{}, "user_id = ? AND deleted_at IS NULL", user.ID)
+
+	// 没有被文章引用的图片（头像、背景图、未发布的图片）一并删除
+	var pictures []models.Picture
+	tx.Unscoped().Select("id, url").
+		Where("user_id = ? AND id NOT IN (?)", user.ID, tx.Model(&models.ArticlePicture{}).Select("picture_id")).
+		Find(&pictures)
+	objects := make([]string, 0, len(pictures))
+	pictureIDs := make([]uint, 0, len(pictures))
+	for _, picture := range pictures {
+		objects = append(objects, "images/"+strings.TrimPrefix(picture.URL, "/static/images/"))
+		pictureIDs = append(pictureIDs, picture.ID)
+	}
+
+	steps := []*gorm.DB{
+		tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.CommentLike{}),
+		tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Like{}),
+		tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Favorite{}),
+		tx.Unscoped().Where("follower_id = ? OR followed_id = ?", user.ID, user.ID).Delete(&models.UserFollow{}),
+	}
+	if len(pictureIDs) > 0 {
+		steps = append(steps,
+			tx.Unscoped().Where("target_type = ? AND target_id IN ?", models.ModerationTargetPicture, pictureIDs).Delete(&models.ModerationRecord{}),
+			tx.Unscoped().Where("id IN ?", pictureIDs).Delete(&models.Picture{}),
+		)
+	}
+	steps = append(steps, tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"username":              "",
+		"password":              "",
+		"nickname":              deletedUserNickname,
+		"avatar":                "",
+		"gender":                0,
+		"phone":                 nil,
+		"email":                 nil,
+		"email_verified_at":     nil,
+		"bio":                   "",
+		"birthday":              nil,
+		"location":              "",
+		"cover_image":           "",
+		"two_factor_enabled":    false,
+		"two_factor_secret":     "",
+		"two_factor_enabled_at": nil,
+		"open_id":               nil,
+		"union_id":              "",
+		"session_key":           "",
+		"following_count":       0,
+		"followers_count":       0,
+		"deletion_scheduled_at": nil,
+		"anonymized_at":         time.Now(),
+	}))
+	for _, step := range steps {
+		if step.Error != nil {
+			return nil, step.Error
+		}
+	}
+
+	syncToggleKeys("article:like:%d:%d", likes, false)
+	syncToggleKeys("article:favorite:%d:%d", favorites, false)
+
+	if err := recountComments(tx, touchedComments); err != nil {
+		return nil, err
+	}
+	if err := recountArticles(tx, touchedArticles); err != nil {
+		return nil, err
+	}
+	return objects, recountFollows(tx, touchedUsers)
+}
+
+// finalizeAccountDeletion 冷静期结束后按配置匿名化或彻底删除账号
+func finalizeAccountDeletion(userID uint) error {
+	var user models.User
+	if err := global.Db.First(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil {
+		return nil
+	}
+
+	// 先吊销全部登录会话
+	if err := RevokeUserSessions(user.ID, ""); err != nil {
+		return err
+	}
+
+	var objects []string
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		exports, err := deletePersonalData(tx, &user)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, exports...)
+
+		var content []string
+		if config.AppConfig.Account.DeletionContentPolicy == DeletionPurge {
+			var touchedUsers []uint
+			tx.Unscoped().Model(&models.UserFollow{}).Where("follower_id = ?", user.ID).Pluck("followed_id", &touchedUsers)
+			var more []uint
+			tx.Unscoped().Model(&models.UserFollow{}).Where("followed_id = ?", user.ID).Pluck("follower_id", &more)
+			touchedUsers = append(touchedUsers, more...)
+
+			if content, err = purgeUser(tx, user.ID); err != nil {
+				return err
+			}
+			if err := recountFollows(tx, touchedUsers); err != nil {
+				return err
+			}
+		} else if content, err = anonymizeUser(tx, &user); err != nil {
+			return err
+		}
+		objects = append(objects, content...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	removeObjects(objects)
+	return nil
+}
+
+// ProcessDueAccountDeletions 执行冷静期已结束的注销申请，返回处理数量
+func ProcessDueAccountDeletions() int {
+	var ids []uint
+	global.Db.Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).
+		Pluck("id", &ids)
+
+	processed := 0
+	for _, id := range ids {
+		if err := finalizeAccountDeletion(id); err != nil {
+			log.Printf("注销账号 #%d 失败: %v\n", id, err)
+			continue
+		}
+		processed++
+	}
+	return processed
+}
+
+// StartAccountWorker 启动账号注销和过期导出文件清理的定时任务
+func StartAccountWorker(interval time.Duration) {
+	// 启动时先处理服务重启前中断的导出任务，避免用户一直无法重新申请导出
+	if stale := failStaleDataExports(); stale > 0 {
+		log.Printf("将 %d 个中断的数据导出标记为失败\n", stale)
+	}
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// 多实例部署时只由抢到锁的实例执行
+			if !global.RedisDB.SetNX(accountWorkerLockKey, "1", interval/2).Val() {
+				continue
+			}
+			if processed := ProcessDueAccountDeletions(); processed > 0 {
+				log.Printf("账号注销任务完成，共处理 %d 个账号\n", processed)
+			}
+			if expired := PurgeExpiredDataExports(); expired > 0 {
+				log.Printf("清理过期数据导出 %d 个\n", expired)
+			}
+		}
+	}()
+}
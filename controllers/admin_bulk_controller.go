@@ -0,0 +1,616 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 管理后台列表的状态筛选
+const (
+	AdminStatusActive  = "active"  // 未删除
+	AdminStatusDeleted = "deleted" // 回收站中
+	AdminStatusAll     = "all"     // 全部
+)
+
+// 批量操作类型
+const (
+	BulkDelete  = "delete"
+	BulkHide    = "hide"
+	BulkRestore = "restore"
+	BulkRetag   = "retag"
+)
+
+// maxBulkIDs 单次批量操作的最大数量
+const maxBulkIDs = 500
+
+// bulkHideReviewer 批量隐藏写入审核记录时使用的复核人，批量恢复只撤销由它隐藏的文章
+const bulkHideReviewer = "admin-bulk"
+
+// applyDateRange 按 from/to 参数筛选创建时间
+func applyDateRange(c *gin.Context, query *gorm.DB, column string) (*gorm.DB, error) {
+	if value := c.Query("from"); value != "" {
+		from, err := parseStatisticsTime(value, false)
+		if err != nil {
+			return nil, fmt.Errorf("无效的开始时间")
+		}
+		query = query.Where(column+" >= ?", from)
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseStatisticsTime(value, true)
+		if err != nil {
+			return nil, fmt.Errorf("无效的结束时间")
+		}
+		query = query.Where(column+" < ?", to)
+	}
+	return query, nil
+}
+
+// applyDeletedStatus 按删除状态筛选，返回是否已处理 status 参数
+func applyDeletedStatus(query *gorm.DB, status, column string) (*gorm.DB, bool) {
+	switch status {
+	case "", AdminStatusActive:
+		return query.Where(column + " IS NULL"), true
+	case AdminStatusDeleted:
+		return query.Where(column + " IS NOT NULL"), true
+	case AdminStatusAll:
+		return query, true
+	}
+	return query, false
+}
+
+// adminUserQuery 根据筛选参数构建用户查询
+// 支持 keyword（用户名/昵称/邮箱/手机号）、from、to、status=active|deleted|all
+func adminUserQuery(c *gin.Context) (*gorm.DB, error) {
+	query := global.Db.Unscoped().Model(&models.User{})
+
+	query, ok := applyDeletedStatus(query, c.Query("status"), "users.deleted_at")
+	if !ok {
+		return nil, fmt.Errorf("无效的状态")
+	}
+
+	if keyword := c.Query("keyword"); keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("users.username LIKE ? OR users.nickname LIKE ? OR users.email LIKE ? OR users.phone LIKE ?", like, like, like, like)
+	}
+
+	return applyDateRange(c, query, "users.created_at")
+}
+
+// adminArticleQuery 根据筛选参数构建文章查询
+// 支持 keyword（标题/内容）、from、to、author_id、author（作者昵称/用户名）、tag、
+// status=active|deleted|all|allow|hold|hide
+func adminArticleQuery(c *gin.Context) (*gorm.DB, error) {
+	query := global.Db.Unscoped().Model(&models.Article{})
+
+	status := c.Query("status")
+	switch status {
+	case models.ModerationAllow, models.ModerationHold, models.ModerationHide:
+		query = query.Where("articles.deleted_at IS NULL AND articles.moderation_status = ?", status)
+	default:
+		var ok bool
+		if query, ok = applyDeletedStatus(query, status, "articles.deleted_at"); !ok {
+			return nil, fmt.Errorf("无效的状态")
+		}
+	}
+
+	if keyword := c.Query("keyword"); keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("articles.title LIKE ? OR articles.content LIKE ?", like, like)
+	}
+
+	if value := c.Query("author_id"); value != "" {
+		authorID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("无效的作者ID")
+		}
+		query = query.Where("articles.author_id = ?", authorID)
+	}
+
+	if author := c.Query("author"); author != "" {
+		like := "%" + author + "%"
+		query = query.Where("articles.author_id IN (?)",
+			global.Db.Unscoped().Model(&models.User{}).Select("id").Where("nickname LIKE ? OR username LIKE ?", like, like))
+	}
+
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Where("articles.id IN (?)",
+			global.Db.Table("article_tags").Select("article_tags.article_id").
+				Joins("JOIN tags ON tags.id = article_tags.tag_id").
+				Where("tags.name = ?", tag))
+	}
+
+	return applyDateRange(c, query, "articles.created_at")
+}
+
+// BulkActionRequest 批量操作请求
+type BulkActionRequest struct {
+	IDs     []uint   `json:"ids" binding:"required,min=1"`
+	Action  string   `json:"action" binding:"required"`
+	Tags    []string `json:"tags"`     // retag 使用的标签
+	TagMode string   `json:"tag_mode"` // retag 模式：replace（默认）、add、remove
+}
+
+// bulkFailure 批量操作中失败的单项
+type bulkFailure struct {
+	ID    uint   `json:"id"`
+	Error string `json:"error"`
+}
+
+// bindBulkRequest 解析并校验批量操作请求
+func bindBulkRequest(c *gin.Context) (*BulkActionRequest, bool) {
+	var req BulkActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return nil, false
+	}
+	if len(req.IDs) > maxBulkIDs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("单次最多操作 %d 条", maxBulkIDs)})
+		return nil, false
+	}
+	return &req, true
+}
+
+// bulkModerationRecords 为批量隐藏/恢复的文章生成人工审核记录
+func bulkModerationRecords(ids []uint, action string) []models.ModerationRecord {
+	records := make([]models.ModerationRecord, 0, len(ids))
+	for _, id := range ids {
+		records = append(records, models.ModerationRecord{
+			TargetType: models.ModerationTargetArticle,
+			TargetID:   id,
+			Provider:   "manual",
+			Action:     action,
+			ReviewerID: bulkHideReviewer,
+		})
+	}
+	return records
+}
+
+// bulkHideArticles 隐藏符合条件且正常展示的文章（包括回收站中的）并记录审核记录，
+// 待复核或已被审核隐藏的文章保持不变，避免批量恢复时连同审核结论一起撤销
+func bulkHideArticles(tx *gorm.DB, query string, args ...interface{}) error {
+	var ids []uint
+	if err := tx.Unscoped().Model(&models.Article{}).Where(query, args...).
+		Where("moderation_status = ?", models.ModerationAllow).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Unscoped().Model(&models.Article{}).Where("id IN ?", ids).
+		Update("moderation_status", models.ModerationHide).Error; err != nil {
+		return err
+	}
+	return tx.Create(bulkModerationRecords(ids, models.ModerationHide)).Error
+}
+
+// bulkUnhideArticles 撤销批量隐藏，只恢复最近一次人工结论为批量隐藏的文章，
+// 审核服务或管理员复核隐藏的文章保持不变
+func bulkUnhideArticles(tx *gorm.DB, query string, args ...interface{}) error {
+	var hidden []uint
+	if err := tx.Unscoped().Model(&models.Article{}).Where(query, args...).
+		Where("moderation_status = ?", models.ModerationHide).Pluck("id", &hidden).Error; err != nil {
+		return err
+	}
+	if len(hidden) == 0 {
+		return nil
+	}
+
+	var records []models.ModerationRecord
+	if err := tx.Where("target_type = ? AND target_id IN ? AND reviewer_id <> ''", models.ModerationTargetArticle, hidden).
+		Order("id DESC").Find(&records).Error; err != nil {
+		return err
+	}
+	seen := map[uint]bool{}
+	var ids []uint
+	for _, record := range records {
+		if seen[record.TargetID] {
+			continue
+		}
+		seen[record.TargetID] = true
+		if record.ReviewerID == bulkHideReviewer && record.Action == models.ModerationHide {
+			ids = append(ids, record.TargetID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := tx.Unscoped().Model(&models.Article{}).Where("id IN ?", ids).
+		Update("moderation_status", models.ModerationAllow).Error; err != nil {
+		return err
+	}
+	return tx.Create(bulkModerationRecords(ids, models.ModerationAllow)).Error
+}
+
+// AdminBulkArticles 批量操作文章：delete、hide、restore、retag
+func AdminBulkArticles(c *gin.Context) {
+	req, ok := bindBulkRequest(c)
+	if !ok {
+		return
+	}
+	if req.Action != BulkDelete && req.Action != BulkHide && req.Action != BulkRestore && req.Action != BulkRetag {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action 只支持 delete、hide、restore、retag"})
+		return
+	}
+
+	var tags []models.Tag
+	if req.Action == BulkRetag {
+		if req.TagMode == "" {
+			req.TagMode = "replace"
+		}
+		if req.TagMode != "replace" && req.TagMode != "add" && req.TagMode != "remove" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tag_mode 只支持 replace、add、remove"})
+			return
+		}
+		for _, tagName := range req.Tags {
+			var tag models.Tag
+			if err := global.Db.Where("name = ?", tagName).FirstOrCreate(&tag, models.Tag{Name: tagName}).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "创建标签失败"})
+				return
+			}
+			tags = append(tags, tag)
+		}
+	}
+
+	succeeded := 0
+	failed := make([]bulkFailure, 0)
+	now := deletionTime()
+	for _, id := range req.IDs {
+		var article models.Article
+		if err := global.Db.Unscoped().First(&article, id).Error; err != nil {
+			failed = append(failed, bulkFailure{ID: id, Error: "文章不存在"})
+			continue
+		}
+
+		var err error
+		switch req.Action {
+		case BulkDelete:
+			if article.DeletedAt.Valid {
+				break
+			}
+			err = global.Db.Transaction(func(tx *gorm.DB) error {
+				return softDeleteArticle(tx, article.ID, now)
+			})
+		case BulkHide:
+			err = global.Db.Transaction(func(tx *gorm.DB) error {
+				return bulkHideArticles(tx, "id = ?", article.ID)
+			})
+		case BulkRestore:
+			// 恢复已删除的文章，并撤销批量隐藏
+			err = global.Db.Transaction(func(tx *gorm.DB) error {
+				if article.DeletedAt.Valid {
+					if err := restoreArticle(tx, article); err != nil {
+						return err
+					}
+				}
+				return bulkUnhideArticles(tx, "id = ?", article.ID)
+			})
+		case BulkRetag:
+			association := global.Db.Model(&article).Association("Tags")
+			switch req.TagMode {
+			case "add":
+				err = association.Append(tags)
+			case "remove":
+				err = association.Delete(tags)
+			default:
+				err = association.Replace(tags)
+			}
+		}
+
+		if err != nil {
+			message := "操作失败"
+			if errors.Is(err, errParentDeleted) {
+				message = "作者仍在回收站中"
+			}
+			failed = append(failed, bulkFailure{ID: id, Error: message})
+			continue
+		}
+		succeeded++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":    req.Action,
+		"succeeded": succeeded,
+		"failed":    failed,
+	})
+}
+
+// AdminBulkUsers 批量操作用户：delete、hide（隐藏其全部文章）、restore
+func AdminBulkUsers(c *gin.Context) {
+	req, ok := bindBulkRequest(c)
+	if !ok {
+		return
+	}
+	if req.Action != BulkDelete && req.Action != BulkHide && req.Action != BulkRestore {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action 只支持 delete、hide、restore"})
+		return
+	}
+
+	succeeded := 0
+	failed := make([]bulkFailure, 0)
+	now := deletionTime()
+	for _, id := range req.IDs {
+		var user models.User
+		if err := global.Db.Unscoped().Select("id, deleted_at").First(&user, id).Error; err != nil {
+			failed = append(failed, bulkFailure{ID: id, Error: "用户不存在"})
+			continue
+		}
+
+		var err error
+		switch req.Action {
+		case BulkDelete:
+			if user.DeletedAt.Valid {
+				break
+			}
+			err = global.Db.Transaction(func(tx *gorm.DB) error {
+				return softDeleteUser(tx, user.ID, now)
+			})
+		case BulkHide:
+			err = global.Db.Transaction(func(tx *gorm.DB) error {
+				return bulkHideArticles(tx, "author_id = ?", user.ID)
+			})
+		case BulkRestore:
+			err = global.Db.Transaction(func(tx *gorm.DB) error {
+				if user.DeletedAt.Valid {
+					if err := restoreUser(tx, user); err != nil {
+						return err
+					}
+				}
+				return bulkUnhideArticles(tx, "author_id = ?", user.ID)
+			})
+		}
+
+		if err != nil {
+			failed = append(failed, bulkFailure{ID: id, Error: "操作失败"})
+			continue
+		}
+		succeeded++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":    req.Action,
+		"succeeded": succeeded,
+		"failed":    failed,
+	})
+}
+
+// exportWriter 以 CSV 或 JSON 数组的形式逐行输出导出数据
+type exportWriter struct {
+	c       *gin.Context
+	name    string
+	format  string
+	csv     *csv.Writer
+	rows    int
+	headers []string
+}
+
+// newExportWriter 设置响应头并写出表头
+func newExportWriter(c *gin.Context, name string, headers []string) (*exportWriter, bool) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format 只支持 csv、json"})
+		return nil, false
+	}
+
+	filename := fmt.Sprintf("%s_%s.%s", name, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	c.Status(http.StatusOK)
+
+	w := &exportWriter{c: c, name: name, format: format, headers: headers}
+	if format == "csv" {
+		c.Writer.WriteString("\xEF\xBB\xBF") // BOM，方便 Excel 识别 UTF-8
+		w.csv = csv.NewWriter(c.Writer)
+		w.csv.Write(headers)
+	} else {
+		c.Writer.WriteString("[")
+	}
+	return w, true
+}
+
+// write 输出一行数据，values 与表头一一对应
+func (w *exportWriter) write(values []interface{}) {
+	if w.format == "csv" {
+		record := make([]string, len(values))
+		for i, value := range values {
+			record[i] = exportCell(value)
+		}
+		w.csv.Write(record)
+	} else {
+		item := make(map[string]interface{}, len(values))
+		for i, value := range values {
+			item[w.headers[i]] = value
+		}
+		data, _ := json.Marshal(item)
+		if w.rows > 0 {
+			w.c.Writer.WriteString(",")
+		}
+		w.c.Writer.Write(data)
+	}
+
+	w.rows++
+	// 定期刷新，避免在内存中积压
+	if w.rows%200 == 0 {
+		w.flush()
+	}
+}
+
+func (w *exportWriter) flush() {
+	if w.csv != nil {
+		w.csv.Flush()
+	}
+	w.c.Writer.Flush()
+}
+
+// close 结束输出；err 不为空说明读取中途出错，此时响应头已发出，
+// 只能记录日志并直接断开连接，让客户端收到不完整的响应，而不是一份看似完整的文件
+func (w *exportWriter) close(err error) {
+	if err != nil {
+		log.Printf("导出 %s 中断，已输出 %d 行: %v", w.name, w.rows, err)
+		w.flush()
+		if conn, _, hijackErr := w.c.Writer.Hijack(); hijackErr == nil {
+			conn.Close()
+		}
+		w.c.Abort()
+		return
+	}
+	if w.format == "json" {
+		w.c.Writer.WriteString("]")
+	}
+	w.flush()
+}
+
+// exportCell 将单元格的值转换为 CSV 文本
+func exportCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// AdminExportUsers 按筛选条件流式导出用户（CSV/JSON）
+func AdminExportUsers(c *gin.Context) {
+	query, err := adminUserQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := query.Select("id, username, nickname, email, phone, gender, followers_count, following_count, posts_count, created_at, deleted_at").
+		Order("id").Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出用户失败"})
+		return
+	}
+	defer rows.Close()
+
+	w, ok := newExportWriter(c, "users", []string{
+		"id", "username", "nickname", "email", "phone", "gender",
+		"followers_count", "following_count", "posts_count", "created_at", "deleted_at",
+	})
+	if !ok {
+		return
+	}
+
+	for rows.Next() {
+		var row struct {
+			ID             uint
+			Username       string
+			Nickname       string
+			Email          string
+			Phone          string
+			Gender         uint8
+			FollowersCount uint
+			FollowingCount uint
+			PostsCount     uint
+			CreatedAt      time.Time
+			DeletedAt      *time.Time
+		}
+		if err = global.Db.ScanRows(rows, &row); err != nil {
+			break
+		}
+		w.write([]interface{}{
+			row.ID, row.Username, row.Nickname, row.Email, row.Phone, row.Gender,
+			row.FollowersCount, row.FollowingCount, row.PostsCount, row.CreatedAt, row.DeletedAt,
+		})
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	w.close(err)
+}
+
+// AdminExportArticles 按筛选条件流式导出文章（CSV/JSON）
+func AdminExportArticles(c *gin.Context) {
+	query, err := adminArticleQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := query.Select(`articles.id, articles.title, articles.author_id, users.nickname AS author_nickname,
+		articles.like_count, articles.favorite_count, articles.comment_count, articles.moderation_status,
+		(SELECT GROUP_CONCAT(tags.name) FROM article_tags JOIN tags ON tags.id = article_tags.tag_id WHERE article_tags.article_id = articles.id) AS tags,
+		articles.created_at, articles.deleted_at`).
+		Joins("LEFT JOIN users ON users.id = articles.author_id").
+		Order("articles.id").Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出文章失败"})
+		return
+	}
+	defer rows.Close()
+
+	w, ok := newExportWriter(c, "articles", []string{
+		"id", "title", "author_id", "author_nickname", "like_count", "favorite_count",
+		"comment_count", "moderation_status", "tags", "created_at", "deleted_at",
+	})
+	if !ok {
+		return
+	}
+
+	for rows.Next() {
+		var row struct {
+			ID               uint
+			Title            string
+			AuthorID         uint
+			AuthorNickname   *string
+			LikeCount        int
+			FavoriteCount    int
+			CommentCount     int
+			ModerationStatus string
+			Tags             *string
+			CreatedAt        time.Time
+			DeletedAt        *time.Time
+		}
+		if err = global.Db.ScanRows(rows, &row); err != nil {
+			break
+		}
+
+		var authorNickname string
+		if row.AuthorNickname != nil {
+			authorNickname = *row.AuthorNickname
+		}
+		tags := make([]string, 0)
+		if row.Tags != nil && *row.Tags != "" {
+			tags = strings.Split(*row.Tags, ",")
+		}
+
+		var tagValue interface{} = tags
+		if w.format == "csv" {
+			tagValue = strings.Join(tags, "|")
+		}
+		w.write([]interface{}{
+			row.ID, row.Title, row.AuthorID, authorNickname, row.LikeCount, row.FavoriteCount,
+			row.CommentCount, row.ModerationStatus, tagValue, row.CreatedAt, row.DeletedAt,
+		})
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	w.close(err)
+}
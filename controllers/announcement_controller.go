@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AnnouncementRequest 创建/更新公告请求
+type AnnouncementRequest struct {
+	Title       string     `json:"title" binding:"required"`
+	Content     string     `json:"content" binding:"required"`
+	Type        string     `json:"type" binding:"omitempty,oneof=general maintenance rules event"`
+	Audience    string     `json:"audience" binding:"omitempty,oneof=all new_users tag_followers"`
+	TagID       *uint      `json:"tag_id"`
+	NewUserDays int        `json:"new_user_days"`
+	StartsAt    *time.Time `json:"starts_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// toModel 校验请求并填充公告模型
+func (req *AnnouncementRequest) toModel(announcement *models.Announcement) (string, bool) {
+	if req.Type == "" {
+		req.Type = "general"
+	}
+	if req.Audience == "" {
+		req.Audience = models.AudienceAll
+	}
+	if req.Audience == models.AudienceTagFollowers {
+		if req.TagID == nil {
+			return "受众为标签关注者时必须指定 tag_id", false
+		}
+		var count int64
+		global.Db.Model(&models.Tag{}).Where("id = ?", *req.TagID).Count(&count)
+		if count == 0 {
+			return "标签不存在", false
+		}
+	}
+	if req.NewUserDays <= 0 {
+		req.NewUserDays = 7
+	}
+
+	startsAt := time.Now()
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(startsAt) {
+		return "过期时间必须晚于开始时间", false
+	}
+
+	announcement.Title = req.Title
+	announcement.Content = req.Content
+	announcement.Type = req.Type
+	announcement.Audience = req.Audience
+	announcement.TagID = nil
+	if req.Audience == models.AudienceTagFollowers {
+		announcement.TagID = req.TagID
+	}
+	announcement.NewUserDays = req.NewUserDays
+	announcement.StartsAt = startsAt
+	announcement.ExpiresAt = req.ExpiresAt
+	return "", true
+}
+
+// AdminCreateAnnouncement 创建公告
+func AdminCreateAnnouncement(c *gin.Context) {
+	var req AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	var announcement models.Announcement
+	if message, ok := req.toModel(&announcement); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	if err := global.Db.Create(&announcement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建公告失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, announcement)
+}
+
+// AdminGetAnnouncements 获取公告列表（含已读人数）
+func AdminGetAnnouncements(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	var total int64
+	global.Db.Model(&models.Announcement{}).Count(&total)
+
+	var announcements []models.Announcement
+	global.Db.Offset(offset).Limit(limit).Order("starts_at DESC").Find(&announcements)
+
+	now := time.Now()
+	items := make([]gin.H, 0, len(announcements))
+	for _, announcement := range announcements {
+		var readCount int64
+		global.Db.Model(&models.AnnouncementRead{}).Where("announcement_id = ?", announcement.ID).Count(&readCount)
+
+		status := "active"
+		if announcement.StartsAt.After(now) {
+			status = "scheduled"
+		} else if announcement.ExpiresAt != nil && !announcement.ExpiresAt.After(now) {
+			status = "expired"
+		}
+
+		items = append(items, gin.H{
+			"announcement": announcement,
+			"status":       status,
+			"read_count":   readCount,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+		"announcements": items,
+	})
+}
+
+// AdminUpdateAnnouncement 更新公告
+func AdminUpdateAnnouncement(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的公告ID"})
+		return
+	}
+
+	var announcement models.Announcement
+	if err := global.Db.First(&announcement, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "公告不存在"})
+		return
+	}
+
+	var req AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if req.StartsAt == nil {
+		req.StartsAt = &announcement.StartsAt
+	}
+	if message, ok := req.toModel(&announcement); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	if err := global.Db.Save(&announcement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新公告失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, announcement)
+}
+
+// AdminDeleteAnnouncement 删除公告
+func AdminDeleteAnnouncement(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的公告ID"})
+		return
+	}
+
+	result := global.Db.Delete(&models.Announcement{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除公告失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "公告不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "公告已删除"})
+}
+
+// visibleAnnouncements 构建当前用户可见（已开始、未过期、受众匹配）的公告查询
+func visibleAnnouncements(userID uint, registeredAt time.Time) *gorm.DB {
+	now := time.Now()
+	return global.Db.Model(&models.Announcement{}).
+		Where("starts_at <= ? AND (expires_at IS NULL OR expires_at > ?)", now, now).
+		Where(global.Db.Where("audience = ?", models.AudienceAll).
+			Or("audience = ? AND ? >= DATE_SUB(starts_at, INTERVAL new_user_days DAY)", models.AudienceNewUsers, registeredAt).
+			Or("audience = ? AND tag_id IN (?)", models.AudienceTagFollowers,
+				global.Db.Model(&models.TagFollow{}).Select("tag_id").Where("user_id = ?", userID)))
+}
+
+// GetAnnouncements 获取当前用户未读的公告
+func GetAnnouncements(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var user models.User
+	if err := global.Db.Select("id, created_at").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	var announcements []models.Announcement
+	if err := visibleAnnouncements(userID, user.CreatedAt).
+		Where("id NOT IN (?)", global.Db.Model(&models.AnnouncementRead{}).Select("announcement_id").Where("user_id = ?", userID)).
+		Order("starts_at DESC").
+		Find(&announcements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取公告失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(announcements))
+	for _, announcement := range announcements {
+		items = append(items, gin.H{
+			"id":         announcement.ID,
+			"title":      announcement.Title,
+			"content":    announcement.Content,
+			"type":       announcement.Type,
+			"starts_at":  announcement.StartsAt,
+			"expires_at": announcement.ExpiresAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"unread_count":  len(items),
+		"announcements": items,
+	})
+}
+
+// MarkAnnouncementRead 标记公告为已读
+func MarkAnnouncementRead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的公告ID"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	var user models.User
+	if err := global.Db.Select("id, created_at").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	var count int64
+	visibleAnnouncements(userID, user.CreatedAt).Where("id = ?", id).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "公告不存在"})
+		return
+	}
+
+	if err := global.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.AnnouncementRead{
+		AnnouncementID: uint(id),
+		UserID:         userID,
+		ReadAt:         time.Now(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "标记已读失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已读"})
+}
+
+// MarkAllAnnouncementsRead 将当前可见的公告全部标记为已读
+func MarkAllAnnouncementsRead(c *gin.Context) {
+	userID := c.GetUint("userID")
+	var user models.User
+	if err := global.Db.Select("id, created_at").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+
+	var ids []uint
+	visibleAnnouncements(userID, user.CreatedAt).Pluck("id", &ids)
+
+	now := time.Now()
+	reads := make([]models.AnnouncementRead, 0, len(ids))
+	for _, id := range ids {
+		reads = append(reads, models.AnnouncementRead{AnnouncementID: id, UserID: userID, ReadAt: now})
+	}
+	if len(reads) > 0 {
+		if err := global.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reads).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "标记已读失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已全部标记为已读"})
+}
@@ -41,11 +41,12 @@ func CreateArticle(c *gin.Context) {
 
 	userID := c.GetUint("userID")
 	article := models.Article{
-		Title:        req.Title,
-		Content:      req.Content,
-		AuthorID:     userID,
-		LikeCount:    0,
-		CommentCount: 0,
+		Title:            req.Title,
+		Content:          req.Content,
+		AuthorID:         userID,
+		LikeCount:        0,
+		CommentCount:     0,
+		ModerationStatus: initialModerationStatus(),
 	}
 
 	// 处理标签
@@ -90,6 +91,16 @@ func CreateArticle(c *gin.Context) {
 	// 更新用户的文章数量
 	global.Db.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("posts_count", gorm.Expr("posts_count + ?", 1))
 
+	// 解析正文中的 @提及
+	mentions := saveMentions(models.MentionSourceArticle, article.ID, article.ID, userID, article.Content)
+
+	// 异步内容审核，通过后再推送到粉丝的关注流并通知被提及的用户
+	published := article
+	moderateTextThen(models.ModerationTargetArticle, article.ID, article.Title+"\n"+article.Content, func() {
+		publishFeedArticle(&published)
+		notifyMentions(models.MentionSourceArticle, published.ID, &published, userID, published.Content, mentions, nil)
+	})
+
 	// 构建不包含用户信息的图片数组
 	var picturesResponse []gin.H
 	// 查询文章图片关联信息以获取顺序
@@ -138,7 +149,10 @@ func GetArticleList(c *gin.Context) {
 	// 预加载作者信息（包含头像）
 	query := global.Db.Model(&models.Article{}).Preload("Author", func(db *gorm.DB) *gorm.DB {
 		return db.Select("id, nickname, avatar")
-	})
+	}).Where("articles.moderation_status = ?", models.ModerationAllow).
+		Scopes(visibleArticlesScope(c.GetUint("userID")),
+			excludeBlockedScope("articles.author_id", c.GetUint("userID")),
+			excludeMutedScope("articles.author_id", c.GetUint("userID")))
 
 	if exists {
 		// 混合推荐算法：关注作者(权重3) + 点赞历史作者(权重2) + 热度(权重1) - 已点赞文章(权重-2)
@@ -225,7 +239,8 @@ func GetArticleList(c *gin.Context) {
 		articleList = append(articleList, gin.H{
 			"id":                    article.ID,
 			"title":                 article.Title,
-			"author_name":           article.Author.Nickname,
+			"author":                newUserBrief(&article.Author),
+			"author_name":           article.Author.Nickname, // 兼容旧客户端，新代码使用 author
 			"author_avatar":         article.Author.Avatar,
 			"cover_url":             coverImageURL,
 			"like_count":            article.LikeCount,
@@ -257,8 +272,11 @@ func GetFollowArticleList(c *gin.Context) {
 
 	// 查询当前用户关注的用户发布的文章
 	query := global.Db.Model(&models.Article{}).
-		Joins("JOIN user_follows ON articles.author_id = user_follows.followed_id").
+		Joins("JOIN user_follows ON articles.author_id = user_follows.followed_id AND user_follows.deleted_at IS NULL").
 		Where("user_follows.follower_id = ?", currentUserID).
+		Where("articles.moderation_status = ?", models.ModerationAllow).
+		Scopes(excludeMutedScope("articles.author_id", c.GetUint("userID")),
+			excludeBlockedScope("articles.author_id", c.GetUint("userID"))).
 		Preload("Author", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, nickname, avatar")
 		})
@@ -329,10 +347,29 @@ func GetArticle(c *gin.Context) {
 		return
 	}
 
+	// 未通过审核的文章仅作者可见
+	if article.ModerationStatus != models.ModerationAllow && c.GetUint("userID") != article.AuthorID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
+		return
+	}
+
+	// 私密账号的文章仅粉丝可见
+	var author models.User
+	if err := global.Db.Select(privacyColumns).First(&author, article.AuthorID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
+		return
+	}
+	if !canViewContent(c.GetUint("userID"), &author) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "作者为私密账号，关注后可查看"})
+		return
+	}
+	canCommentArticle, _ := canComment(c.GetUint("userID"), &author)
+
 	// 获取文章图片
 	var pictures []models.Picture
 	global.Db.Joins("JOIN article_pictures ON pictures.id = article_pictures.picture_id").
 		Where("article_pictures.article_id = ?", id).
+		Where("pictures.moderation_status = ? OR pictures.user_id = ?", models.ModerationAllow, c.GetUint("userID")).
 		Order("article_pictures.`order`").
 		Find(&pictures)
 
@@ -362,9 +399,22 @@ func GetArticle(c *gin.Context) {
 		// 检查是否为文章作者
 		isAuthor = currentUserID == article.AuthorID
 	}
+	// 正文和评论中的 @提及
+	articleMentions, commentMentions := articleMentionSpans(article.ID, c.GetUint("userID"))
+
 	// 构建评论列表
 	var filteredComments []gin.H
+	hiddenUsers := hiddenUserIDs(c.GetUint("userID"))
 	for _, comment := range article.Comments {
+		// 未通过审核的评论仅评论者本人可见
+		if comment.ModerationStatus != models.ModerationAllow && comment.UserID != c.GetUint("userID") {
+			continue
+		}
+		// 不显示拉黑关系中和已静音用户的评论
+		if hiddenUsers[comment.UserID] {
+			continue
+		}
+
 		// 检查当前用户是否对该评论点赞
 		var commentIsLiked bool
 		if exists {
@@ -379,11 +429,8 @@ func GetArticle(c *gin.Context) {
 			"created_at": comment.CreatedAt,
 			"like_count": comment.LikeCount,
 			"is_liked":   commentIsLiked,
-			"user": gin.H{
-				"id":       comment.User.ID,
-				"nickname": comment.User.Nickname,
-				"avatar":   comment.User.Avatar,
-			},
+			"mentions":   commentMentions[comment.ID],
+			"user":       newUserBrief(&comment.User),
 		})
 	}
 
@@ -392,12 +439,9 @@ func GetArticle(c *gin.Context) {
 		"id":         article.ID,
 		"title":      article.Title,
 		"content":    article.Content,
+		"mentions":   articleMentions,
 		"created_at": article.CreatedAt,
-		"author": gin.H{
-			"id":       article.Author.ID,
-			"nickname": article.Author.Nickname,
-			"avatar":   article.Author.Avatar,
-		},
+		"author":     newUserBrief(&article.Author),
 		"tags": func() []string {
 			var tagNames []string
 			for _, tag := range article.Tags {
@@ -423,6 +467,7 @@ func GetArticle(c *gin.Context) {
 		"is_favorited":   isFavorited,
 		"is_followed":    isFollowed,
 		"is_author":      isAuthor,
+		"can_comment":    canCommentArticle,
 	})
 }
 
@@ -452,11 +497,17 @@ func UpdateArticle(c *gin.Context) {
 		return
 	}
 
+	// 只更新标题和正文，审核状态在下方按需重置，避免覆盖异步审核写入的结论
+	updates := map[string]interface{}{}
+	title, content := article.Title, article.Content
 	if req.Title != "" {
-		article.Title = req.Title
+		title = req.Title
+		updates["title"] = title
 	}
+	contentChanged := req.Content != "" && req.Content != article.Content
 	if req.Content != "" {
-		article.Content = req.Content
+		content = req.Content
+		updates["content"] = content
 	}
 
 	// 更新标签
@@ -474,12 +525,30 @@ func UpdateArticle(c *gin.Context) {
 		global.Db.Model(&article).Association("Tags").Replace(tags)
 	}
 
-	if err := global.Db.Save(&article).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文章失败"})
-		return
+	if len(updates) > 0 {
+		// 配置了审核服务时修改后的内容先待审核，人工复核过的文章保持管理员的结论
+		if initialModerationStatus() == models.ModerationHold && !hasManualReview(models.ModerationTargetArticle, article.ID) {
+			updates["moderation_status"] = models.ModerationHold
+		}
+		if err := global.Db.Model(&article).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文章失败"})
+			return
+		}
+		// 正文修改后重新解析提及，避免保存的位置与正文不一致
+		var mentions []models.Mention
+		var previous map[uint]bool
+		if contentChanged {
+			mentions, previous = replaceMentions(models.MentionSourceArticle, article.ID, article.ID, userID, content)
+		}
+		// 修改后的内容重新审核，通过后只通知新增的被提及用户
+		updated := article
+		moderateTextThen(models.ModerationTargetArticle, article.ID, title+"\n"+content, func() {
+			notifyMentions(models.MentionSourceArticle, updated.ID, &updated, userID, content, mentions, previous)
+		})
 	}
 
-	c.JSON(http.StatusOK, article)
+	global.Db.Preload("Author").Preload("Tags").Preload("Pictures").First(&article, article.ID)
+	c.JSON(http.StatusOK, newArticleResponse(&article))
 }
 
 // Delete 删除文章
@@ -502,7 +571,10 @@ func DeleteArticle(c *gin.Context) {
 		return
 	}
 
-	if err := global.Db.Delete(&article).Error; err != nil {
+	now := deletionTime()
+	if err := global.Db.Transaction(func(tx *gorm.DB) error {
+		return softDeleteArticle(tx, article.ID, now)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文章失败"})
 		return
 	}
@@ -524,6 +596,8 @@ func SearchArticles(c *gin.Context) {
 	var articles []models.Article
 	query := global.Db.Model(&models.Article{}).
 		Where("title LIKE ? OR content LIKE ?", "%"+keyword+"%", "%"+keyword+"%").
+		Where("articles.moderation_status = ?", models.ModerationAllow).
+		Scopes(visibleArticlesScope(c.GetUint("userID")), excludeBlockedScope("articles.author_id", c.GetUint("userID"))).
 		Preload("Author", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, nickname, avatar")
 		}).
@@ -586,7 +660,8 @@ func SearchUsers(c *gin.Context) {
 
 	var users []models.User
 	query := global.Db.Model(&models.User{}).
-		Where("nickname LIKE ?", "%"+keyword+"%").
+		Where("nickname LIKE ? AND anonymized_at IS NULL", "%"+keyword+"%").
+		Scopes(excludeBlockedScope("users.id", c.GetUint("userID"))).
 		Select("id, nickname, avatar").
 		Order("created_at DESC")
 
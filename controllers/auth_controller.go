@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// issueTokens 为用户签发新会话的访问令牌和刷新令牌，并记录登录设备
+func issueTokens(c *gin.Context, userID uint, method string) (*utils.TokenPair, error) {
+	pair, err := utils.GenerateTokenPair(fmt.Sprintf("%d", userID), "")
+	if err != nil {
+		return nil, err
+	}
+	if err := createSession(c, userID, pair, method); err != nil {
+		return nil, err
+	}
+	if err := utils.StoreRefreshToken(pair); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	claims, err := utils.ParseToken(req.RefreshToken, utils.TokenTypeRefresh)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的刷新令牌", "detail": err.Error()})
+		return
+	}
+
+	if err := utils.ConsumeRefreshToken(claims); err != nil {
+		if errors.Is(err, utils.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "刷新令牌已被使用，会话已失效，请重新登录"})
+		} else {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "刷新令牌已失效"})
+		}
+		return
+	}
+
+	// 确认用户仍然存在
+	var count int64
+	global.Db.Model(&models.User{}).Where("id = ?", claims.UserID).Count(&count)
+	if count == 0 {
+		revokeSessions([]string{claims.SessionID})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	// 确认会话未被吊销
+	var session models.UserSession
+	if err := global.Db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", claims.SessionID, claims.UserID).First(&session).Error; err != nil {
+		utils.RevokeSession(claims.SessionID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "会话已失效，请重新登录"})
+		return
+	}
+
+	pair, err := utils.GenerateTokenPair(claims.UserID, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		return
+	}
+	if err := utils.StoreRefreshToken(pair); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		return
+	}
+
+	// 刷新会话的活跃信息和过期时间
+	now := time.Now()
+	global.Db.Model(&session).Updates(map[string]interface{}{
+		"ip":           c.ClientIP(),
+		"user_agent":   truncate(c.Request.UserAgent(), 500),
+		"last_seen_at": now,
+		"expires_at":   now.Add(utils.RefreshTokenTTL),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+	})
+}
+
+// Logout 退出登录，吊销当前访问令牌及其所属会话
+func Logout(c *gin.Context) {
+	value, exists := c.Get("tokenClaims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+	claims := value.(*utils.TokenClaims)
+
+	if err := utils.RevokeToken(claims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+		return
+	}
+	if err := revokeSessions([]string{claims.SessionID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出登录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已退出登录"})
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangePassword 修改密码，成功后吊销所有已登录会话并为当前设备重新签发令牌
+func ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	var user models.User
+	if err := global.Db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	if !utils.CheckPassword(req.OldPassword, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "原密码错误"})
+		return
+	}
+
+	hashedPassword, err := utils.HassPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码加密失败"})
+		return
+	}
+	if err := global.Db.Model(&user).Update("password", hashedPassword).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改密码失败"})
+		return
+	}
+
+	if err := RevokeUserSessions(user.ID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "吊销登录会话失败"})
+		return
+	}
+
+	pair, err := issueTokens(c, user.ID, LoginMethodPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "密码修改成功，其他设备已下线",
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+	})
+}
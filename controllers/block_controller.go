@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// isBlocked 双方任意一方拉黑了另一方
+func isBlocked(userID, otherID uint) bool {
+	if userID == 0 || otherID == 0 || userID == otherID {
+		return false
+	}
+	var count int64
+	global.Db.Model(&models.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).
+		Count(&count)
+	return count > 0
+}
+
+// hasBlocked 是否由 blockerID 拉黑了 blockedID
+func hasBlocked(blockerID, blockedID uint) bool {
+	var count int64
+	global.Db.Model(&models.UserBlock{}).Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Count(&count)
+	return count > 0
+}
+
+// hasMuted 是否由 muterID 静音了 mutedID
+func hasMuted(muterID, mutedID uint) bool {
+	var count int64
+	global.Db.Model(&models.UserMute{}).Where("muter_id = ? AND muted_id = ?", muterID, mutedID).Count(&count)
+	return count > 0
+}
+
+// blockedUserIDs 与当前用户存在拉黑关系的用户，包括拉黑了当前用户的人
+func blockedUserIDs(userID uint) map[uint]bool {
+	blocked := map[uint]bool{}
+	if userID == 0 {
+		return blocked
+	}
+	var ids, more []uint
+	global.Db.Model(&models.UserBlock{}).Where("blocker_id = ?", userID).Pluck("blocked_id", &ids)
+	global.Db.Model(&models.UserBlock{}).Where("blocked_id = ?", userID).Pluck("blocker_id", &more)
+	for _, id := range append(ids, more...) {
+		blocked[id] = true
+	}
+	return blocked
+}
+
+// hiddenUserIDs 当前用户看不到的用户：拉黑关系中的双方和被静音的用户
+func hiddenUserIDs(viewerID uint) map[uint]bool {
+	hidden := blockedUserIDs(viewerID)
+	if viewerID == 0 {
+		return hidden
+	}
+	var ids []uint
+	global.Db.Model(&models.UserMute{}).Where("muter_id = ?", viewerID).Pluck("muted_id", &ids)
+	for _, id := range ids {
+		hidden[id] = true
+	}
+	return hidden
+}
+
+// excludeBlockedScope 过滤与当前用户存在拉黑关系的用户，column 为用户ID所在的列
+func excludeBlockedScope(column string, viewerID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == 0 {
+			return db
+		}
+		blocking := global.Db.Model(&models.UserBlock{}).Select("blocked_id").Where("blocker_id = ?", viewerID)
+		blockedBy := global.Db.Model(&models.UserBlock{}).Select("blocker_id").Where("blocked_id = ?", viewerID)
+		return db.Where(column+" NOT IN (?)", blocking).Where(column+" NOT IN (?)", blockedBy)
+	}
+}
+
+// excludeMutedScope 过滤当前用户静音的用户，column 为用户ID所在的列
+func excludeMutedScope(column string, viewerID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == 0 {
+			return db
+		}
+		return db.Where(column+" NOT IN (?)", global.Db.Model(&models.UserMute{}).Select("muted_id").Where("muter_id = ?", viewerID))
+	}
+}
+
+// relationTarget 解析路径中的目标用户，返回 false 时已写入响应
+func relationTarget(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return 0, false
+	}
+	if uint(id) == c.GetUint("userID") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能对自己进行此操作"})
+		return 0, false
+	}
+	var count int64
+	global.Db.Model(&models.User{}).Where("id = ?", id).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// BlockUser 拉黑用户，同时解除双方的关注关系和等待中的关注请求
+func BlockUser(c *gin.Context) {
+	targetID, ok := relationTarget(c)
+	if !ok {
+		return
+	}
+	userID := c.GetUint("userID")
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		block := models.UserBlock{BlockerID: userID, BlockedID: targetID}
+		if err := tx.Where(&block).FirstOrCreate(&block).Error; err != nil {
+			return err
+		}
+		if _, err := removeFollow(tx, userID, targetID); err != nil {
+			return err
+		}
+		if _, err := removeFollow(tx, targetID, userID); err != nil {
+			return err
+		}
+		return tx.Model(&models.FollowRequest{}).
+			Where("((requester_id = ? AND target_id = ?) OR (requester_id = ? AND target_id = ?)) AND status = ?",
+				userID, targetID, targetID, userID, models.FollowRequestPending).
+			Updates(map[string]interface{}{"status": models.FollowRequestCanceled, "responded_at": time.Now()}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "拉黑失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已拉黑"})
+}
+
+// UnblockUser 取消拉黑，之前解除的关注关系不会恢复
+func UnblockUser(c *gin.Context) {
+	targetID, ok := relationTarget(c)
+	if !ok {
+		return
+	}
+	if err := global.Db.Where("blocker_id = ? AND blocked_id = ?", c.GetUint("userID"), targetID).
+		Delete(&models.UserBlock{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消拉黑失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已取消拉黑"})
+}
+
+// MuteUser 静音用户，对方不会收到通知
+func MuteUser(c *gin.Context) {
+	targetID, ok := relationTarget(c)
+	if !ok {
+		return
+	}
+	mute := models.UserMute{MuterID: c.GetUint("userID"), MutedID: targetID}
+	if err := global.Db.Where(&mute).FirstOrCreate(&mute).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "静音失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已静音"})
+}
+
+// UnmuteUser 取消静音
+func UnmuteUser(c *gin.Context) {
+	targetID, ok := relationTarget(c)
+	if !ok {
+		return
+	}
+	if err := global.Db.Where("muter_id = ? AND muted_id = ?", c.GetUint("userID"), targetID).
+		Delete(&models.UserMute{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消静音失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已取消静音"})
+}
+
+// listRelatedUsers 分页查询当前用户拉黑或静音的用户
+func listRelatedUsers(c *gin.Context, table, ownerColumn, targetColumn string) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := global.Db.Model(&models.User{}).
+		Joins("JOIN "+table+" ON "+table+"."+targetColumn+" = users.id").
+		Where(table+"."+ownerColumn+" = ?", c.GetUint("userID"))
+
+	var total int64
+	query.Count(&total)
+
+	var users []models.User
+	if err := query.Select("users.id, users.nickname, users.avatar").
+		Order(table + ".created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
+		return
+	}
+
+	list := make([]*UserBrief, 0, len(users))
+	for i := range users {
+		list = append(list, newUserBrief(&users[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": list,
+		"meta": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetBlockedUsers 拉黑列表
+func GetBlockedUsers(c *gin.Context) {
+	listRelatedUsers(c, "user_blocks", "blocker_id", "blocked_id")
+}
+
+// GetMutedUsers 静音列表
+func GetMutedUsers(c *gin.Context) {
+	listRelatedUsers(c, "user_mutes", "muter_id", "muted_id")
+}
@@ -30,10 +30,22 @@ func CreateComment(c *gin.Context) {
 	}
 
 	userID := c.GetUint("userID")
+
+	// 检查文章是否可见以及作者是否允许评论
+	article, author, ok := loadVisibleArticle(c, uint(id))
+	if !ok {
+		return
+	}
+	if allowed, reason := canComment(userID, author); !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return
+	}
+
 	comment := models.Comment{
-		Content:   req.Content,
-		UserID:    userID,
-		ArticleID: uint(id),
+		Content:          req.Content,
+		UserID:           userID,
+		ArticleID:        uint(id),
+		ModerationStatus: initialModerationStatus(),
 	}
 
 	if err := global.Db.Create(&comment).Error; err != nil {
@@ -50,6 +62,26 @@ func CreateComment(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
 		return
 	}
+	user.ID = userID
+	comment.User = user
+
+	// 解析评论中的 @提及
+	mentions := saveMentions(models.MentionSourceComment, comment.ID, article.ID, userID, comment.Content)
+
+	// 异步内容审核，通过后再通知文章作者和被提及的用户并推送给正在浏览文章的用户，通知中带有评论摘要
+	published := comment
+	moderateTextThen(models.ModerationTargetComment, comment.ID, comment.Content, func() {
+		notify(notificationEvent{
+			UserID:     author.ID,
+			ActorID:    userID,
+			Type:       models.NotificationComment,
+			TargetType: models.NotificationTargetArticle,
+			TargetID:   uint(id),
+			Preview:    published.Content,
+		})
+		publishNewComment(&published)
+		notifyMentions(models.MentionSourceComment, published.ID, article, userID, published.Content, mentions, nil)
+	})
 
 	// 返回用户昵称、头像和评论内容
 	response := gin.H{
@@ -0,0 +1,400 @@
+package controllers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+)
+
+// dataExportTimeout 单个导出任务的最长执行时间
+const dataExportTimeout = 30 * time.Minute
+
+func dataExportTTL() time.Duration {
+	if hours := config.AppConfig.Account.ExportTTL; hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return 72 * time.Hour
+}
+
+// exportArticleRef 点赞、收藏记录中的文章
+type exportArticleRef struct {
+	ArticleID uint      `json:"article_id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportUserRef 关注、粉丝记录中的用户
+type exportUserRef struct {
+	UserID    uint      `json:"user_id"`
+	Nickname  string    `json:"nickname"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// writeZipJSON 将 v 以 JSON 格式写入压缩包
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// exportArticleRefs 查询用户点赞或收藏的文章
+func exportArticleRefs(table string, userID uint) ([]exportArticleRef, error) {
+	refs := make([]exportArticleRef, 0)
+	err := global.Db.Table(table).
+		Select(table+".article_id, articles.title, "+table+".created_at").
+		Joins("LEFT JOIN articles ON articles.id = "+table+".article_id").
+		Where(table+".user_id = ? AND "+table+".deleted_at IS NULL", userID).
+		Order(table + ".created_at DESC").
+		Scan(&refs).Error
+	return refs, err
+}
+
+// exportFollowRefs 查询关注列表（following=true）或粉丝列表
+func exportFollowRefs(userID uint, following bool) ([]exportUserRef, error) {
+	selfColumn, otherColumn := "followed_id", "follower_id"
+	if following {
+		selfColumn, otherColumn = "follower_id", "followed_id"
+	}
+	refs := make([]exportUserRef, 0)
+	err := global.Db.Table("user_follows").
+		Select("users.id AS user_id, users.nickname, user_follows.created_at").
+		Joins("JOIN users ON users.id = user_follows."+otherColumn+" AND users.deleted_at IS NULL").
+		Where("user_follows."+selfColumn+" = ? AND user_follows.deleted_at IS NULL", userID).
+		Order("user_follows.created_at DESC").
+		Scan(&refs).Error
+	return refs, err
+}
+
+// writeDataExport 将用户的个人数据写入压缩包
+func writeDataExport(ctx context.Context, zw *zip.Writer, userID uint) error {
+	var user models.User
+	if err := global.Db.First(&user, userID).Error; err != nil {
+		return err
+	}
+	var identities []models.UserIdentity
+	global.Db.Where("user_id = ?", userID).Find(&identities)
+	var changes []models.ProfileChange
+	global.Db.Where("user_id = ?", userID).Order("created_at DESC").Find(&changes)
+	if err := writeZipJSON(zw, "profile.json", gin.H{
+		"profile":         newUserPrivate(&user),
+		"identities":      identities,
+		"profile_changes": changes,
+	}); err != nil {
+		return err
+	}
+
+	var articles []models.Article
+	if err := global.Db.Preload("Tags").Preload("Pictures").
+		Where("author_id = ?", userID).Order("created_at DESC").Find(&articles).Error; err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "articles.json", newArticleResponseList(articles)); err != nil {
+		return err
+	}
+
+	var comments []models.Comment
+	if err := global.Db.Where("user_id = ?", userID).Order("created_at DESC").Find(&comments).Error; err != nil {
+		return err
+	}
+	commentList := make([]CommentResponse, 0, len(comments))
+	for i := range comments {
+		commentList = append(commentList, newCommentResponse(&comments[i]))
+	}
+	if err := writeZipJSON(zw, "comments.json", commentList); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"likes", "favorites"} {
+		refs, err := exportArticleRefs(table, userID)
+		if err != nil {
+			return err
+		}
+		if err := writeZipJSON(zw, table+".json", refs); err != nil {
+			return err
+		}
+	}
+
+	following, err := exportFollowRefs(userID, true)
+	if err != nil {
+		return err
+	}
+	followers, err := exportFollowRefs(userID, false)
+	if err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "follows.json", gin.H{"following": following, "followers": followers}); err != nil {
+		return err
+	}
+
+	// 原图
+	var pictures []models.Picture
+	global.Db.Select("id, url").Where("user_id = ?", userID).Find(&pictures)
+	for _, picture := range pictures {
+		filename := strings.TrimPrefix(picture.URL, "/static/images/")
+		object, err := global.MinIOClient.GetObject(ctx, global.MinIOConf.BucketName, "images/"+filename, minio.GetObjectOptions{})
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create("pictures/" + path.Base(filename))
+		if err == nil {
+			_, err = io.Copy(w, object)
+		}
+		object.Close()
+		if err != nil {
+			// 对象已不存在时跳过
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				log.Printf("数据导出 user=%d: 图片 %s 不存在，已跳过", userID, filename)
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// buildDataExport 生成压缩包并上传到 MinIO
+func buildDataExport(export *models.DataExport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportTimeout)
+	defer cancel()
+
+	file, err := os.CreateTemp("", "greenbook-export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	if err := writeDataExport(ctx, zw, export.UserID); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	objectName := fmt.Sprintf("exports/%d/%d-%s.zip", export.UserID, export.ID, utils.RandomID())
+	if _, err := global.MinIOClient.PutObject(ctx, global.MinIOConf.BucketName, objectName, file, size,
+		minio.PutObjectOptions{ContentType: "application/zip"}); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(dataExportTTL())
+	export.Status = models.DataExportReady
+	export.ObjectName = objectName
+	export.Size = size
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	return global.Db.Model(export).Updates(map[string]interface{}{
+		"status":       export.Status,
+		"object_name":  objectName,
+		"size":         size,
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+// dataExportLink 生成导出文件的签名下载链接，有效期与文件保留时间一致
+func dataExportLink(export *models.DataExport) (string, error) {
+	if export.ExpiresAt == nil {
+		return "", errors.New("export has no expiry")
+	}
+	ttl := time.Until(*export.ExpiresAt)
+	if ttl <= 0 {
+		return "", errors.New("export expired")
+	}
+	token, err := utils.GenerateActionToken(export.UserID, utils.TokenTypeDataExport, strconv.FormatUint(uint64(export.ID), 10), ttl)
+	if err != nil {
+		return "", err
+	}
+	return accountLink("/api/exports/download", token), nil
+}
+
+// runDataExport 后台执行导出任务，完成后邮件通知
+func runDataExport(export models.DataExport) {
+	if err := buildDataExport(&export); err != nil {
+		log.Printf("数据导出 #%d 失败: %v", export.ID, err)
+		global.Db.Model(&export).Updates(map[string]interface{}{
+			"status": models.DataExportFailed,
+			"error":  truncate(err.Error(), 500),
+		})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.Select("id, nickname, email").First(&user, export.UserID).Error; err != nil || user.Email == "" {
+		return
+	}
+	link, err := dataExportLink(&export)
+	if err != nil {
+		log.Printf("数据导出 #%d 生成下载链接失败: %v", export.ID, err)
+		return
+	}
+	sendMailAsync(utils.Mail{
+		To:      user.Email,
+		Subject: "你的个人数据已导出",
+		Text: fmt.Sprintf("你好 %s：\n\n你申请导出的个人数据已准备好，请在 %d 小时内通过以下链接下载：\n%s\n\n如果这不是你本人的操作，请立即登录并修改密码。",
+			user.Nickname, int(dataExportTTL().Hours()), link),
+	})
+}
+
+// dataExportView 导出任务的响应，已完成的任务附带下载链接
+func dataExportView(export *models.DataExport) gin.H {
+	view := gin.H{
+		"id":           export.ID,
+		"status":       export.Status,
+		"size":         export.Size,
+		"created_at":   export.CreatedAt,
+		"completed_at": export.CompletedAt,
+		"expires_at":   export.ExpiresAt,
+	}
+	if export.Status == models.DataExportReady {
+		if link, err := dataExportLink(export); err == nil {
+			view["download_url"] = link
+		}
+	}
+	return view
+}
+
+// RequestDataExport 申请导出个人数据，生成完成后通过签名链接下载
+func RequestDataExport(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	// 已中断的任务不再阻止重新申请，由定时任务标记为失败
+	var pending int64
+	global.Db.Model(&models.DataExport{}).
+		Where("user_id = ? AND status = ? AND created_at >= ?", userID, models.DataExportPending, dataExportStaleBefore()).
+		Count(&pending)
+	if pending > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "已有导出任务正在进行"})
+		return
+	}
+
+	export := models.DataExport{UserID: userID, Status: models.DataExportPending}
+	if err := global.Db.Create(&export).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建导出任务失败"})
+		return
+	}
+	go runDataExport(export)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "导出任务已创建，完成后可在此处下载或通过邮件获取下载链接",
+		"export":  dataExportView(&export),
+	})
+}
+
+// GetDataExports 获取最近的导出任务
+func GetDataExports(c *gin.Context) {
+	var exports []models.DataExport
+	if err := global.Db.Where("user_id = ?", c.GetUint("userID")).
+		Order("created_at DESC").Limit(10).
+		Find(&exports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取导出任务失败"})
+		return
+	}
+
+	views := make([]gin.H, 0, len(exports))
+	for i := range exports {
+		views = append(views, dataExportView(&exports[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"exports": views})
+}
+
+// DownloadDataExport 通过签名链接下载导出文件，链接有效期内可重复下载
+func DownloadDataExport(c *gin.Context) {
+	claims, err := utils.ValidateActionToken(c.Query("token"), utils.TokenTypeDataExport)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "下载链接无效或已过期"})
+		return
+	}
+
+	var export models.DataExport
+	if err := global.Db.Where("id = ? AND user_id = ? AND status = ?", claims.Binding, claims.UserID, models.DataExportReady).
+		First(&export).Error; err != nil || export.ExpiresAt == nil || export.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "导出文件不存在或已过期"})
+		return
+	}
+
+	object, err := global.MinIOClient.GetObject(c.Request.Context(), global.MinIOConf.BucketName, export.ObjectName, minio.GetObjectOptions{})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "导出文件不存在或已过期"})
+		return
+	}
+	defer object.Close()
+
+	filename := fmt.Sprintf("greenbook-export-%s.zip", export.CreatedAt.Format("20060102"))
+	c.DataFromReader(http.StatusOK, export.Size, "application/zip", object, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, filename),
+		"Cache-Control":       "no-store",
+	})
+}
+
+// PurgeExpiredDataExports 删除过期的导出文件，返回处理数量
+func PurgeExpiredDataExports() int {
+	var exports []models.DataExport
+	global.Db.Where("status = ? AND expires_at < ?", models.DataExportReady, time.Now()).Find(&exports)
+
+	for _, export := range exports {
+		removeObjects([]string{export.ObjectName})
+	}
+	if len(exports) > 0 {
+		ids := make([]uint, 0, len(exports))
+		for _, export := range exports {
+			ids = append(ids, export.ID)
+		}
+		global.Db.Model(&models.DataExport{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":      models.DataExportExpired,
+			"object_name": "",
+		})
+	}
+
+	if stale := failStaleDataExports(); stale > 0 {
+		log.Printf("将 %d 个超时的数据导出标记为失败\n", stale)
+	}
+
+	return len(exports)
+}
+
+// dataExportStaleBefore 在此之前创建且仍未完成的任务视为已中断（例如服务重启）
+func dataExportStaleBefore() time.Time {
+	return time.Now().Add(-2 * dataExportTimeout)
+}
+
+// failStaleDataExports 将已中断的导出任务标记为失败，返回处理的数量
+func failStaleDataExports() int64 {
+	return global.Db.Model(&models.DataExport{}).
+		Where("status = ? AND created_at < ?", models.DataExportPending, dataExportStaleBefore()).
+		Updates(map[string]interface{}{
+			"status": models.DataExportFailed,
+			"error":  "任务超时未完成",
+		}).RowsAffected
+}
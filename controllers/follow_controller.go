@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/appabin/greenbook/global"
 	"github.com/appabin/greenbook/models"
@@ -15,7 +16,49 @@ type FollowRequest struct {
 	UserID uint `json:"user_id" binding:"required"` // 要关注/取消关注的用户ID
 }
 
-// FollowAction 关注/取消关注操作
+// addFollow 创建关注关系并更新双方计数，已取消的关注记录直接恢复
+func addFollow(tx *gorm.DB, followerID, followedID uint) error {
+	var follow models.UserFollow
+	err := tx.Unscoped().Where("follower_id = ? AND followed_id = ?", followerID, followedID).First(&follow).Error
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		err = tx.Create(&models.UserFollow{FollowerID: followerID, FollowedID: followedID}).Error
+	case err != nil:
+		return err
+	case !follow.DeletedAt.Valid:
+		return nil // 已关注
+	default:
+		err = tx.Unscoped().Model(&models.UserFollow{}).
+			Where("follower_id = ? AND followed_id = ?", followerID, followedID).
+			Updates(map[string]interface{}{"deleted_at": nil, "created_at": time.Now()}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", followerID).UpdateColumn("following_count", gorm.Expr("following_count + ?", 1)).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.User{}).Where("id = ?", followedID).UpdateColumn("followers_count", gorm.Expr("followers_count + ?", 1)).Error
+}
+
+// removeFollow 取消关注并更新双方计数，未关注时返回 false
+func removeFollow(tx *gorm.DB, followerID, followedID uint) (bool, error) {
+	result := tx.Where("follower_id = ? AND followed_id = ?", followerID, followedID).Delete(&models.UserFollow{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ? AND following_count > 0", followerID).UpdateColumn("following_count", gorm.Expr("following_count - ?", 1)).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ? AND followers_count > 0", followedID).UpdateColumn("followers_count", gorm.Expr("followers_count - ?", 1)).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FollowAction 关注/取消关注操作，目标为私密账号时发送关注请求
 func FollowAction(c *gin.Context) {
 	var req FollowRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -24,105 +67,199 @@ func FollowAction(c *gin.Context) {
 	}
 
 	// 获取当前用户ID
-	followerID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
-		return
-	}
+	followerID := c.GetUint("userID")
 
 	// 不能关注自己
-	if uint(followerID.(uint)) == req.UserID {
+	if followerID == req.UserID {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "不能关注自己"})
 		return
 	}
 
 	// 检查目标用户是否存在
 	var targetUser models.User
-	if err := global.Db.First(&targetUser, req.UserID).Error; err != nil {
+	if err := global.Db.Select("id, private_account").Where("anonymized_at IS NULL").First(&targetUser, req.UserID).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "目标用户不存在"})
 		return
 	}
+	if isBlocked(followerID, req.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无法关注该用户"})
+		return
+	}
 
-	// 检查是否已关注或已取消关注
-	var follow models.UserFollow
-
-	if err := global.Db.Where("follower_id =? AND followed_id =?", followerID, req.UserID).First(&follow).Error; err == nil {
-		// 已关注，执行取消关注操作
-		if err := global.Db.Delete(&follow).Error; err != nil {
+	// 已关注，执行取消关注操作
+	if isFollowing(followerID, req.UserID) {
+		if err := global.Db.Transaction(func(tx *gorm.DB) error {
+			_, err := removeFollow(tx, followerID, req.UserID)
+			return err
+		}); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "取消关注失败"})
 			return
 		}
+		c.JSON(http.StatusOK, gin.H{"message": "取消关注成功", "status": "none"})
+		return
+	}
 
-		// 更新关注者的关注数 -1
-		if err := global.Db.Model(&models.User{}).Where("id =?", followerID).UpdateColumn("following_count", gorm.Expr("following_count -?", 1)).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新关注数失败"})
+	// 私密账号需要对方同意，再次操作时撤回请求
+	if targetUser.PrivateAccount {
+		var request models.FollowRequest
+		err := global.Db.Where("requester_id = ? AND target_id = ?", followerID, req.UserID).First(&request).Error
+		if err == nil && request.Status == models.FollowRequestPending {
+			if err := respondFollowRequest(&request, models.FollowRequestCanceled); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "撤回关注请求失败"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "已撤回关注请求", "status": "none"})
 			return
 		}
-		// 更新被关注者的粉丝数 -1
-		if err := global.Db.Model(&models.User{}).Where("id =?", req.UserID).UpdateColumn("followers_count", gorm.Expr("followers_count -?", 1)).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新粉丝数失败"})
+
+		if err == gorm.ErrRecordNotFound {
+			request = models.FollowRequest{RequesterID: followerID, TargetID: req.UserID, Status: models.FollowRequestPending}
+			err = global.Db.Create(&request).Error
+		} else if err == nil {
+			// 之前被拒绝或撤回的请求重新发起
+			err = global.Db.Model(&request).Updates(map[string]interface{}{
+				"status":       models.FollowRequestPending,
+				"responded_at": nil,
+				"created_at":   time.Now(),
+			}).Error
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "发送关注请求失败"})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "取消关注成功"})
+		go notify(notificationEvent{
+			UserID:     req.UserID,
+			ActorID:    followerID,
+			Type:       models.NotificationFollowRequest,
+			TargetType: models.NotificationTargetUser,
+			TargetID:   followerID,
+		})
+		c.JSON(http.StatusOK, gin.H{"message": "已发送关注请求，等待对方同意", "status": "requested", "request_id": request.ID})
 		return
 	}
 
 	// 执行关注操作
-	follow = models.UserFollow{
-		FollowerID: uint(followerID.(uint)),
-		FollowedID: req.UserID,
-	}
-	if err := global.Db.Create(&follow).Error; err != nil {
+	if err := global.Db.Transaction(func(tx *gorm.DB) error {
+		return addFollow(tx, followerID, req.UserID)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "关注失败"})
 		return
 	}
-	// 更新关注者的关注数 +1
-	if err := global.Db.Model(&models.User{}).Where("id =?", followerID).UpdateColumn("following_count", gorm.Expr("following_count +?", 1)).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新关注数失败"})
-		return
+	go notify(notificationEvent{
+		UserID:     req.UserID,
+		ActorID:    followerID,
+		Type:       models.NotificationFollow,
+		TargetType: models.NotificationTargetUser,
+		TargetID:   req.UserID,
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "关注成功", "status": "following"})
+}
+
+// FollowUserResponse 关注/粉丝列表中的用户，附带与当前用户的关注关系
+type FollowUserResponse struct {
+	UserBrief
+	IsFollowing bool `json:"is_following"` // 当前用户已关注对方
+	FollowsYou  bool `json:"follows_you"`  // 对方已关注当前用户
+}
+
+// followListOwner 解析要查看的用户并校验其隐私设置，raw 为空时为当前用户，返回 false 时已写入响应
+func followListOwner(c *gin.Context, raw string, followers bool) (uint, bool) {
+	viewerID := c.GetUint("userID")
+	if raw == "" {
+		return viewerID, true
 	}
-	// 更新被关注者的粉丝数 +1
-	if err := global.Db.Model(&models.User{}).Where("id =?", req.UserID).UpdateColumn("followers_count", gorm.Expr("followers_count +?", 1)).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新粉丝数失败"})
-		return
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return 0, false
+	}
+
+	var owner models.User
+	if err := global.Db.Select(privacyColumns).First(&owner, id).Error; err != nil || hasBlocked(owner.ID, viewerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return 0, false
+	}
+	if !canViewFollowList(viewerID, &owner, followers) {
+		if followers {
+			c.JSON(http.StatusForbidden, gin.H{"error": "该用户未公开粉丝列表"})
+		} else {
+			c.JSON(http.StatusForbidden, gin.H{"error": "该用户未公开关注列表"})
+		}
+		return 0, false
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "关注成功"})
+	return owner.ID, true
 }
 
-// GetFollowingList 获取关注列表
-func GetFollowingList(c *gin.Context) {
-	// 获取当前用户ID
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
-		return
+// followUsersQuery 查询关注了 ownerID 的用户（followers 为 true）或 ownerID 关注的用户，忽略已取消的关注
+func followUsersQuery(ownerID uint, followers bool) *gorm.DB {
+	if followers {
+		return global.Db.Model(&models.User{}).
+			Joins("JOIN user_follows ON users.id = user_follows.follower_id AND user_follows.deleted_at IS NULL").
+			Where("user_follows.followed_id = ?", ownerID)
 	}
+	return global.Db.Model(&models.User{}).
+		Joins("JOIN user_follows ON users.id = user_follows.followed_id AND user_follows.deleted_at IS NULL").
+		Where("user_follows.follower_id = ?", ownerID)
+}
+
+// respondFollowUsers 分页返回用户列表，并标注每个用户与当前用户的关注关系
+func respondFollowUsers(c *gin.Context, query *gorm.DB, errMsg string) {
+	viewerID := c.GetUint("userID")
 
 	// 分页参数
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	offset := (page - 1) * pageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
 
-	// 查询关注的用户
-	var followings []models.User
-	err := global.Db.Table("users").
-		Joins("JOIN user_follows ON users.id = user_follows.followed_id").
-		Where("user_follows.follower_id = ?", userID).
-		Offset(offset).Limit(pageSize).
-		Select("users.id, users.username, users.nickname, users.avatar").
-		Find(&followings).Error
+	// 不显示与当前用户存在拉黑关系的用户
+	query = query.Scopes(excludeBlockedScope("users.id", viewerID))
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取关注列表失败"})
+	var total int64
+	query.Count(&total)
+
+	var users []models.User
+	if err := query.Select("users.id, users.nickname, users.avatar").
+		Order("user_follows.created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errMsg})
 		return
 	}
 
-	// 获取总数
-	var total int64
-	global.Db.Model(&models.UserFollow{}).Where("follower_id = ?", userID).Count(&total)
+	// 批量查询当前用户与列表中用户的关注关系
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	following, followedBy := map[uint]bool{}, map[uint]bool{}
+	if len(ids) > 0 {
+		var followingIDs, followerIDs []uint
+		global.Db.Model(&models.UserFollow{}).Where("follower_id = ? AND followed_id IN ?", viewerID, ids).Pluck("followed_id", &followingIDs)
+		global.Db.Model(&models.UserFollow{}).Where("followed_id = ? AND follower_id IN ?", viewerID, ids).Pluck("follower_id", &followerIDs)
+		for _, id := range followingIDs {
+			following[id] = true
+		}
+		for _, id := range followerIDs {
+			followedBy[id] = true
+		}
+	}
+
+	list := make([]FollowUserResponse, 0, len(users))
+	for i := range users {
+		list = append(list, FollowUserResponse{
+			UserBrief:   *newUserBrief(&users[i]),
+			IsFollowing: following[users[i].ID],
+			FollowsYou:  followedBy[users[i].ID],
+		})
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": followings,
+		"data": list,
 		"meta": gin.H{
 			"total":     total,
 			"page":      page,
@@ -131,44 +268,49 @@ func GetFollowingList(c *gin.Context) {
 	})
 }
 
-// GetFollowersList 获取粉丝列表
-func GetFollowersList(c *gin.Context) {
-	// 获取当前用户ID
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+// GetFollowingList 获取关注列表，传入 user_id 时查询指定用户
+func GetFollowingList(c *gin.Context) {
+	ownerID, ok := followListOwner(c, c.Query("user_id"), false)
+	if !ok {
 		return
 	}
+	respondFollowUsers(c, followUsersQuery(ownerID, false), "获取关注列表失败")
+}
 
-	// 分页参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	offset := (page - 1) * pageSize
-
-	// 查询粉丝用户
-	var followers []models.User
-	err := global.Db.Table("users").
-		Joins("JOIN user_follows ON users.id = user_follows.follower_id").
-		Where("user_follows.followed_id = ?", userID).
-		Offset(offset).Limit(pageSize).
-		Select("users.id, users.username, users.nickname, users.avatar").
-		Find(&followers).Error
+// GetFollowersList 获取粉丝列表，传入 user_id 时查询指定用户
+func GetFollowersList(c *gin.Context) {
+	ownerID, ok := followListOwner(c, c.Query("user_id"), true)
+	if !ok {
+		return
+	}
+	respondFollowUsers(c, followUsersQuery(ownerID, true), "获取粉丝列表失败")
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取粉丝列表失败"})
+// GetUserFollowingList 获取指定用户的关注列表
+func GetUserFollowingList(c *gin.Context) {
+	ownerID, ok := followListOwner(c, c.Param("id"), false)
+	if !ok {
 		return
 	}
+	respondFollowUsers(c, followUsersQuery(ownerID, false), "获取关注列表失败")
+}
 
-	// 获取总数
-	var total int64
-	global.Db.Model(&models.UserFollow{}).Where("followed_id = ?", userID).Count(&total)
+// GetUserFollowersList 获取指定用户的粉丝列表
+func GetUserFollowersList(c *gin.Context) {
+	ownerID, ok := followListOwner(c, c.Param("id"), true)
+	if !ok {
+		return
+	}
+	respondFollowUsers(c, followUsersQuery(ownerID, true), "获取粉丝列表失败")
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": followers,
-		"meta": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
-	})
+// GetMutualFollowers 共同关注：指定用户的粉丝中当前用户也关注的人
+func GetMutualFollowers(c *gin.Context) {
+	ownerID, ok := followListOwner(c, c.Param("id"), true)
+	if !ok {
+		return
+	}
+	query := followUsersQuery(ownerID, true).
+		Joins("JOIN user_follows AS mine ON mine.followed_id = users.id AND mine.follower_id = ? AND mine.deleted_at IS NULL", c.GetUint("userID"))
+	respondFollowUsers(c, query, "获取共同关注失败")
 }
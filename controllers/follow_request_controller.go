@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FollowRequestResponse 关注请求信息
+type FollowRequestResponse struct {
+	ID        uint       `json:"id"`
+	User      *UserBrief `json:"user"` // 收到的请求为申请人，发出的请求为对方
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// respondFollowRequest 修改请求状态，同意时在同一事务中创建关注关系并通知申请人
+func respondFollowRequest(request *models.FollowRequest, status string) error {
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		// 只处理仍在等待中的请求，避免重复同意导致计数错误
+		result := tx.Model(&models.FollowRequest{}).
+			Where("id = ? AND status = ?", request.ID, models.FollowRequestPending).
+			Updates(map[string]interface{}{"status": status, "responded_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		request.Status = status
+		if status != models.FollowRequestApproved {
+			return nil
+		}
+		return addFollow(tx, request.RequesterID, request.TargetID)
+	})
+	if err == nil && status == models.FollowRequestApproved {
+		go notify(notificationEvent{
+			UserID:     request.RequesterID,
+			ActorID:    request.TargetID,
+			Type:       models.NotificationFollowAccepted,
+			TargetType: models.NotificationTargetUser,
+			TargetID:   request.TargetID,
+		})
+	}
+	return err
+}
+
+// approvePendingFollowRequests 私密账号改为公开时同意全部等待中的请求
+func approvePendingFollowRequests(targetID uint) {
+	var requests []models.FollowRequest
+	global.Db.Where("target_id = ? AND status = ?", targetID, models.FollowRequestPending).Find(&requests)
+	for i := range requests {
+		respondFollowRequest(&requests[i], models.FollowRequestApproved)
+	}
+}
+
+// listFollowRequests 分页查询等待中的关注请求，incoming 为 true 时查询收到的请求
+func listFollowRequests(c *gin.Context, incoming bool) {
+	userID := c.GetUint("userID")
+
+	// 分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	selfColumn, otherAssoc := "requester_id", "Target"
+	if incoming {
+		selfColumn, otherAssoc = "target_id", "Requester"
+	}
+	query := global.Db.Model(&models.FollowRequest{}).
+		Where(selfColumn+" = ? AND status = ?", userID, models.FollowRequestPending)
+
+	var total int64
+	query.Count(&total)
+
+	var requests []models.FollowRequest
+	if err := query.Preload(otherAssoc, func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, nickname, avatar")
+	}).Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取关注请求失败"})
+		return
+	}
+
+	list := make([]FollowRequestResponse, 0, len(requests))
+	for i := range requests {
+		other := &requests[i].Target
+		if incoming {
+			other = &requests[i].Requester
+		}
+		list = append(list, FollowRequestResponse{
+			ID:        requests[i].ID,
+			User:      newUserBrief(other),
+			Status:    requests[i].Status,
+			CreatedAt: requests[i].CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": list,
+		"meta": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetIncomingFollowRequests 收到的关注请求
+func GetIncomingFollowRequests(c *gin.Context) {
+	listFollowRequests(c, true)
+}
+
+// GetOutgoingFollowRequests 发出的关注请求
+func GetOutgoingFollowRequests(c *gin.Context) {
+	listFollowRequests(c, false)
+}
+
+// handleFollowRequest 查找当前用户可以处理的请求并修改状态，column 为当前用户在请求中的角色
+func handleFollowRequest(c *gin.Context, column, status, message string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求ID"})
+		return
+	}
+
+	var request models.FollowRequest
+	if err := global.Db.Where(column+" = ? AND status = ?", c.GetUint("userID"), models.FollowRequestPending).
+		First(&request, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "关注请求不存在或已处理"})
+		return
+	}
+
+	if err := respondFollowRequest(&request, status); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "关注请求不存在或已处理"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "处理关注请求失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "status": request.Status})
+}
+
+// ApproveFollowRequest 同意关注请求
+func ApproveFollowRequest(c *gin.Context) {
+	handleFollowRequest(c, "target_id", models.FollowRequestApproved, "已同意关注请求")
+}
+
+// DenyFollowRequest 拒绝关注请求
+func DenyFollowRequest(c *gin.Context) {
+	handleFollowRequest(c, "target_id", models.FollowRequestDenied, "已拒绝关注请求")
+}
+
+// CancelFollowRequest 撤回自己发出的关注请求
+func CancelFollowRequest(c *gin.Context) {
+	handleFollowRequest(c, "requester_id", models.FollowRequestCanceled, "已撤回关注请求")
+}
@@ -20,6 +20,10 @@ func ArticleToggleLike(c *gin.Context) {
 	}
 
 	userID := c.GetUint("userID")
+	article, _, ok := loadVisibleArticle(c, uint(id))
+	if !ok {
+		return
+	}
 	likeKey := fmt.Sprintf("article:like:%d:%d", id, userID)
 	countKey := fmt.Sprintf("article:like_count:%d", id)
 
@@ -42,6 +46,14 @@ func ArticleToggleLike(c *gin.Context) {
 				}
 				global.Db.Create(&like)
 				global.Db.Model(&models.Article{}).Where("id = ?", id).Update("like_count", gorm.Expr("like_count + ?", 1))
+				publishArticleLikeCount(uint(id))
+				notify(notificationEvent{
+					UserID:     article.AuthorID,
+					ActorID:    userID,
+					Type:       models.NotificationLike,
+					TargetType: models.NotificationTargetArticle,
+					TargetID:   article.ID,
+				})
 			}()
 
 			c.JSON(http.StatusOK, gin.H{"message": "点赞成功"})
@@ -62,6 +74,14 @@ func ArticleToggleLike(c *gin.Context) {
 					"created_at": gorm.Expr("NOW()"),
 				})
 				global.Db.Model(&models.Article{}).Where("id = ?", id).Update("like_count", gorm.Expr("like_count + ?", 1))
+				publishArticleLikeCount(uint(id))
+				notify(notificationEvent{
+					UserID:     article.AuthorID,
+					ActorID:    userID,
+					Type:       models.NotificationLike,
+					TargetType: models.NotificationTargetArticle,
+					TargetID:   article.ID,
+				})
 			}()
 
 			c.JSON(http.StatusOK, gin.H{"message": "点赞成功"})
@@ -79,6 +99,7 @@ func ArticleToggleLike(c *gin.Context) {
 			go func() {
 				global.Db.Delete(&existingLike)
 				global.Db.Model(&models.Article{}).Where("id = ?", id).Update("like_count", gorm.Expr("like_count - ?", 1))
+				publishArticleLikeCount(uint(id))
 			}()
 
 			c.JSON(http.StatusOK, gin.H{"message": "已取消点赞"})
@@ -97,6 +118,10 @@ func ArticleToggleFavorite(c *gin.Context) {
 	}
 
 	userID := c.GetUint("userID")
+	article, _, ok := loadVisibleArticle(c, uint(id))
+	if !ok {
+		return
+	}
 	favoriteKey := fmt.Sprintf("article:favorite:%d:%d", id, userID)
 	countKey := fmt.Sprintf("article:favorite_count:%d", id)
 
@@ -119,6 +144,13 @@ func ArticleToggleFavorite(c *gin.Context) {
 				}
 				global.Db.Create(&favorite)
 				global.Db.Model(&models.Article{}).Where("id = ?", id).Update("favorite_count", gorm.Expr("favorite_count + ?", 1))
+				notify(notificationEvent{
+					UserID:     article.AuthorID,
+					ActorID:    userID,
+					Type:       models.NotificationFavorite,
+					TargetType: models.NotificationTargetArticle,
+					TargetID:   article.ID,
+				})
 			}()
 
 			c.JSON(http.StatusOK, gin.H{"message": "收藏成功"})
@@ -139,6 +171,13 @@ func ArticleToggleFavorite(c *gin.Context) {
 					"created_at": gorm.Expr("NOW()"),
 				})
 				global.Db.Model(&models.Article{}).Where("id = ?", id).Update("favorite_count", gorm.Expr("favorite_count + ?", 1))
+				notify(notificationEvent{
+					UserID:     article.AuthorID,
+					ActorID:    userID,
+					Type:       models.NotificationFavorite,
+					TargetType: models.NotificationTargetArticle,
+					TargetID:   article.ID,
+				})
 			}()
 
 			c.JSON(http.StatusOK, gin.H{"message": "收藏成功"})
@@ -173,6 +212,22 @@ func CommentToggleLike(c *gin.Context) {
 	}
 
 	userID := c.GetUint("userID")
+
+	// 存在拉黑关系时不能点赞对方的评论，未通过审核的评论仅评论者本人可见
+	var comment models.Comment
+	if err := global.Db.Select("id, user_id, content, moderation_status").First(&comment, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评论不存在"})
+		return
+	}
+	if comment.ModerationStatus != models.ModerationAllow && comment.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评论不存在"})
+		return
+	}
+	if isBlocked(userID, comment.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "你无法与该用户互动"})
+		return
+	}
+
 	likeKey := fmt.Sprintf("comment:like:%d:%d", id, userID)
 	countKey := fmt.Sprintf("comment:like_count:%d", id)
 
@@ -191,6 +246,19 @@ func CommentToggleLike(c *gin.Context) {
 			global.Db.Create(&commentLike)
 			// 更新评论的点赞数
 			global.Db.Model(&models.Comment{}).Where("id = ?", id).Update("like_count", gorm.Expr("like_count + ?", 1))
+			// 未通过审核的评论不带摘要
+			var preview string
+			if comment.ModerationStatus == models.ModerationAllow {
+				preview = comment.Content
+			}
+			notify(notificationEvent{
+				UserID:     comment.UserID,
+				ActorID:    userID,
+				Type:       models.NotificationCommentLike,
+				TargetType: models.NotificationTargetComment,
+				TargetID:   comment.ID,
+				Preview:    preview,
+			})
 		}()
 
 		c.JSON(http.StatusOK, gin.H{"message": "点赞成功"})
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 登录防护范围，普通用户与管理员分开计数
+const (
+	LoginScopeUser  = "user"
+	LoginScopeAdmin = "admin"
+)
+
+// retryAfterSeconds 将等待时长向上取整为秒
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// abortTooManyAttempts 返回 429 并设置 Retry-After
+func abortTooManyAttempts(c *gin.Context, retry time.Duration, message string) {
+	seconds := retryAfterSeconds(retry)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("%s，请在 %d 秒后重试", message, seconds),
+		"retry_after": seconds,
+	})
+}
+
+// captchaRequired 判断当前失败次数是否需要验证码
+func captchaRequired(failures int64) bool {
+	return utils.Captcha.Enabled() && utils.LoginGuard.CaptchaAfter > 0 && failures >= int64(utils.LoginGuard.CaptchaAfter)
+}
+
+// checkLoginGuard 登录前检查锁定状态和验证码，返回 false 时已写入响应
+// Redis 不可用时放行，避免因限流组件故障导致无法登录
+func checkLoginGuard(c *gin.Context, scope, username, captchaToken string) bool {
+	ip := c.ClientIP()
+	username = strings.ToLower(username)
+
+	remaining, err := utils.LoginLockRemaining(scope, ip, username)
+	if err != nil {
+		log.Printf("login guard: check lock failed: %v", err)
+		return true
+	}
+	if remaining > 0 {
+		abortTooManyAttempts(c, remaining, "登录失败次数过多，账号已临时锁定")
+		return false
+	}
+
+	failures, err := utils.LoginFailureCount(scope, ip, username)
+	if err != nil {
+		log.Printf("login guard: count failures failed: %v", err)
+		return true
+	}
+	if !captchaRequired(failures) {
+		return true
+	}
+
+	ok, err := utils.Captcha.Verify(c.Request.Context(), captchaToken, ip)
+	if err != nil {
+		log.Printf("login guard: captcha verify failed: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "验证码服务暂不可用", "captcha_required": true})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "请完成验证码校验", "captcha_required": true})
+		return false
+	}
+	return true
+}
+
+// loginFailed 记录登录失败并写入响应，达到阈值时返回 429
+func loginFailed(c *gin.Context, scope, username, reason string, status int, message string) {
+	ip := c.ClientIP()
+	username = strings.ToLower(username)
+
+	log.Printf("login failed: scope=%s username=%q ip=%s reason=%s", scope, username, ip, reason)
+	attempt := models.LoginAttempt{
+		Scope:     scope,
+		Username:  truncate(username, 100),
+		IP:        ip,
+		UserAgent: truncate(c.Request.UserAgent(), 500),
+		Reason:    reason,
+	}
+	go global.Db.Create(&attempt)
+
+	failures, locked, err := utils.RecordLoginFailure(scope, ip, username)
+	if err != nil {
+		log.Printf("login guard: record failure failed: %v", err)
+	}
+	if locked > 0 {
+		abortTooManyAttempts(c, locked, "登录失败次数过多，账号已临时锁定")
+		return
+	}
+
+	c.JSON(status, gin.H{"error": message, "captcha_required": captchaRequired(failures)})
+}
+
+// loginSucceeded 登录成功后清除该用户名的失败记录
+func loginSucceeded(scope, username string) {
+	if err := utils.ResetLoginFailures(scope, strings.ToLower(username)); err != nil {
+		log.Printf("login guard: reset failures failed: %v", err)
+	}
+}
+
+// checkRegisterLimit 限制同一IP的注册频率，返回 false 时已写入响应
+func checkRegisterLimit(c *gin.Context) bool {
+	if utils.LoginGuard.RegisterMaxPerIP <= 0 {
+		return true
+	}
+	key := fmt.Sprintf("register:ip:%s", c.ClientIP())
+	count, err := utils.SlidingWindowHit(key, utils.LoginGuard.RegisterWindow)
+	if err != nil {
+		log.Printf("login guard: register limit failed: %v", err)
+		return true
+	}
+	if count > int64(utils.LoginGuard.RegisterMaxPerIP) {
+		abortTooManyAttempts(c, utils.LoginGuard.RegisterWindow, "注册过于频繁")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mentionMaxUsers 一篇文章或一条评论最多提及的用户数，超出的不再解析
+const mentionMaxUsers = 10
+
+// MentionSpan 内容中的一处提及，Offset 和 Length 按字符计算
+type MentionSpan struct {
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+	UserID   uint   `json:"user_id"`
+	Nickname string `json:"nickname"`
+}
+
+// resolveMentions 解析内容中的提及并匹配用户，不匹配拉黑关系中和已注销的用户
+// 昵称重名时优先匹配作者关注的用户，其次是粉丝多的用户
+func resolveMentions(authorID uint, text string) []models.Mention {
+	tokens := utils.ParseMentions(text)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var ids []uint
+	var nicknames []string
+	for _, token := range tokens {
+		if token.UserID != 0 {
+			ids = append(ids, token.UserID)
+		} else {
+			nicknames = append(nicknames, token.Nickname)
+		}
+	}
+
+	var users []models.User
+	query := global.Db.Select("id, nickname, followers_count").Where("anonymized_at IS NULL")
+	switch {
+	case len(ids) > 0 && len(nicknames) > 0:
+		query = query.Where("id IN ? OR nickname IN ?", ids, nicknames)
+	case len(ids) > 0:
+		query = query.Where("id IN ?", ids)
+	default:
+		query = query.Where("nickname IN ?", nicknames)
+	}
+	query.Find(&users)
+	if len(users) == 0 {
+		return nil
+	}
+
+	candidateIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		candidateIDs = append(candidateIDs, user.ID)
+	}
+	var followedIDs []uint
+	global.Db.Model(&models.UserFollow{}).
+		Where("follower_id = ? AND followed_id IN ?", authorID, candidateIDs).
+		Pluck("followed_id", &followedIDs)
+	followed := map[uint]bool{}
+	for _, id := range followedIDs {
+		followed[id] = true
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if followed[users[i].ID] != followed[users[j].ID] {
+			return followed[users[i].ID]
+		}
+		return users[i].FollowersCount > users[j].FollowersCount
+	})
+
+	blocked := blockedUserIDs(authorID)
+	byID := map[uint]bool{}
+	byNickname := map[string]uint{}
+	for _, user := range users {
+		if blocked[user.ID] {
+			continue
+		}
+		byID[user.ID] = true
+		if _, ok := byNickname[user.Nickname]; !ok {
+			byNickname[user.Nickname] = user.ID
+		}
+	}
+
+	var mentions []models.Mention
+	distinct := map[uint]bool{}
+	for _, token := range tokens {
+		userID := token.UserID
+		if userID == 0 {
+			userID = byNickname[token.Nickname]
+		} else if !byID[userID] {
+			userID = 0
+		}
+		if userID == 0 {
+			continue
+		}
+		if !distinct[userID] && len(distinct) >= mentionMaxUsers {
+			continue
+		}
+		distinct[userID] = true
+		mentions = append(mentions, models.Mention{
+			MentionerID: authorID,
+			MentionedID: userID,
+			Offset:      token.Offset,
+			Length:      token.Length,
+		})
+	}
+	return mentions
+}
+
+// saveMentions 解析并保存文章或评论中的提及，通知要等内容审核通过后由 notifyMentions 发送
+func saveMentions(sourceType string, sourceID, articleID, authorID uint, text string) []models.Mention {
+	mentions := resolveMentions(authorID, text)
+	if len(mentions) == 0 {
+		return nil
+	}
+	for i := range mentions {
+		mentions[i].SourceType = sourceType
+		mentions[i].SourceID = sourceID
+		mentions[i].ArticleID = articleID
+	}
+	if err := global.Db.Create(&mentions).Error; err != nil {
+		log.Printf("保存提及失败 %s#%d: %v", sourceType, sourceID, err)
+		return nil
+	}
+	return mentions
+}
+
+// replaceMentions 内容修改后删除原有提及并按新内容重新保存，同时返回修改前已被提及的用户
+func replaceMentions(sourceType string, sourceID, articleID, authorID uint, text string) ([]models.Mention, map[uint]bool) {
+	var previousIDs []uint
+	global.Db.Model(&models.Mention{}).
+		Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		Pluck("mentioned_id", &previousIDs)
+	if err := global.Db.Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		Delete(&models.Mention{}).Error; err != nil {
+		log.Printf("删除提及失败 %s#%d: %v", sourceType, sourceID, err)
+		return nil, nil
+	}
+
+	previous := make(map[uint]bool, len(previousIDs))
+	for _, id := range previousIDs {
+		previous[id] = true
+	}
+	return saveMentions(sourceType, sourceID, articleID, authorID, text), previous
+}
+
+// notifyMentions 通知被提及的用户，skip 中的用户已经收到过通知，无权查看文章的用户不会收到通知
+func notifyMentions(sourceType string, sourceID uint, article *models.Article, authorID uint, text string, mentions []models.Mention, skip map[uint]bool) {
+	if len(mentions) == 0 {
+		return
+	}
+	var articleAuthor models.User
+	if err := global.Db.Select(privacyColumns).First(&articleAuthor, article.AuthorID).Error; err != nil {
+		return
+	}
+	targetType := models.NotificationTargetArticle
+	if sourceType == models.MentionSourceComment {
+		targetType = models.NotificationTargetComment
+	}
+	notified := map[uint]bool{}
+	for _, mention := range mentions {
+		if skip[mention.MentionedID] || notified[mention.MentionedID] ||
+			!canViewContent(mention.MentionedID, &articleAuthor) {
+			continue
+		}
+		notified[mention.MentionedID] = true
+		notify(notificationEvent{
+			UserID:     mention.MentionedID,
+			ActorID:    authorID,
+			Type:       models.NotificationMention,
+			TargetType: targetType,
+			TargetID:   sourceID,
+			Preview:    text,
+		})
+	}
+}
+
+// articleMentionSpans 查询文章正文和评论中的提及，按内容分组；不返回与当前用户存在拉黑关系的用户
+func articleMentionSpans(articleID, viewerID uint) ([]MentionSpan, map[uint][]MentionSpan) {
+	var mentions []models.Mention
+	global.Db.Preload("Mentioned", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, nickname, anonymized_at")
+	}).Where("article_id = ?", articleID).Order("source_id, `offset`").Find(&mentions)
+
+	blocked := blockedUserIDs(viewerID)
+	articleSpans := make([]MentionSpan, 0)
+	commentSpans := map[uint][]MentionSpan{}
+	for _, mention := range mentions {
+		if blocked[mention.MentionedID] || mention.Mentioned.ID == 0 || mention.Mentioned.AnonymizedAt != nil {
+			continue
+		}
+		span := MentionSpan{
+			Offset:   mention.Offset,
+			Length:   mention.Length,
+			UserID:   mention.MentionedID,
+			Nickname: mention.Mentioned.Nickname,
+		}
+		if mention.SourceType == models.MentionSourceArticle {
+			articleSpans = append(articleSpans, span)
+		} else {
+			commentSpans[mention.SourceID] = append(commentSpans[mention.SourceID], span)
+		}
+	}
+	return articleSpans, commentSpans
+}
+
+// GetMentionSuggestions 提及用户时的自动补全，关注的人在前，其次是关注了自己的人和粉丝多的用户
+// keyword 为空时只返回关注的人
+func GetMentionSuggestions(c *gin.Context) {
+	userID := c.GetUint("userID")
+	keyword := c.Query("keyword")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	query := global.Db.Table("users").
+		Select("users.id, users.nickname, users.avatar, mine.id IS NOT NULL AS is_following, theirs.id IS NOT NULL AS follows_you").
+		Joins("LEFT JOIN user_follows AS mine ON mine.followed_id = users.id AND mine.follower_id = ? AND mine.deleted_at IS NULL", userID).
+		Joins("LEFT JOIN user_follows AS theirs ON theirs.follower_id = users.id AND theirs.followed_id = ? AND theirs.deleted_at IS NULL", userID).
+		Where("users.id <> ? AND users.anonymized_at IS NULL AND users.deleted_at IS NULL", userID).
+		Scopes(excludeBlockedScope("users.id", userID))
+	if keyword == "" {
+		query = query.Where("mine.id IS NOT NULL")
+	} else {
+		query = query.Where("users.nickname LIKE ?", "%"+keyword+"%")
+	}
+
+	var rows []struct {
+		ID          uint
+		Nickname    string
+		Avatar      string
+		IsFollowing bool
+		FollowsYou  bool
+	}
+	// 昵称以关键词开头的排在包含关键词的前面
+	if err := query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:  "is_following DESC, follows_you DESC, users.nickname LIKE ? DESC, users.followers_count DESC, users.id",
+		Vars: []interface{}{keyword + "%"},
+	}}).Limit(limit).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户失败"})
+		return
+	}
+
+	list := make([]FollowUserResponse, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, FollowUserResponse{
+			UserBrief:   UserBrief{ID: row.ID, Nickname: row.Nickname, Avatar: row.Avatar},
+			IsFollowing: row.IsFollowing,
+			FollowsYou:  row.FollowsYou,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
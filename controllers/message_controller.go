@@ -0,0 +1,443 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 私信参数
+const (
+	messageMaxLength     = 2000 // 文字消息最大字数
+	messagePageSize      = 30
+	messagePageSizeMax   = 100
+	messagePreviewLength = 50
+)
+
+// SendMessageRequest 发送私信请求，content 和 picture_id 二选一
+type SendMessageRequest struct {
+	RecipientID uint   `json:"recipient_id" binding:"required"`
+	Content     string `json:"content"`
+	PictureID   uint   `json:"picture_id"`
+}
+
+// MessagePicture 图片消息中的图片
+type MessagePicture struct {
+	ID  uint   `json:"id"`
+	URL string `json:"url"`
+}
+
+// MessageResponse 私信消息
+type MessageResponse struct {
+	ID             uint            `json:"id"`
+	ConversationID uint            `json:"conversation_id"`
+	SenderID       uint            `json:"sender_id"`
+	Type           string          `json:"type"`
+	Content        string          `json:"content"`
+	Picture        *MessagePicture `json:"picture,omitempty"`
+	IsRead         bool            `json:"is_read"` // 对方是否已读，仅对自己发送的消息有意义
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// ConversationResponse 会话列表中的会话
+type ConversationResponse struct {
+	ID            uint             `json:"id"`
+	Peer          *UserBrief       `json:"peer"`
+	LastMessage   *MessageResponse `json:"last_message"`
+	UnreadCount   int              `json:"unread_count"`
+	LastMessageAt *time.Time       `json:"last_message_at"`
+}
+
+func newMessageResponse(message *models.Message, peerLastReadID uint) *MessageResponse {
+	response := &MessageResponse{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Type:           message.Type,
+		Content:        message.Content,
+		IsRead:         message.ID <= peerLastReadID,
+		CreatedAt:      message.CreatedAt,
+	}
+	if message.Picture != nil {
+		response.Picture = &MessagePicture{ID: message.Picture.ID, URL: message.Picture.URL}
+	}
+	return response
+}
+
+// messagePreview 会话列表和推送中显示的消息摘要
+func messagePreview(message *models.Message) string {
+	if message.Type == models.MessagePicture {
+		return "[图片]"
+	}
+	return truncateRunes(message.Content, messagePreviewLength)
+}
+
+// conversationPair 会话中的两个用户按ID从小到大排列
+func conversationPair(userID, otherID uint) (uint, uint) {
+	if userID < otherID {
+		return userID, otherID
+	}
+	return otherID, userID
+}
+
+// findOrCreateConversation 查找两人的会话，不存在时创建会话和双方的成员记录。
+// 两人同时发出第一条消息时，唯一索引冲突的一方忽略插入并重新读取对方创建的会话；
+// 需要在事务外调用，事务内的一致性读看不到其他事务刚提交的会话
+func findOrCreateConversation(userID, otherID uint) (*models.Conversation, error) {
+	low, high := conversationPair(userID, otherID)
+	var conversation models.Conversation
+	err := global.Db.Where("user_low_id = ? AND user_high_id = ?", low, high).First(&conversation).Error
+	if err == nil {
+		return &conversation, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if err := global.Db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Conversation{UserLowID: low, UserHighID: high}).Error; err != nil {
+		return nil, err
+	}
+	if err := global.Db.Where("user_low_id = ? AND user_high_id = ?", low, high).First(&conversation).Error; err != nil {
+		return nil, err
+	}
+	members := []models.ConversationMember{
+		{ConversationID: conversation.ID, UserID: userID, PeerID: otherID},
+		{ConversationID: conversation.ID, UserID: otherID, PeerID: userID},
+	}
+	if err := global.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error; err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// loadConversationMember 查询当前用户在会话中的成员记录，返回 false 时已写入响应
+func loadConversationMember(c *gin.Context) (*models.ConversationMember, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的会话ID"})
+		return nil, false
+	}
+	var member models.ConversationMember
+	if err := global.Db.Where("conversation_id = ? AND user_id = ?", id, c.GetUint("userID")).
+		First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+		return nil, false
+	}
+	return &member, true
+}
+
+// peerLastReadMessageID 对方已读到的消息
+func peerLastReadMessageID(conversationID, peerID uint) uint {
+	var peer models.ConversationMember
+	global.Db.Select("last_read_message_id").
+		Where("conversation_id = ? AND user_id = ?", conversationID, peerID).
+		First(&peer)
+	return peer.LastReadMessageID
+}
+
+// visibleMessagesScope 过滤当前用户删除的消息
+func visibleMessagesScope(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("messages.id NOT IN (?)",
+			global.Db.Model(&models.MessageDeletion{}).Select("message_id").Where("user_id = ?", userID))
+	}
+}
+
+// SendMessage 发送私信，会话不存在时自动创建
+func SendMessage(c *gin.Context) {
+	var req SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	req.Content = strings.TrimSpace(req.Content)
+	if (req.Content == "") == (req.PictureID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "消息内容和图片必须且只能填写一项"})
+		return
+	}
+	if utf8.RuneCountInString(req.Content) > messageMaxLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "消息不能超过2000字"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	if req.RecipientID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能给自己发送私信"})
+		return
+	}
+	var recipient models.User
+	if err := global.Db.Select(privacyColumns).
+		Where("anonymized_at IS NULL").
+		First(&recipient, req.RecipientID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if isBlocked(userID, recipient.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "你无法与该用户互动"})
+		return
+	}
+
+	// 已有会话时对方发过的消息允许直接回复
+	var conversationID uint
+	low, high := conversationPair(userID, recipient.ID)
+	global.Db.Model(&models.Conversation{}).
+		Where("user_low_id = ? AND user_high_id = ?", low, high).
+		Pluck("id", &conversationID)
+	if allowed, reason := canMessage(userID, &recipient, conversationID); !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return
+	}
+
+	message := models.Message{SenderID: userID, Type: models.MessageText, Content: req.Content}
+	if req.PictureID != 0 {
+		var picture models.Picture
+		if err := global.Db.Where("id = ? AND user_id = ? AND moderation_status <> ?", req.PictureID, userID, models.ModerationHide).
+			First(&picture).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "图片不存在或不属于当前用户"})
+			return
+		}
+		message.Type = models.MessagePicture
+		message.PictureID = &picture.ID
+		message.Picture = &picture
+	}
+
+	conversation, err := findOrCreateConversation(userID, recipient.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送私信失败"})
+		return
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		message.ConversationID = conversation.ID
+		if err := tx.Omit("Picture").Create(&message).Error; err != nil {
+			return err
+		}
+
+		now := message.CreatedAt
+		if err := tx.Model(conversation).Updates(map[string]interface{}{
+			"last_message_id": message.ID,
+			"last_message_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		// 自己发送的消息视为已读
+		if err := tx.Model(&models.ConversationMember{}).
+			Where("conversation_id = ? AND user_id = ?", conversation.ID, userID).
+			Updates(map[string]interface{}{
+				"unread_count":         0,
+				"last_read_message_id": message.ID,
+				"last_message_at":      now,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ConversationMember{}).
+			Where("conversation_id = ? AND user_id = ?", conversation.ID, recipient.ID).
+			Updates(map[string]interface{}{
+				"unread_count":    gorm.Expr("unread_count + ?", 1),
+				"last_message_at": now,
+			}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送私信失败"})
+		return
+	}
+
+	response := newMessageResponse(&message, 0)
+	// 推送给对方和自己的其他设备
+	go func() {
+		event := gin.H{"message": response, "preview": messagePreview(&message)}
+		publishStreamEvent(utils.UserStreamChannel(recipient.ID), streamEventMessage, userID, event)
+		publishStreamEvent(utils.UserStreamChannel(userID), streamEventMessage, userID, event)
+	}()
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetConversations 会话列表，按最后一条消息时间倒序
+func GetConversations(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	// 分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	// 不显示拉黑关系中的会话
+	query := global.Db.Model(&models.ConversationMember{}).
+		Where("user_id = ? AND last_message_at IS NOT NULL", userID).
+		Scopes(excludeBlockedScope("peer_id", userID))
+
+	var total int64
+	query.Count(&total)
+
+	var members []models.ConversationMember
+	if err := query.Preload("Peer", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, nickname, avatar")
+	}).Order("last_message_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取会话列表失败"})
+		return
+	}
+
+	list := make([]ConversationResponse, 0, len(members))
+	for i := range members {
+		item := ConversationResponse{
+			ID:            members[i].ConversationID,
+			Peer:          newUserBrief(&members[i].Peer),
+			UnreadCount:   members[i].UnreadCount,
+			LastMessageAt: members[i].LastMessageAt,
+		}
+		// 最后一条自己未删除的消息
+		var last models.Message
+		if err := global.Db.Preload("Picture").Scopes(visibleMessagesScope(userID)).
+			Where("conversation_id = ?", members[i].ConversationID).
+			Order("id DESC").First(&last).Error; err == nil {
+			item.LastMessage = newMessageResponse(&last, peerLastReadMessageID(last.ConversationID, members[i].PeerID))
+		}
+		list = append(list, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": list,
+		"meta": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetConversationMessages 会话中的消息，按ID倒序，before_id 为上一页最后一条消息的ID
+func GetConversationMessages(c *gin.Context) {
+	member, ok := loadConversationMember(c)
+	if !ok {
+		return
+	}
+	userID := c.GetUint("userID")
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(messagePageSize)))
+	if limit < 1 || limit > messagePageSizeMax {
+		limit = messagePageSize
+	}
+	query := global.Db.Preload("Picture").Scopes(visibleMessagesScope(userID)).
+		Where("conversation_id = ?", member.ConversationID)
+	if beforeID, err := strconv.ParseUint(c.Query("before_id"), 10, 32); err == nil && beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	// 多查一条判断是否还有更早的消息
+	var messages []models.Message
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取消息失败"})
+		return
+	}
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	peerLastRead := peerLastReadMessageID(member.ConversationID, member.PeerID)
+	list := make([]*MessageResponse, 0, len(messages))
+	for i := range messages {
+		list = append(list, newMessageResponse(&messages[i], peerLastRead))
+	}
+
+	var peer models.User
+	global.Db.Select("id, nickname, avatar").First(&peer, member.PeerID)
+
+	response := gin.H{
+		"data":                      list,
+		"peer":                      newUserBrief(&peer),
+		"unread_count":              member.UnreadCount,
+		"peer_last_read_message_id": peerLastRead,
+		"has_more":                  hasMore,
+	}
+	if hasMore {
+		response["next_before_id"] = messages[len(messages)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// MarkConversationRead 会话标记为已读，并向对方发送已读回执
+func MarkConversationRead(c *gin.Context) {
+	member, ok := loadConversationMember(c)
+	if !ok {
+		return
+	}
+
+	var conversation models.Conversation
+	if err := global.Db.Select("id, last_message_id").First(&conversation, member.ConversationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+		return
+	}
+	if err := global.Db.Model(member).Updates(map[string]interface{}{
+		"unread_count":         0,
+		"last_read_message_id": conversation.LastMessageID,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+
+	if conversation.LastMessageID > 0 {
+		go publishStreamEvent(utils.UserStreamChannel(member.PeerID), streamEventMessageRead, member.UserID, gin.H{
+			"conversation_id":      conversation.ID,
+			"reader_id":            member.UserID,
+			"last_read_message_id": conversation.LastMessageID,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已标记为已读", "last_read_message_id": conversation.LastMessageID})
+}
+
+// GetUnreadMessageCount 未读私信总数
+func GetUnreadMessageCount(c *gin.Context) {
+	userID := c.GetUint("userID")
+	var total int64
+	global.Db.Model(&models.ConversationMember{}).
+		Where("user_id = ?", userID).
+		Scopes(excludeBlockedScope("peer_id", userID)).
+		Select("COALESCE(SUM(unread_count), 0)").
+		Scan(&total)
+	c.JSON(http.StatusOK, gin.H{"unread_count": total})
+}
+
+// DeleteMessage 删除消息，仅对自己隐藏，对方仍可看到
+func DeleteMessage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的消息ID"})
+		return
+	}
+	userID := c.GetUint("userID")
+
+	// 只能删除自己所在会话中的消息
+	var message models.Message
+	if err := global.Db.Select("messages.id").
+		Joins("JOIN conversation_members ON conversation_members.conversation_id = messages.conversation_id").
+		Where("messages.id = ? AND conversation_members.user_id = ?", id, userID).
+		First(&message).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "消息不存在"})
+		return
+	}
+
+	deletion := models.MessageDeletion{MessageID: message.ID, UserID: userID}
+	if err := global.Db.Where(&deletion).FirstOrCreate(&deletion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除消息失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "消息已删除"})
+}
@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+)
+
+// moderationTimeout 单次异步审核的最长耗时
+const moderationTimeout = 30 * time.Second
+
+// decideModerationAction 根据审核结果和阈值得出审核结论
+func decideModerationAction(result *utils.ModerationResult) string {
+	switch result.Action {
+	case models.ModerationAllow, models.ModerationHold, models.ModerationHide:
+		return result.Action
+	}
+
+	score := result.MaxScore()
+	hide := config.AppConfig.Moderation.HideThreshold
+	hold := config.AppConfig.Moderation.HoldThreshold
+	if hide > 0 && score >= hide {
+		return models.ModerationHide
+	}
+	if hold > 0 && score >= hold {
+		return models.ModerationHold
+	}
+	return models.ModerationAllow
+}
+
+// moderationModel 返回审核对象对应的模型
+func moderationModel(targetType string) interface{} {
+	switch targetType {
+	case models.ModerationTargetArticle:
+		return &models.Article{}
+	case models.ModerationTargetComment:
+		return &models.Comment{}
+	case models.ModerationTargetPicture:
+		return &models.Picture{}
+	}
+	return nil
+}
+
+// initialModerationStatus 新内容的初始审核状态，配置了审核服务时先待审核，审核通过后才公开
+func initialModerationStatus() string {
+	if utils.ContentModerator.Name() == (utils.NoopModerator{}).Name() {
+		return models.ModerationAllow
+	}
+	return models.ModerationHold
+}
+
+// hasManualReview 内容是否已经人工复核过，人工结论优先于自动审核
+func hasManualReview(targetType string, targetID uint) bool {
+	var count int64
+	global.Db.Model(&models.ModerationRecord{}).
+		Where("target_type = ? AND target_id = ? AND reviewer_id <> ''", targetType, targetID).
+		Count(&count)
+	return count > 0
+}
+
+// currentModerationStatus 查询对象当前的审核状态
+func currentModerationStatus(targetType string, targetID uint) string {
+	model := moderationModel(targetType)
+	if model == nil {
+		return ""
+	}
+	var current struct{ ModerationStatus string }
+	global.Db.Model(model).Select("moderation_status").Where("id = ?", targetID).Take(&current)
+	return current.ModerationStatus
+}
+
+// applyModeration 保存审核记录并更新对象的可见状态，返回对象审核后的状态；
+// 审核失败或已有人工复核结论时不修改状态
+func applyModeration(targetType string, targetID uint, result *utils.ModerationResult, moderateErr error) string {
+	record := models.ModerationRecord{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Provider:   utils.ContentModerator.Name(),
+		Action:     models.ModerationAllow,
+	}
+
+	status := ""
+	if moderateErr != nil {
+		// 审核服务不可用时保持原状态（配置了审核服务时新内容为待审核），只记录错误
+		record.Error = moderateErr.Error()
+		log.Printf("内容审核失败 %s#%d: %v\n", targetType, targetID, moderateErr)
+		status = currentModerationStatus(targetType, targetID)
+	} else {
+		labels, _ := json.Marshal(result.Labels)
+		scores, _ := json.Marshal(result.Scores)
+		record.Labels = string(labels)
+		record.Scores = string(scores)
+		record.Action = decideModerationAction(result)
+
+		if hasManualReview(targetType, targetID) {
+			// 管理员的复核结论不被自动审核覆盖，只追加审核记录
+			status = currentModerationStatus(targetType, targetID)
+		} else {
+			if model := moderationModel(targetType); model != nil {
+				global.Db.Model(model).Where("id = ?", targetID).Update("moderation_status", record.Action)
+			}
+			status = record.Action
+		}
+	}
+
+	global.Db.Create(&record)
+	return status
+}
+
+// moderateTextThen 异步审核文本内容，审核后内容可见时调用 onAllow，
+// 用于推送、通知等会把内容展示给其他用户的操作，避免待复核或已隐藏的内容提前泄露
+func moderateTextThen(targetType string, targetID uint, text string, onAllow func()) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), moderationTimeout)
+		defer cancel()
+
+		result, err := utils.ContentModerator.ModerateText(ctx, text)
+		if status := applyModeration(targetType, targetID, result, err); status == models.ModerationAllow && onAllow != nil {
+			onAllow()
+		}
+	}()
+}
+
+// moderatePictureAsync 异步审核已上传到 MinIO 的图片
+func moderatePictureAsync(pictureID uint, objectName, contentType string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), moderationTimeout)
+		defer cancel()
+
+		object, err := global.MinIOClient.GetObject(ctx, global.MinIOConf.BucketName, objectName, minio.GetObjectOptions{})
+		if err != nil {
+			applyModeration(models.ModerationTargetPicture, pictureID, nil, err)
+			return
+		}
+		defer object.Close()
+
+		data, err := io.ReadAll(object)
+		if err != nil {
+			applyModeration(models.ModerationTargetPicture, pictureID, nil, err)
+			return
+		}
+
+		result, err := utils.ContentModerator.ModerateImage(ctx, data, contentType)
+		applyModeration(models.ModerationTargetPicture, pictureID, result, err)
+	}()
+}
+
+// AdminGetModerationQueue 获取待复核/已隐藏的内容列表
+func AdminGetModerationQueue(c *gin.Context) {
+	targetType := c.DefaultQuery("type", models.ModerationTargetArticle)
+	status := c.DefaultQuery("status", models.ModerationHold)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	model := moderationModel(targetType)
+	if model == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容类型"})
+		return
+	}
+
+	var total int64
+	global.Db.Model(model).Where("moderation_status = ?", status).Count(&total)
+
+	var items []gin.H
+	query := global.Db.Model(model).Where("moderation_status = ?", status).
+		Offset(offset).Limit(limit).Order("created_at DESC")
+
+	switch targetType {
+	case models.ModerationTargetArticle:
+		var articles []models.Article
+		query.Select("id, title, content, author_id, created_at, moderation_status").Find(&articles)
+		for _, article := range articles {
+			items = append(items, gin.H{
+				"id":                article.ID,
+				"title":             article.Title,
+				"content":           article.Content,
+				"user_id":           article.AuthorID,
+				"created_at":        article.CreatedAt,
+				"moderation_status": article.ModerationStatus,
+			})
+		}
+	case models.ModerationTargetComment:
+		var comments []models.Comment
+		query.Select("id, content, user_id, article_id, created_at, moderation_status").Find(&comments)
+		for _, comment := range comments {
+			items = append(items, gin.H{
+				"id":                comment.ID,
+				"content":           comment.Content,
+				"user_id":           comment.UserID,
+				"article_id":        comment.ArticleID,
+				"created_at":        comment.CreatedAt,
+				"moderation_status": comment.ModerationStatus,
+			})
+		}
+	case models.ModerationTargetPicture:
+		var pictures []models.Picture
+		query.Select("id, url, user_id, created_at, moderation_status").Find(&pictures)
+		for _, picture := range pictures {
+			items = append(items, gin.H{
+				"id":                picture.ID,
+				"url":               picture.URL,
+				"user_id":           picture.UserID,
+				"created_at":        picture.CreatedAt,
+				"moderation_status": picture.ModerationStatus,
+			})
+		}
+	}
+
+	// 附上最近一次审核记录
+	for _, item := range items {
+		var record models.ModerationRecord
+		if err := global.Db.Where("target_type = ? AND target_id = ?", targetType, item["id"]).
+			Order("id DESC").First(&record).Error; err == nil {
+			item["labels"] = json.RawMessage(orEmptyJSON(record.Labels, "[]"))
+			item["scores"] = json.RawMessage(orEmptyJSON(record.Scores, "{}"))
+			item["provider"] = record.Provider
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"page":  page,
+		"limit": limit,
+		"items": items,
+	})
+}
+
+// orEmptyJSON 空字符串时返回默认的JSON字面量
+func orEmptyJSON(raw, fallback string) string {
+	if raw == "" || raw == "null" {
+		return fallback
+	}
+	return raw
+}
+
+// ReviewContentRequest 人工复核请求
+type ReviewContentRequest struct {
+	Action string `json:"action" binding:"required,oneof=allow hold hide"`
+}
+
+// AdminReviewContent 人工复核内容，覆盖自动审核结论
+func AdminReviewContent(c *gin.Context) {
+	targetType := c.Param("type")
+	model := moderationModel(targetType)
+	if model == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容类型"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容ID"})
+		return
+	}
+
+	var req ReviewContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	var count int64
+	global.Db.Model(model).Where("id = ?", id).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "内容不存在"})
+		return
+	}
+
+	if err := global.Db.Model(model).Where("id = ?", id).Update("moderation_status", req.Action).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新审核状态失败"})
+		return
+	}
+
+	global.Db.Create(&models.ModerationRecord{
+		TargetType: targetType,
+		TargetID:   uint(id),
+		Provider:   "manual",
+		Action:     req.Action,
+		ReviewerID: "admin",
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "审核状态已更新", "action": req.Action})
+}
@@ -0,0 +1,392 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 通知列表分页和展示参数
+const (
+	notificationPageSize    = 20
+	notificationMaxPageSize = 50
+	notificationShownActors = 3   // 合并通知中展示的最近用户数
+	notificationPreviewLen  = 100 // 评论摘要的字符数
+)
+
+// 同一对象上的未读通知会合并，评论和提及每条单独展示
+var aggregatedNotificationTypes = map[string]bool{
+	models.NotificationLike:        true,
+	models.NotificationFavorite:    true,
+	models.NotificationCommentLike: true,
+	models.NotificationFollow:      true,
+}
+
+// 通知文案中的动作
+var notificationVerbs = map[string]string{
+	models.NotificationLike:           "赞了你的文章",
+	models.NotificationFavorite:       "收藏了你的文章",
+	models.NotificationComment:        "评论了你的文章",
+	models.NotificationCommentLike:    "赞了你的评论",
+	models.NotificationFollow:         "关注了你",
+	models.NotificationFollowRequest:  "请求关注你",
+	models.NotificationFollowAccepted: "同意了你的关注请求",
+	models.NotificationMention:        "提到了你",
+}
+
+// notificationEvent 一次需要通知接收者的操作
+type notificationEvent struct {
+	UserID     uint // 接收者
+	ActorID    uint // 触发的用户
+	Type       string
+	TargetType string
+	TargetID   uint
+	Preview    string
+}
+
+// NotificationResponse 通知信息
+type NotificationResponse struct {
+	ID         uint        `json:"id"`
+	Type       string      `json:"type"`
+	TargetType string      `json:"target_type"`
+	TargetID   uint        `json:"target_id"`
+	Actor      *UserBrief  `json:"actor"`
+	Actors     []UserBrief `json:"actors"` // 最近的几位用户
+	ActorCount int         `json:"actor_count"`
+	Preview    string      `json:"preview,omitempty"`
+	Summary    string      `json:"summary"`
+	Read       bool        `json:"read"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// notificationEnabled 接收者是否接收该类型的通知，未设置时默认接收
+func notificationEnabled(userID uint, notificationType string) bool {
+	var preference models.NotificationPreference
+	if err := global.Db.Where("user_id = ? AND type = ?", userID, notificationType).First(&preference).Error; err != nil {
+		return true
+	}
+	return preference.Enabled
+}
+
+// notify 写入通知。自己的操作、存在拉黑关系、触发者被接收者静音以及接收者关闭了该类型时不通知
+func notify(event notificationEvent) {
+	if event.UserID == 0 || event.UserID == event.ActorID {
+		return
+	}
+	if isBlocked(event.UserID, event.ActorID) || hasMuted(event.UserID, event.ActorID) ||
+		!notificationEnabled(event.UserID, event.Type) {
+		return
+	}
+	event.Preview = truncateRunes(event.Preview, notificationPreviewLen)
+
+	var notificationID uint
+	var actorCount int
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if aggregatedNotificationTypes[event.Type] {
+			var notification models.Notification
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("user_id = ? AND type = ? AND target_type = ? AND target_id = ? AND read_at IS NULL",
+					event.UserID, event.Type, event.TargetType, event.TargetID).
+				Order("id DESC").First(&notification).Error
+			if err == nil {
+				// 同一用户重复触发（如取消后再次点赞）不再提醒
+				var count int64
+				tx.Model(&models.NotificationActor{}).
+					Where("notification_id = ? AND actor_id = ?", notification.ID, event.ActorID).
+					Count(&count)
+				if count > 0 {
+					return nil
+				}
+				if err := tx.Create(&models.NotificationActor{NotificationID: notification.ID, ActorID: event.ActorID, CreatedAt: now}).Error; err != nil {
+					return err
+				}
+				notificationID, actorCount = notification.ID, notification.ActorCount+1
+				return tx.Model(&notification).Updates(map[string]interface{}{
+					"actor_id":    event.ActorID,
+					"actor_count": gorm.Expr("actor_count + ?", 1),
+					"preview":     event.Preview,
+					"updated_at":  now,
+				}).Error
+			}
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+		}
+
+		notification := models.Notification{
+			UserID:     event.UserID,
+			Type:       event.Type,
+			TargetType: event.TargetType,
+			TargetID:   event.TargetID,
+			ActorID:    event.ActorID,
+			ActorCount: 1,
+			Preview:    event.Preview,
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		notificationID, actorCount = notification.ID, 1
+		return tx.Create(&models.NotificationActor{NotificationID: notification.ID, ActorID: event.ActorID, CreatedAt: now}).Error
+	})
+	if err != nil {
+		log.Printf("写入通知失败 user=%d type=%s: %v", event.UserID, event.Type, err)
+		return
+	}
+	if notificationID == 0 {
+		return
+	}
+
+	// 推送给接收人的实时连接
+	if err := utils.PublishStreamEvent(utils.UserStreamChannel(event.UserID), streamEventNotification, event.ActorID, gin.H{
+		"id":          notificationID,
+		"type":        event.Type,
+		"target_type": event.TargetType,
+		"target_id":   event.TargetID,
+		"actor_count": actorCount,
+		"preview":     event.Preview,
+	}); err != nil {
+		log.Printf("推送通知失败 user=%d: %v", event.UserID, err)
+	}
+}
+
+// notificationSummary 生成通知文案，如"小明 和其他 12 人赞了你的文章"
+func notificationSummary(notification *models.Notification, actorName string) string {
+	verb := notificationVerbs[notification.Type]
+	if notification.ActorCount > 1 {
+		return fmt.Sprintf("%s 和其他 %d 人%s", actorName, notification.ActorCount-1, verb)
+	}
+	return actorName + " " + verb
+}
+
+// encodeNotificationCursor 游标为最后一条通知的更新时间（毫秒）和ID
+func encodeNotificationCursor(notification *models.Notification) string {
+	return fmt.Sprintf("%d_%d", notification.UpdatedAt.UnixMilli(), notification.ID)
+}
+
+func decodeNotificationCursor(cursor string) (time.Time, uint, bool) {
+	rawTime, rawID, ok := strings.Cut(cursor, "_")
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	millis, err := strconv.ParseInt(rawTime, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	id, err := strconv.ParseUint(rawID, 10, 32)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return time.UnixMilli(millis), uint(id), true
+}
+
+// GetNotifications 通知列表，按最近更新时间倒序，使用 cursor 翻页
+func GetNotifications(c *gin.Context) {
+	userID := c.GetUint("userID")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(notificationPageSize)))
+	if limit < 1 || limit > notificationMaxPageSize {
+		limit = notificationPageSize
+	}
+
+	query := global.Db.Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	if notificationType := c.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		updatedAt, id, ok := decodeNotificationCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的游标"})
+			return
+		}
+		query = query.Where("updated_at < ? OR (updated_at = ? AND id < ?)", updatedAt, updatedAt, id)
+	}
+
+	// 多查一条判断是否还有下一页
+	var notifications []models.Notification
+	if err := query.Preload("Actor", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, nickname, avatar")
+	}).Order("updated_at DESC, id DESC").Limit(limit + 1).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通知失败"})
+		return
+	}
+	nextCursor := ""
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		nextCursor = encodeNotificationCursor(&notifications[limit-1])
+	}
+
+	// 不展示拉黑关系中和已静音的用户
+	hidden := hiddenUserIDs(userID)
+	hiddenIDs := []uint{0}
+	for id := range hidden {
+		hiddenIDs = append(hiddenIDs, id)
+	}
+
+	list := make([]NotificationResponse, 0, len(notifications))
+	for i := range notifications {
+		notification := &notifications[i]
+
+		var actors []models.User
+		global.Db.Model(&models.User{}).Select("users.id, users.nickname, users.avatar").
+			Joins("JOIN notification_actors ON notification_actors.actor_id = users.id").
+			Where("notification_actors.notification_id = ? AND users.id NOT IN ?", notification.ID, hiddenIDs).
+			Order("notification_actors.created_at DESC").Limit(notificationShownActors).
+			Find(&actors)
+
+		actor := newUserBrief(&notification.Actor)
+		if hidden[notification.ActorID] {
+			if len(actors) == 0 {
+				continue
+			}
+			actor = newUserBrief(&actors[0])
+		}
+		actorName := deletedUserNickname
+		if actor != nil {
+			actorName = actor.Nickname
+		}
+
+		briefs := make([]UserBrief, 0, len(actors))
+		for j := range actors {
+			briefs = append(briefs, *newUserBrief(&actors[j]))
+		}
+		list = append(list, NotificationResponse{
+			ID:         notification.ID,
+			Type:       notification.Type,
+			TargetType: notification.TargetType,
+			TargetID:   notification.TargetID,
+			Actor:      actor,
+			Actors:     briefs,
+			ActorCount: notification.ActorCount,
+			Preview:    notification.Preview,
+			Summary:    notificationSummary(notification, actorName),
+			Read:       notification.ReadAt != nil,
+			CreatedAt:  notification.CreatedAt,
+			UpdatedAt:  notification.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        list,
+		"next_cursor": nextCursor,
+	})
+}
+
+// GetUnreadNotificationCount 未读通知数，按类型分别统计
+func GetUnreadNotificationCount(c *gin.Context) {
+	var rows []struct {
+		Type  string
+		Total int64
+	}
+	if err := global.Db.Model(&models.Notification{}).
+		Select("type, COUNT(*) AS total").
+		Where("user_id = ? AND read_at IS NULL", c.GetUint("userID")).
+		Group("type").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取未读数失败"})
+		return
+	}
+
+	var total int64
+	byType := gin.H{}
+	for _, row := range rows {
+		total += row.Total
+		byType[row.Type] = row.Total
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "by_type": byType})
+}
+
+// MarkNotificationRead 标记单条通知已读
+func MarkNotificationRead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的通知ID"})
+		return
+	}
+
+	var notification models.Notification
+	if err := global.Db.Where("user_id = ?", c.GetUint("userID")).First(&notification, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+		return
+	}
+	if notification.ReadAt == nil {
+		if err := global.Db.Model(&notification).Update("read_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已读"})
+}
+
+// MarkAllNotificationsRead 全部标记已读，传入 type 时只处理该类型
+func MarkAllNotificationsRead(c *gin.Context) {
+	query := global.Db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", c.GetUint("userID"))
+	if notificationType := c.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+	result := query.Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已全部标记为已读", "count": result.RowsAffected})
+}
+
+// notificationPreferences 每种通知类型是否接收
+func notificationPreferences(userID uint) map[string]bool {
+	preferences := make(map[string]bool, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		preferences[notificationType] = true
+	}
+	var rows []models.NotificationPreference
+	global.Db.Where("user_id = ?", userID).Find(&rows)
+	for _, row := range rows {
+		preferences[row.Type] = row.Enabled
+	}
+	return preferences
+}
+
+// GetNotificationPreferences 获取通知偏好
+func GetNotificationPreferences(c *gin.Context) {
+	c.JSON(http.StatusOK, notificationPreferences(c.GetUint("userID")))
+}
+
+// UpdateNotificationPreferences 修改通知偏好，请求体为 {"类型": 是否接收}
+func UpdateNotificationPreferences(c *gin.Context) {
+	var req map[string]bool
+	if err := c.ShouldBindJSON(&req); err != nil || len(req) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	rows := make([]models.NotificationPreference, 0, len(req))
+	for notificationType, enabled := range req {
+		if _, ok := notificationVerbs[notificationType]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未知的通知类型: " + notificationType})
+			return
+		}
+		rows = append(rows, models.NotificationPreference{UserID: userID, Type: notificationType, Enabled: enabled})
+	}
+
+	if err := global.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存通知设置失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "通知设置已保存", "preferences": notificationPreferences(userID)})
+}
@@ -0,0 +1,506 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	oauthStateTTL        = 10 * time.Minute
+	oauthExchangeTimeout = 15 * time.Second
+)
+
+var (
+	errIdentityTaken    = errors.New("identity is linked to another user")
+	errProviderLinked   = errors.New("user already linked another account of this provider")
+	errLastLoginMethod  = errors.New("identity is the only login method")
+	errIdentityNotFound = errors.New("identity not found")
+)
+
+// oauthState 跳转授权期间保存在 Redis 中的状态
+type oauthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	RedirectURI  string `json:"redirect_uri"`
+	LinkUserID   uint   `json:"link_user_id,omitempty"` // 不为 0 时表示绑定到该用户，而不是登录
+}
+
+// OAuthCallbackRequest 第三方授权回调参数
+type OAuthCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// LinkIdentityRequest 直接使用 code 绑定（微信小程序等无需跳转的方式）
+type LinkIdentityRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func oauthStateKey(state string) string {
+	return fmt.Sprintf("oauth:state:%s", state)
+}
+
+// oauthRedirectURI 第三方授权完成后跳回的前端地址
+func oauthRedirectURI(name string) string {
+	if cfg, ok := config.AppConfig.OAuth.Providers[name]; ok && cfg.RedirectURL != "" {
+		return cfg.RedirectURL
+	}
+	return strings.TrimRight(config.AppConfig.OAuth.RedirectBaseURL, "/") + "/" + name
+}
+
+// redirectProvider 查找需要跳转授权的登录方式，返回 false 时已写入响应
+func redirectProvider(c *gin.Context, name string) (utils.RedirectAuthProvider, bool) {
+	provider, ok := utils.AuthProviders[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "不支持的登录方式"})
+		return nil, false
+	}
+	redirect, ok := provider.(utils.RedirectAuthProvider)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该登录方式不支持跳转授权"})
+		return nil, false
+	}
+	return redirect, true
+}
+
+// beginOAuth 生成 state、nonce 和 PKCE 参数并返回授权地址
+func beginOAuth(c *gin.Context, name string, linkUserID uint) {
+	provider, ok := redirectProvider(c, name)
+	if !ok {
+		return
+	}
+
+	state := utils.RandomID()
+	st := oauthState{
+		Provider:     name,
+		Nonce:        utils.RandomID(),
+		CodeVerifier: utils.RandomID() + utils.RandomID(),
+		RedirectURI:  oauthRedirectURI(name),
+		LinkUserID:   linkUserID,
+	}
+	authURL, err := provider.AuthCodeURL(state, st.Nonce, st.RedirectURI, utils.PKCEChallenge(st.CodeVerifier))
+	if err != nil {
+		log.Printf("oauth %s: build authorize url failed: %v", name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "获取授权地址失败"})
+		return
+	}
+
+	data, _ := json.Marshal(st)
+	if err := global.RedisDB.Set(oauthStateKey(state), data, oauthStateTTL).Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存授权状态失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"authorize_url": authURL,
+		"state":         state,
+		"expires_in":    int(oauthStateTTL.Seconds()),
+	})
+}
+
+// consumeOAuthState 取出并删除授权状态，每个 state 只能使用一次
+func consumeOAuthState(state string) (*oauthState, error) {
+	pipe := global.RedisDB.TxPipeline()
+	get := pipe.Get(oauthStateKey(state))
+	pipe.Del(oauthStateKey(state))
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+
+	var st oauthState
+	if err := json.Unmarshal([]byte(get.Val()), &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// exchangeIdentity 用授权码向第三方换取账号信息
+func exchangeIdentity(c *gin.Context, provider utils.AuthProvider, req utils.AuthExchange) (*utils.ExternalIdentity, error) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), oauthExchangeTimeout)
+	defer cancel()
+
+	identity, err := provider.Exchange(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("provider returned an empty subject")
+	}
+	return identity, nil
+}
+
+// truncateRunes 按字符截断，避免截断多字节字符
+func truncateRunes(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return value
+}
+
+// identityProfile 第三方账号信息中需要同步到绑定记录的字段
+func identityProfile(identity *utils.ExternalIdentity) map[string]interface{} {
+	return map[string]interface{}{
+		"email":      truncate(identity.Email, 100),
+		"name":       truncateRunes(identity.Name, 100),
+		"avatar_url": truncate(identity.AvatarURL, 500),
+	}
+}
+
+// syncWeChatColumns 绑定或登录微信时同步用户表中的微信字段
+func syncWeChatColumns(tx *gorm.DB, userID uint, identity *utils.ExternalIdentity) error {
+	if identity.Provider != "wechat" {
+		return nil
+	}
+	updates := map[string]interface{}{
+		"open_id":     identity.Subject,
+		"session_key": identity.SessionKey,
+	}
+	if identity.UnionID != "" {
+		updates["union_id"] = identity.UnionID
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error
+}
+
+// findIdentityUser 根据第三方账号查找已绑定的用户，并刷新绑定记录
+func findIdentityUser(identity *utils.ExternalIdentity) (*models.User, error) {
+	var record models.UserIdentity
+	if err := global.Db.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&record).Error; err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := global.Db.First(&user, record.UserID).Error; err != nil {
+		return nil, fmt.Errorf("load user %d: %v", record.UserID, err)
+	}
+
+	now := time.Now()
+	updates := identityProfile(identity)
+	updates["last_login_at"] = now
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&record).Updates(updates).Error; err != nil {
+			return err
+		}
+		return syncWeChatColumns(tx, user.ID, identity)
+	})
+	if err != nil {
+		log.Printf("oauth %s: refresh identity of user %d failed: %v", identity.Provider, user.ID, err)
+	}
+	return &user, nil
+}
+
+// linkIdentity 将第三方账号绑定到用户，已绑定到其他用户或同平台已绑定其他账号时返回错误
+func linkIdentity(tx *gorm.DB, userID uint, identity *utils.ExternalIdentity) (*models.UserIdentity, error) {
+	var existing models.UserIdentity
+	err := tx.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&existing).Error
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, errIdentityTaken
+		}
+		if err := tx.Model(&existing).Updates(identityProfile(identity)).Error; err != nil {
+			return nil, err
+		}
+		return &existing, syncWeChatColumns(tx, userID, identity)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var count int64
+	if err := tx.Model(&models.UserIdentity{}).Where("user_id = ? AND provider = ?", userID, identity.Provider).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errProviderLinked
+	}
+	// 旧版微信登录只在用户表中保存了 openid
+	if identity.Provider == "wechat" {
+		if err := tx.Unscoped().Model(&models.User{}).Where("open_id = ? AND id <> ?", identity.Subject, userID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errIdentityTaken
+		}
+	}
+
+	now := time.Now()
+	record := models.UserIdentity{
+		UserID:      userID,
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		Email:       truncate(identity.Email, 100),
+		Name:        truncateRunes(identity.Name, 100),
+		AvatarURL:   truncate(identity.AvatarURL, 500),
+		LastLoginAt: &now,
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, syncWeChatColumns(tx, userID, identity)
+}
+
+// createUser 创建用户，空的手机号和邮箱不写入，避免唯一索引冲突
+func createUser(tx *gorm.DB, user *models.User) error {
+	var omit []string
+	if user.Phone == "" {
+		omit = append(omit, "Phone")
+	}
+	if user.Email == "" {
+		omit = append(omit, "Email")
+	}
+	if len(omit) > 0 {
+		tx = tx.Omit(omit...)
+	}
+	return tx.Create(user).Error
+}
+
+// resolveIdentityUser 第三方登录：已绑定则直接登录，邮箱均已验证时合并到已有账号，否则创建新用户
+func resolveIdentityUser(identity *utils.ExternalIdentity) (*models.User, error) {
+	user, err := findIdentityUser(identity)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	// 第三方已验证的邮箱与本站已验证的邮箱一致时视为同一人
+	if identity.EmailVerified && identity.Email != "" {
+		var existing models.User
+		err := global.Db.Where("email = ? AND email_verified_at IS NOT NULL", identity.Email).First(&existing).Error
+		if err == nil {
+			if _, err := linkIdentity(global.Db, existing.ID, identity); err != nil {
+				return nil, err
+			}
+			return &existing, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	nickname := truncateRunes(strings.TrimSpace(identity.Name), config.AppConfig.Profile.NicknameMaxLength)
+	if nickname == "" {
+		nickname = "用户" + utils.RandomID()[:8]
+	}
+	newUser := models.User{
+		Nickname: nickname,
+		Avatar:   truncate(identity.AvatarURL, 500),
+	}
+	// 邮箱未被占用时一并保存
+	if identity.EmailVerified && identity.Email != "" {
+		var count int64
+		global.Db.Model(&models.User{}).Where("email = ?", identity.Email).Count(&count)
+		if count == 0 {
+			now := time.Now()
+			newUser.Email = identity.Email
+			newUser.EmailVerifiedAt = &now
+		}
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := createUser(tx, &newUser); err != nil {
+			return err
+		}
+		_, err := linkIdentity(tx, newUser.ID, identity)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &newUser, nil
+}
+
+// respondLinkError 绑定失败时写入响应
+func respondLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errIdentityTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "该第三方账号已绑定其他用户"})
+	case errors.Is(err, errProviderLinked):
+		c.JSON(http.StatusConflict, gin.H{"error": "已绑定该平台的其他账号，请先解绑"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "绑定失败"})
+	}
+}
+
+// OAuthAuthorize 获取第三方登录授权地址
+func OAuthAuthorize(c *gin.Context) {
+	beginOAuth(c, c.Param("provider"), 0)
+}
+
+// OAuthCallback 第三方授权回调：登录或完成绑定
+func OAuthCallback(c *gin.Context) {
+	var req OAuthCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	name := c.Param("provider")
+	st, err := consumeOAuthState(req.State)
+	if err != nil || st.Provider != name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "授权已过期，请重新登录"})
+		return
+	}
+	provider, ok := redirectProvider(c, name)
+	if !ok {
+		return
+	}
+
+	identity, err := exchangeIdentity(c, provider, utils.AuthExchange{
+		Code:         req.Code,
+		RedirectURI:  st.RedirectURI,
+		Nonce:        st.Nonce,
+		CodeVerifier: st.CodeVerifier,
+	})
+	if err != nil {
+		log.Printf("oauth %s: exchange failed: %v", name, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "第三方登录失败"})
+		return
+	}
+
+	// 已登录用户发起的绑定
+	if st.LinkUserID != 0 {
+		record, err := linkIdentity(global.Db, st.LinkUserID, identity)
+		if err != nil {
+			respondLinkError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "绑定成功", "identity": record})
+		return
+	}
+
+	user, err := resolveIdentityUser(identity)
+	if err != nil {
+		log.Printf("oauth %s: resolve user failed: %v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登录失败"})
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		startTwoFactorChallenge(c, user, name)
+		return
+	}
+	completeLogin(c, user, name)
+}
+
+// GetIdentities 当前用户绑定的第三方账号及可用的登录方式
+func GetIdentities(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var identities []models.UserIdentity
+	if err := global.Db.Where("user_id = ?", userID).Order("id").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取绑定信息失败"})
+		return
+	}
+
+	providers := make([]gin.H, 0, len(utils.AuthProviders))
+	for name, provider := range utils.AuthProviders {
+		_, redirect := provider.(utils.RedirectAuthProvider)
+		providers = append(providers, gin.H{"name": name, "redirect": redirect})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities, "providers": providers})
+}
+
+// LinkIdentityAuthorize 获取绑定第三方账号的授权地址
+func LinkIdentityAuthorize(c *gin.Context) {
+	beginOAuth(c, c.Param("provider"), c.GetUint("userID"))
+}
+
+// LinkIdentity 使用 code 直接绑定无需跳转授权的第三方账号（如微信小程序）
+func LinkIdentity(c *gin.Context) {
+	var req LinkIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	name := c.Param("provider")
+	provider, ok := utils.AuthProviders[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "不支持的登录方式"})
+		return
+	}
+	if _, redirect := provider.(utils.RedirectAuthProvider); redirect {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该登录方式需要跳转授权"})
+		return
+	}
+
+	identity, err := exchangeIdentity(c, provider, utils.AuthExchange{Code: req.Code})
+	if err != nil {
+		log.Printf("oauth %s: exchange failed: %v", name, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "第三方授权失败"})
+		return
+	}
+
+	record, err := linkIdentity(global.Db, c.GetUint("userID"), identity)
+	if err != nil {
+		respondLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "绑定成功", "identity": record})
+}
+
+// UnlinkIdentity 解绑第三方账号，不能解绑唯一的登录方式
+func UnlinkIdentity(c *gin.Context) {
+	userID := c.GetUint("userID")
+	name := c.Param("provider")
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		var record models.UserIdentity
+		if err := tx.Where("user_id = ? AND provider = ?", userID, name).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errIdentityNotFound
+			}
+			return err
+		}
+
+		var others int64
+		if err := tx.Model(&models.UserIdentity{}).Where("user_id = ? AND id <> ?", userID, record.ID).Count(&others).Error; err != nil {
+			return err
+		}
+		if user.Password == "" && others == 0 {
+			return errLastLoginMethod
+		}
+
+		if err := tx.Delete(&record).Error; err != nil {
+			return err
+		}
+		if name == "wechat" {
+			return tx.Model(&user).Updates(map[string]interface{}{
+				"open_id":     gorm.Expr("NULL"),
+				"union_id":    "",
+				"session_key": "",
+			}).Error
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "解绑成功"})
+	case errors.Is(err, errIdentityNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "未绑定该第三方账号"})
+	case errors.Is(err, errLastLoginMethod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "这是唯一的登录方式，请先设置密码或绑定其他账号"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解绑失败"})
+	}
+}
@@ -68,8 +68,9 @@ func UploadPicture(c *gin.Context) {
 
 	// 创建图片记录
 	picture := models.Picture{
-		URL:    url,
-		UserID: userID,
+		URL:              url,
+		UserID:           userID,
+		ModerationStatus: initialModerationStatus(),
 	}
 
 	if err := global.Db.Create(&picture).Error; err != nil {
@@ -77,6 +78,9 @@ func UploadPicture(c *gin.Context) {
 		return
 	}
 
+	// 异步内容审核
+	moderatePictureAsync(picture.ID, objectName, "image/jpeg")
+
 	c.JSON(http.StatusOK, UploadPictureResponse{
 		ID:  picture.ID,
 		URL: picture.URL,
@@ -127,8 +131,9 @@ func UploadPictureMultipart(c *gin.Context) {
 
 	// 创建图片记录
 	picture := models.Picture{
-		URL:    url,
-		UserID: userID,
+		URL:              url,
+		UserID:           userID,
+		ModerationStatus: initialModerationStatus(),
 	}
 
 	if err := global.Db.Create(&picture).Error; err != nil {
@@ -136,6 +141,9 @@ func UploadPictureMultipart(c *gin.Context) {
 		return
 	}
 
+	// 异步内容审核
+	moderatePictureAsync(picture.ID, objectName, file.Header.Get("Content-Type"))
+
 	c.JSON(http.StatusOK, UploadPictureResponse{
 		ID:  picture.ID,
 		URL: picture.URL,
@@ -147,6 +155,16 @@ func ServeImageFromMinIO(c *gin.Context) {
 	filename := c.Param("filename")
 	objectName := fmt.Sprintf("images/%s", filename)
 
+	// 未通过审核的图片不对外提供
+	var blocked int64
+	global.Db.Model(&models.Picture{}).
+		Where("url = ? AND moderation_status <> ?", fmt.Sprintf("/static/images/%s", filename), models.ModerationAllow).
+		Count(&blocked)
+	if blocked > 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
 	// 从 MinIO 获取图片
 	object, err := global.MinIOClient.GetObject(
 		context.Background(),
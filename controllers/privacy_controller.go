@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 判断隐私设置时需要的用户字段
+const privacyColumns = "id, private_account, hide_favorites, hide_likes, hide_following, hide_followers, comment_permission, message_mutuals_only"
+
+// PrivacySettings 隐私设置
+type PrivacySettings struct {
+	PrivateAccount     bool   `json:"private_account"`      // 私密账号，文章仅粉丝可见
+	HideFavorites      bool   `json:"hide_favorites"`       // 隐藏收藏列表
+	HideLikes          bool   `json:"hide_likes"`           // 隐藏点赞列表
+	HideFollowing      bool   `json:"hide_following"`       // 隐藏关注列表
+	HideFollowers      bool   `json:"hide_followers"`       // 隐藏粉丝列表
+	CommentPermission  string `json:"comment_permission"`   // everyone、followers、mutuals 或 nobody
+	MessageMutualsOnly bool   `json:"message_mutuals_only"` // 仅接收互相关注的用户的私信
+}
+
+// UpdatePrivacyRequest 修改隐私设置请求，未传的字段保持不变
+type UpdatePrivacyRequest struct {
+	PrivateAccount     *bool   `json:"private_account"`
+	HideFavorites      *bool   `json:"hide_favorites"`
+	HideLikes          *bool   `json:"hide_likes"`
+	HideFollowing      *bool   `json:"hide_following"`
+	HideFollowers      *bool   `json:"hide_followers"`
+	CommentPermission  *string `json:"comment_permission"`
+	MessageMutualsOnly *bool   `json:"message_mutuals_only"`
+}
+
+func newPrivacySettings(user *models.User) PrivacySettings {
+	return PrivacySettings{
+		PrivateAccount:     user.PrivateAccount,
+		HideFavorites:      user.HideFavorites,
+		HideLikes:          user.HideLikes,
+		HideFollowing:      user.HideFollowing,
+		HideFollowers:      user.HideFollowers,
+		CommentPermission:  user.CommentPermission,
+		MessageMutualsOnly: user.MessageMutualsOnly,
+	}
+}
+
+// isFollowing 是否关注了对方（已取消的关注不算）
+func isFollowing(followerID, followedID uint) bool {
+	if followerID == 0 || followedID == 0 {
+		return false
+	}
+	var count int64
+	global.Db.Model(&models.UserFollow{}).
+		Where("follower_id = ? AND followed_id = ?", followerID, followedID).
+		Count(&count)
+	return count > 0
+}
+
+// canViewContent 私密账号的文章、收藏和点赞仅本人和粉丝可见
+func canViewContent(viewerID uint, owner *models.User) bool {
+	return viewerID == owner.ID || !owner.PrivateAccount || isFollowing(viewerID, owner.ID)
+}
+
+// canViewFavorites 是否可以查看对方的收藏列表
+func canViewFavorites(viewerID uint, owner *models.User) bool {
+	return viewerID == owner.ID || (!owner.HideFavorites && canViewContent(viewerID, owner))
+}
+
+// canViewLikes 是否可以查看对方的点赞列表
+func canViewLikes(viewerID uint, owner *models.User) bool {
+	return viewerID == owner.ID || (!owner.HideLikes && canViewContent(viewerID, owner))
+}
+
+// canViewFollowList 是否可以查看对方的关注列表（followers 为 true 时为粉丝列表）
+func canViewFollowList(viewerID uint, owner *models.User, followers bool) bool {
+	if viewerID == owner.ID {
+		return true
+	}
+	if followers && owner.HideFollowers || !followers && owner.HideFollowing {
+		return false
+	}
+	return canViewContent(viewerID, owner)
+}
+
+// canComment 按作者设置判断能否评论其文章，不允许时返回提示
+func canComment(viewerID uint, author *models.User) (bool, string) {
+	if viewerID == author.ID {
+		return true, ""
+	}
+	switch author.CommentPermission {
+	case models.CommentPermissionNobody:
+		return false, "作者已关闭评论"
+	case models.CommentPermissionFollowers:
+		if !isFollowing(viewerID, author.ID) {
+			return false, "作者仅允许粉丝评论"
+		}
+	case models.CommentPermissionMutuals:
+		if !isFollowing(viewerID, author.ID) || !isFollowing(author.ID, viewerID) {
+			return false, "作者仅允许互相关注的用户评论"
+		}
+	}
+	return true, ""
+}
+
+// canMessage 按对方的隐私设置判断能否发送私信，不允许时返回提示
+// 对方已在会话中给自己发过消息时总是可以回复
+func canMessage(senderID uint, recipient *models.User, conversationID uint) (bool, string) {
+	if conversationID != 0 {
+		var count int64
+		global.Db.Model(&models.Message{}).
+			Where("conversation_id = ? AND sender_id = ?", conversationID, recipient.ID).
+			Count(&count)
+		if count > 0 {
+			return true, ""
+		}
+	}
+	if recipient.MessageMutualsOnly {
+		if !isFollowing(senderID, recipient.ID) || !isFollowing(recipient.ID, senderID) {
+			return false, "对方仅接收互相关注的用户的私信"
+		}
+		return true, ""
+	}
+	// 私密账号只接收粉丝和自己关注的人的私信
+	if recipient.PrivateAccount && !isFollowing(senderID, recipient.ID) && !isFollowing(recipient.ID, senderID) {
+		return false, "对方为私密账号，关注后可发送私信"
+	}
+	return true, ""
+}
+
+// visibleArticlesScope 过滤掉当前用户无权查看的私密账号文章
+func visibleArticlesScope(viewerID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		followed := global.Db.Model(&models.UserFollow{}).Select("followed_id").Where("follower_id = ?", viewerID)
+		private := global.Db.Model(&models.User{}).Select("id").
+			Where("private_account = ? AND id <> ?", true, viewerID).
+			Where("id NOT IN (?)", followed)
+		return db.Where("articles.author_id NOT IN (?)", private)
+	}
+}
+
+// loadVisibleArticle 查询当前用户可以互动的文章及作者的隐私设置，返回 false 时已写入响应
+func loadVisibleArticle(c *gin.Context, articleID uint) (*models.Article, *models.User, bool) {
+	article, author, status, message := visibleArticle(c.GetUint("userID"), articleID)
+	if status != http.StatusOK {
+		c.JSON(status, gin.H{"error": message})
+		return nil, nil, false
+	}
+	return article, author, true
+}
+
+// visibleArticle 检查文章对当前用户是否可见，不可见时返回状态码和错误信息
+func visibleArticle(viewerID, articleID uint) (*models.Article, *models.User, int, string) {
+	var article models.Article
+	if err := global.Db.Select("id, author_id, moderation_status").First(&article, articleID).Error; err != nil {
+		return nil, nil, http.StatusNotFound, "文章不存在"
+	}
+	// 未通过审核的文章仅作者可见
+	if article.ModerationStatus != models.ModerationAllow && article.AuthorID != viewerID {
+		return nil, nil, http.StatusNotFound, "文章不存在"
+	}
+
+	var author models.User
+	if err := global.Db.Select(privacyColumns).First(&author, article.AuthorID).Error; err != nil {
+		return nil, nil, http.StatusNotFound, "文章不存在"
+	}
+	if !canViewContent(viewerID, &author) {
+		return nil, nil, http.StatusForbidden, "作者为私密账号，关注后可查看"
+	}
+	// 存在拉黑关系时不能评论、点赞和收藏
+	if isBlocked(viewerID, author.ID) {
+		return nil, nil, http.StatusForbidden, "你无法与该用户互动"
+	}
+	return &article, &author, http.StatusOK, ""
+}
+
+// GetPrivacySettings 获取当前用户的隐私设置
+func GetPrivacySettings(c *gin.Context) {
+	var user models.User
+	if err := global.Db.Select(privacyColumns).First(&user, c.GetUint("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, newPrivacySettings(&user))
+}
+
+// UpdatePrivacySettings 修改当前用户的隐私设置
+func UpdatePrivacySettings(c *gin.Context) {
+	var req UpdatePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.PrivateAccount != nil {
+		updates["private_account"] = *req.PrivateAccount
+	}
+	if req.HideFavorites != nil {
+		updates["hide_favorites"] = *req.HideFavorites
+	}
+	if req.HideLikes != nil {
+		updates["hide_likes"] = *req.HideLikes
+	}
+	if req.HideFollowing != nil {
+		updates["hide_following"] = *req.HideFollowing
+	}
+	if req.HideFollowers != nil {
+		updates["hide_followers"] = *req.HideFollowers
+	}
+	if req.CommentPermission != nil {
+		switch *req.CommentPermission {
+		case models.CommentPermissionEveryone, models.CommentPermissionFollowers,
+			models.CommentPermissionMutuals, models.CommentPermissionNobody:
+			updates["comment_permission"] = *req.CommentPermission
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "评论权限只能是 everyone、followers、mutuals 或 nobody"})
+			return
+		}
+	}
+	if req.MessageMutualsOnly != nil {
+		updates["message_mutuals_only"] = *req.MessageMutualsOnly
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要修改的设置"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	if err := global.Db.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存隐私设置失败"})
+		return
+	}
+
+	// 改为公开账号后，等待中的关注请求全部同意
+	if req.PrivateAccount != nil && !*req.PrivateAccount {
+		approvePendingFollowRequests(userID)
+	}
+
+	var user models.User
+	global.Db.Select(privacyColumns).First(&user, userID)
+	c.JSON(http.StatusOK, gin.H{"message": "隐私设置已保存", "privacy": newPrivacySettings(&user)})
+}
@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	phonePattern = regexp.MustCompile(`^\+?[0-9]{6,20}$`)
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// 资料字段长度上限（字符数）
+const (
+	bioMaxLength      = 200
+	locationMaxLength = 50
+)
+
+// UpdateProfileRequest 修改个人资料请求，未传的字段保持不变
+type UpdateProfileRequest struct {
+	Nickname       *string `json:"nickname"`
+	Gender         *uint8  `json:"gender"` // 0:未知,1:男,2:女
+	Email          *string `json:"email"`  // 修改后需要重新验证
+	Phone          *string `json:"phone"`
+	Bio            *string `json:"bio"`
+	Birthday       *string `json:"birthday"` // YYYY-MM-DD，空字符串表示清除
+	Location       *string `json:"location"`
+	CoverPictureID *uint   `json:"cover_picture_id"` // 主页背景图，0 表示清除
+}
+
+// profileUpdate 待保存的资料修改
+type profileUpdate struct {
+	updates map[string]interface{}
+	changes []models.ProfileChange
+}
+
+func (p *profileUpdate) set(column string, oldValue, newValue interface{}, display func(interface{}) string) {
+	if display == nil {
+		display = func(v interface{}) string { return fmt.Sprint(v) }
+	}
+	if display(oldValue) == display(newValue) {
+		return
+	}
+	p.updates[column] = newValue
+	p.changes = append(p.changes, models.ProfileChange{
+		Field:    column,
+		OldValue: truncate(display(oldValue), 500),
+		NewValue: truncate(display(newValue), 500),
+	})
+}
+
+func (p *profileUpdate) changed(column string) bool {
+	_, ok := p.updates[column]
+	return ok
+}
+
+// save 保存修改并写入修改记录
+func (p *profileUpdate) save(user *models.User, ip string) error {
+	return global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(p.updates).Error; err != nil {
+			return err
+		}
+		for i := range p.changes {
+			p.changes[i].UserID = user.ID
+			p.changes[i].IP = ip
+		}
+		return tx.Create(&p.changes).Error
+	})
+}
+
+// displayDate 日期字段的记录格式
+func displayDate(v interface{}) string {
+	if t, ok := v.(*time.Time); ok && t != nil {
+		return t.Format("2006-01-02")
+	}
+	return ""
+}
+
+// checkChangeQuota 检查窗口期内字段的修改次数，limit 为 0 时不限制
+func checkChangeQuota(userID uint, field string, limit, windowDays int) error {
+	if limit <= 0 {
+		return nil
+	}
+	if windowDays <= 0 {
+		windowDays = 30
+	}
+	var count int64
+	global.Db.Model(&models.ProfileChange{}).
+		Where("user_id = ? AND field = ? AND created_at > ?", userID, field, time.Now().AddDate(0, 0, -windowDays)).
+		Count(&count)
+	if count >= int64(limit) {
+		return fmt.Errorf("%d 天内最多只能修改 %d 次", windowDays, limit)
+	}
+	return nil
+}
+
+// valueTaken 检查字段值是否已被其他用户使用，unscoped 时包含已注销用户（唯一索引仍然占用）
+func valueTaken(column, value string, userID uint, unscoped bool) bool {
+	query := global.Db.Model(&models.User{})
+	if unscoped {
+		query = query.Unscoped()
+	}
+	var count int64
+	query.Where(column+" = ? AND id <> ?", value, userID).Count(&count)
+	return count > 0
+}
+
+// UpdateProfile 修改个人资料
+func UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	var user models.User
+	if err := global.Db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	cfg := config.AppConfig.Profile
+	update := &profileUpdate{updates: map[string]interface{}{}}
+
+	if req.Nickname != nil {
+		nickname := strings.TrimSpace(*req.Nickname)
+		minLength, maxLength := cfg.NicknameMinLength, cfg.NicknameMaxLength
+		if minLength <= 0 {
+			minLength = 2
+		}
+		if maxLength <= 0 {
+			maxLength = 20
+		}
+		if length := utf8.RuneCountInString(nickname); length < minLength || length > maxLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("昵称长度需在 %d~%d 个字符之间", minLength, maxLength)})
+			return
+		}
+		update.set("nickname", user.Nickname, nickname, nil)
+		if update.changed("nickname") {
+			if err := checkChangeQuota(userID, "nickname", cfg.NicknameChanges, cfg.NicknameWindowDays); err != nil {
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "昵称" + err.Error()})
+				return
+			}
+			if cfg.NicknameUnique && valueTaken("nickname", nickname, userID, false) {
+				c.JSON(http.StatusConflict, gin.H{"error": "昵称已被使用"})
+				return
+			}
+		}
+	}
+
+	if req.Gender != nil {
+		if *req.Gender > 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的性别"})
+			return
+		}
+		update.set("gender", user.Gender, *req.Gender, nil)
+	}
+
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if len(email) > 100 || !emailPattern.MatchString(email) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "邮箱格式不正确"})
+			return
+		}
+		if !strings.EqualFold(email, user.Email) {
+			update.set("email", user.Email, email, nil)
+			if err := checkChangeQuota(userID, "email", cfg.EmailChanges, cfg.EmailWindowDays); err != nil {
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "邮箱" + err.Error()})
+				return
+			}
+			if valueTaken("email", email, userID, true) {
+				c.JSON(http.StatusConflict, gin.H{"error": "邮箱已被使用"})
+				return
+			}
+			// 新邮箱需要重新验证
+			update.updates["email_verified_at"] = nil
+		}
+	}
+
+	if req.Phone != nil {
+		phone := strings.TrimSpace(*req.Phone)
+		if !phonePattern.MatchString(phone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "手机号格式不正确"})
+			return
+		}
+		update.set("phone", user.Phone, phone, nil)
+		if update.changed("phone") && valueTaken("phone", phone, userID, true) {
+			c.JSON(http.StatusConflict, gin.H{"error": "手机号已被使用"})
+			return
+		}
+	}
+
+	if req.Bio != nil {
+		bio := strings.TrimSpace(*req.Bio)
+		if utf8.RuneCountInString(bio) > bioMaxLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("个人简介不能超过 %d 个字符", bioMaxLength)})
+			return
+		}
+		update.set("bio", user.Bio, bio, nil)
+	}
+
+	if req.Birthday != nil {
+		var birthday *time.Time
+		if value := strings.TrimSpace(*req.Birthday); value != "" {
+			t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil || t.Year() < 1900 || t.After(time.Now()) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的生日"})
+				return
+			}
+			birthday = &t
+		}
+		update.set("birthday", user.Birthday, birthday, displayDate)
+	}
+
+	if req.Location != nil {
+		location := strings.TrimSpace(*req.Location)
+		if utf8.RuneCountInString(location) > locationMaxLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("所在地不能超过 %d 个字符", locationMaxLength)})
+			return
+		}
+		update.set("location", user.Location, location, nil)
+	}
+
+	if req.CoverPictureID != nil {
+		coverImage := ""
+		if *req.CoverPictureID != 0 {
+			var picture models.Picture
+			if err := global.Db.Select("id, url").
+				Where("id = ? AND user_id = ? AND moderation_status = ?", *req.CoverPictureID, userID, models.ModerationAllow).
+				First(&picture).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "图片不存在"})
+				return
+			}
+			coverImage = picture.URL
+		}
+		update.set("cover_image", user.CoverImage, coverImage, nil)
+	}
+
+	if len(update.changes) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "资料未修改", "profile": newUserPrivate(&user)})
+		return
+	}
+
+	if err := update.save(&user, c.ClientIP()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新资料失败"})
+		return
+	}
+
+	global.Db.First(&user, userID)
+	if update.changed("email") {
+		if err := sendVerificationEmail(&user); err != nil {
+			log.Printf("生成邮箱验证令牌失败 user=%d: %v", user.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "资料更新成功", "profile": newUserPrivate(&user)})
+}
+
+// GetProfileHistory 获取当前用户的资料修改记录
+func GetProfileHistory(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var changes []models.ProfileChange
+	if err := global.Db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(100).
+		Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取修改记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
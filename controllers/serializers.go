@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/appabin/greenbook/models"
+)
+
+// 响应对象：接口只返回这里定义的字段，不直接序列化数据库模型，
+// 避免密码哈希、微信 session_key 等内部字段被返回给客户端
+
+// UserBrief 用户简要信息（作者、评论者等）
+type UserBrief struct {
+	ID       uint   `json:"id"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+}
+
+// UserPublic 其他用户可见的用户信息
+type UserPublic struct {
+	ID             uint      `json:"id"`
+	Nickname       string    `json:"nickname"`
+	Avatar         string    `json:"avatar"`
+	Gender         uint8     `json:"gender"`
+	Bio            string    `json:"bio"`
+	Location       string    `json:"location"`
+	CoverImage     string    `json:"cover_image"`
+	FollowingCount uint      `json:"following_count"`
+	FollowersCount uint      `json:"followers_count"`
+	PostsCount     uint      `json:"posts_count"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// UserProfile 用户主页信息
+type UserProfile struct {
+	UserPublic
+	PrivateAccount bool `json:"private_account"`
+}
+
+// UserPrivate 本人或管理员可见的用户信息
+type UserPrivate struct {
+	UserPublic
+	Username         string     `json:"username"`
+	Email            string     `json:"email"`
+	EmailVerified    bool       `json:"email_verified"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	Phone            string     `json:"phone"`
+	Birthday         string     `json:"birthday"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"` // 已申请注销时为计划注销时间
+
+	Privacy PrivacySettings `json:"privacy"`
+}
+
+// PictureResponse 图片信息
+type PictureResponse struct {
+	ID  uint   `json:"id"`
+	URL string `json:"url"`
+}
+
+// ArticleResponse 文章信息
+type ArticleResponse struct {
+	ID               uint              `json:"id"`
+	Title            string            `json:"title"`
+	Content          string            `json:"content,omitempty"`
+	AuthorID         uint              `json:"author_id"`
+	Author           *UserBrief        `json:"author,omitempty"`
+	Tags             []string          `json:"tags"`
+	Pictures         []PictureResponse `json:"pictures,omitempty"`
+	LikeCount        int               `json:"like_count"`
+	FavoriteCount    int               `json:"favorite_count"`
+	CommentCount     int               `json:"comment_count"`
+	ModerationStatus string            `json:"moderation_status,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// CommentResponse 评论信息
+type CommentResponse struct {
+	ID        uint       `json:"id"`
+	Content   string     `json:"content"`
+	ArticleID uint       `json:"article_id"`
+	UserID    uint       `json:"user_id"`
+	User      *UserBrief `json:"user,omitempty"`
+	LikeCount int        `json:"like_count"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// newUserBrief 未加载关联用户（ID 为 0）时返回 nil
+func newUserBrief(user *models.User) *UserBrief {
+	if user == nil || user.ID == 0 {
+		return nil
+	}
+	return &UserBrief{ID: user.ID, Nickname: user.Nickname, Avatar: user.Avatar}
+}
+
+func newUserPublic(user *models.User) UserPublic {
+	return UserPublic{
+		ID:             user.ID,
+		Nickname:       user.Nickname,
+		Avatar:         user.Avatar,
+		Gender:         user.Gender,
+		Bio:            user.Bio,
+		Location:       user.Location,
+		CoverImage:     user.CoverImage,
+		FollowingCount: user.FollowingCount,
+		FollowersCount: user.FollowersCount,
+		PostsCount:     user.PostsCount,
+		CreatedAt:      user.CreatedAt,
+	}
+}
+
+func newUserPrivate(user *models.User) UserPrivate {
+	return UserPrivate{
+		UserPublic:       newUserPublic(user),
+		Username:         user.Username,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		EmailVerifiedAt:  user.EmailVerifiedAt,
+		Phone:            user.Phone,
+		Birthday:         displayDate(user.Birthday),
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		UpdatedAt:        user.UpdatedAt,
+
+		DeletionScheduledAt: user.DeletionScheduledAt,
+
+		Privacy: newPrivacySettings(user),
+	}
+}
+
+func newUserPrivateList(users []models.User) []UserPrivate {
+	list := make([]UserPrivate, 0, len(users))
+	for i := range users {
+		list = append(list, newUserPrivate(&users[i]))
+	}
+	return list
+}
+
+func newArticleResponse(article *models.Article) ArticleResponse {
+	response := ArticleResponse{
+		ID:               article.ID,
+		Title:            article.Title,
+		Content:          article.Content,
+		AuthorID:         article.AuthorID,
+		Author:           newUserBrief(&article.Author),
+		Tags:             make([]string, 0, len(article.Tags)),
+		LikeCount:        article.LikeCount,
+		FavoriteCount:    article.FavoriteCount,
+		CommentCount:     article.CommentCount,
+		ModerationStatus: article.ModerationStatus,
+		CreatedAt:        article.CreatedAt,
+		UpdatedAt:        article.UpdatedAt,
+	}
+	for _, tag := range article.Tags {
+		response.Tags = append(response.Tags, tag.Name)
+	}
+	for _, picture := range article.Pictures {
+		response.Pictures = append(response.Pictures, PictureResponse{ID: picture.ID, URL: picture.URL})
+	}
+	return response
+}
+
+func newArticleResponseList(articles []models.Article) []ArticleResponse {
+	list := make([]ArticleResponse, 0, len(articles))
+	for i := range articles {
+		list = append(list, newArticleResponse(&articles[i]))
+	}
+	return list
+}
+
+func newCommentResponse(comment *models.Comment) CommentResponse {
+	return CommentResponse{
+		ID:        comment.ID,
+		Content:   comment.Content,
+		ArticleID: comment.ArticleID,
+		UserID:    comment.UserID,
+		User:      newUserBrief(&comment.User),
+		LikeCount: comment.LikeCount,
+		CreatedAt: comment.CreatedAt,
+	}
+}
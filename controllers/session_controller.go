@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 登录方式
+const (
+	LoginMethodPassword = "password"
+	LoginMethodWeChat   = "wechat"
+)
+
+// deviceName 获取设备名称，优先使用客户端上报的 X-Device-Name
+func deviceName(c *gin.Context) string {
+	if name := strings.TrimSpace(c.GetHeader("X-Device-Name")); name != "" {
+		if len(name) > 100 {
+			name = name[:100]
+		}
+		return name
+	}
+
+	ua := c.Request.UserAgent()
+	switch {
+	case strings.Contains(ua, "miniProgram") || strings.Contains(ua, "MicroMessenger"):
+		return "微信小程序"
+	case strings.Contains(ua, "iPhone"):
+		return "iPhone"
+	case strings.Contains(ua, "iPad"):
+		return "iPad"
+	case strings.Contains(ua, "Android"):
+		return "Android"
+	case strings.Contains(ua, "Windows"):
+		return "Windows"
+	case strings.Contains(ua, "Macintosh"):
+		return "Mac"
+	case strings.Contains(ua, "Linux"):
+		return "Linux"
+	}
+	return "未知设备"
+}
+
+// truncate 截断字符串到指定字节数
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
+
+// createSession 记录新的登录会话
+func createSession(c *gin.Context, userID uint, pair *utils.TokenPair, method string) error {
+	now := time.Now()
+	return global.Db.Create(&models.UserSession{
+		ID:          pair.SessionID,
+		UserID:      userID,
+		Device:      deviceName(c),
+		IP:          c.ClientIP(),
+		UserAgent:   truncate(c.Request.UserAgent(), 500),
+		LoginMethod: method,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(utils.RefreshTokenTTL),
+	}).Error
+}
+
+// revokeSessions 吊销会话（数据库标记 + Redis 黑名单）
+func revokeSessions(sessionIDs []string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	for _, sessionID := range sessionIDs {
+		if err := utils.RevokeSession(sessionID); err != nil {
+			return err
+		}
+	}
+	return global.Db.Model(&models.UserSession{}).
+		Where("id IN ? AND revoked_at IS NULL", sessionIDs).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions 吊销用户的全部会话，exceptSessionID 不为空时保留该会话
+func RevokeUserSessions(userID uint, exceptSessionID string) error {
+	var sessionIDs []string
+	global.Db.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ? AND id <> ?", userID, time.Now(), exceptSessionID).
+		Pluck("id", &sessionIDs)
+	return revokeSessions(sessionIDs)
+}
+
+// currentSessionID 获取当前请求所属的会话ID
+func currentSessionID(c *gin.Context) string {
+	if value, exists := c.Get("tokenClaims"); exists {
+		return value.(*utils.TokenClaims).SessionID
+	}
+	return ""
+}
+
+// GetSessions 获取当前用户的登录设备列表
+func GetSessions(c *gin.Context) {
+	userID := c.GetUint("userID")
+	current := currentSessionID(c)
+
+	var sessions []models.UserSession
+	if err := global.Db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取登录设备失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, gin.H{
+			"id":           session.ID,
+			"device":       session.Device,
+			"ip":           session.IP,
+			"user_agent":   session.UserAgent,
+			"login_method": session.LoginMethod,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"current":      session.ID == current,
+		})
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// RevokeSession 下线指定的登录设备
+func RevokeSession(c *gin.Context) {
+	userID := c.GetUint("userID")
+	sessionID := c.Param("id")
+
+	var session models.UserSession
+	if err := global.Db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+		return
+	}
+
+	if err := revokeSessions([]string{session.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "下线设备失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "设备已下线"})
+}
+
+// RevokeOtherSessions 下线除当前设备外的所有设备
+func RevokeOtherSessions(c *gin.Context) {
+	userID := c.GetUint("userID")
+	if err := RevokeUserSessions(userID, currentSessionID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "下线其他设备失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "其他设备已全部下线"})
+}
@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 统计粒度
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+	GranularityWeek = "week"
+)
+
+// maxStatisticsBuckets 单次统计允许的最大时间桶数量
+const maxStatisticsBuckets = 2000
+
+// DailyCount 按时间桶聚合的数量
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// RetentionCohort 留存分组：某一时间桶内注册的用户在之后各时间桶的活跃情况
+type RetentionCohort struct {
+	Cohort    string    `json:"cohort"`    // 注册时间桶
+	Size      int64     `json:"size"`      // 分组人数
+	Retained  []int64   `json:"retained"`  // 第 N 个时间桶仍活跃的人数，下标 0 为注册当期
+	Retention []float64 `json:"retention"` // 留存率
+}
+
+// bucketExpr 返回将时间列归入时间桶的 SQL 表达式
+func bucketExpr(column, granularity string) string {
+	switch granularity {
+	case GranularityHour:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:00')", column)
+	case GranularityWeek:
+		return fmt.Sprintf("DATE_FORMAT(DATE_SUB(%s, INTERVAL WEEKDAY(%s) DAY), '%%Y-%%m-%%d')", column, column)
+	default:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d')", column)
+	}
+}
+
+// bucketStart 返回时间所在时间桶的起始时间
+func bucketStart(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityHour:
+		return t.Truncate(time.Hour)
+	case GranularityWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		offset := (int(day.Weekday()) + 6) % 7 // 周一为一周的开始
+		return day.AddDate(0, 0, -offset)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// nextBucket 返回下一个时间桶的起始时间
+func nextBucket(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityHour:
+		return t.Add(time.Hour)
+	case GranularityWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// bucketLabel 返回时间桶的展示名称，与 bucketExpr 的输出一致
+func bucketLabel(t time.Time, granularity string) string {
+	if granularity == GranularityHour {
+		return t.Format("2006-01-02 15:04")
+	}
+	return t.Format("2006-01-02")
+}
+
+// statisticsBuckets 生成 [from, to) 区间内的全部时间桶
+func statisticsBuckets(from, to time.Time, granularity string) []string {
+	var buckets []string
+	for t := bucketStart(from, granularity); t.Before(to); t = nextBucket(t, granularity) {
+		buckets = append(buckets, bucketLabel(t, granularity))
+	}
+	return buckets
+}
+
+// fillBuckets 将聚合结果按时间桶补齐，没有数据的时间桶计为 0
+func fillBuckets(buckets []string, rows []DailyCount) []DailyCount {
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Date] = row.Count
+	}
+
+	result := make([]DailyCount, 0, len(buckets))
+	for _, bucket := range buckets {
+		result = append(result, DailyCount{Date: bucket, Count: counts[bucket]})
+	}
+	return result
+}
+
+// parseStatisticsTime 解析统计区间参数，仅有日期时 end 为 true 表示取当天结束
+func parseStatisticsTime(value string, end bool) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// countTrend 按时间桶统计模型在区间内新增的数量
+func countTrend(model interface{}, from, to time.Time, granularity string, buckets []string) ([]DailyCount, int64, error) {
+	var rows []DailyCount
+	err := global.Db.Model(model).
+		Select(bucketExpr("created_at", granularity)+" AS date, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("date").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	for _, row := range rows {
+		total += row.Count
+	}
+	return fillBuckets(buckets, rows), total, nil
+}
+
+// activeTrend 按时间桶统计活跃用户数（去重）
+func activeTrend(from, to time.Time, granularity string, buckets []string) ([]DailyCount, int64, error) {
+	var rows []DailyCount
+	err := global.Db.Model(&models.UserActivity{}).
+		Select(bucketExpr("hour", granularity)+" AS date, COUNT(DISTINCT user_id) AS count").
+		Where("hour >= ? AND hour < ?", from, to).
+		Group("date").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	err = global.Db.Model(&models.UserActivity{}).
+		Where("hour >= ? AND hour < ?", from, to).
+		Distinct("user_id").
+		Count(&total).Error
+	return fillBuckets(buckets, rows), total, err
+}
+
+// retentionCohorts 计算注册分组在之后各时间桶的留存
+func retentionCohorts(from, to time.Time, granularity string, buckets []string, signups []DailyCount) ([]RetentionCohort, error) {
+	var rows []struct {
+		Cohort string
+		Bucket string
+		Count  int64
+	}
+	err := global.Db.Table("users").
+		Select(bucketExpr("users.created_at", granularity)+" AS cohort, "+
+			bucketExpr("user_activities.hour", granularity)+" AS bucket, COUNT(DISTINCT users.id) AS count").
+		Joins("JOIN user_activities ON user_activities.user_id = users.id").
+		Where("users.created_at >= ? AND users.created_at < ? AND users.deleted_at IS NULL", from, to).
+		Where("user_activities.hour < ?", to).
+		Group("cohort, bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(buckets))
+	for i, bucket := range buckets {
+		index[bucket] = i
+	}
+
+	retained := make(map[string][]int64, len(signups))
+	for _, signup := range signups {
+		retained[signup.Date] = make([]int64, len(buckets)-index[signup.Date])
+	}
+	for _, row := range rows {
+		cohortIndex, ok := index[row.Cohort]
+		bucketIndex, ok2 := index[row.Bucket]
+		if !ok || !ok2 || bucketIndex < cohortIndex {
+			continue
+		}
+		retained[row.Cohort][bucketIndex-cohortIndex] = row.Count
+	}
+
+	cohorts := make([]RetentionCohort, 0)
+	for _, signup := range signups {
+		if signup.Count == 0 {
+			continue
+		}
+		counts := retained[signup.Date]
+		rates := make([]float64, len(counts))
+		for i, count := range counts {
+			rates[i] = float64(count) / float64(signup.Count)
+		}
+		cohorts = append(cohorts, RetentionCohort{
+			Cohort:    signup.Date,
+			Size:      signup.Count,
+			Retained:  counts,
+			Retention: rates,
+		})
+	}
+	return cohorts, nil
+}
+
+// GetStatistics 获取数据统计
+// 支持 from、to（日期或时间）和 granularity=hour|day|week，默认最近7天按天统计
+func GetStatistics(c *gin.Context) {
+	granularity := c.DefaultQuery("granularity", GranularityDay)
+	if granularity != GranularityHour && granularity != GranularityDay && granularity != GranularityWeek {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity 只支持 hour、day、week"})
+		return
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from := today.AddDate(0, 0, -6)
+	to := today.AddDate(0, 0, 1)
+
+	var err error
+	if value := c.Query("from"); value != "" {
+		if from, err = parseStatisticsTime(value, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始时间"})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = parseStatisticsTime(value, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束时间"})
+			return
+		}
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "开始时间必须早于结束时间"})
+		return
+	}
+
+	// 时间桶对齐后再统计，保证首尾时间桶的数据完整
+	from = bucketStart(from, granularity)
+	buckets := statisticsBuckets(from, to, granularity)
+	if len(buckets) > maxStatisticsBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "统计区间过大，请缩小范围或增大统计粒度"})
+		return
+	}
+
+	// 优先读取缓存
+	cacheKey := fmt.Sprintf("statistics:%s:%d:%d", granularity, from.Unix(), to.Unix())
+	if cached, err := global.RedisDB.Get(cacheKey).Result(); err == nil {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(cached))
+		return
+	}
+
+	// 总量统计
+	var userCount, articleCount, commentCount, likeCount, favoriteCount, followCount int64
+	global.Db.Model(&models.User{}).Count(&userCount)
+	global.Db.Model(&models.Article{}).Count(&articleCount)
+	global.Db.Model(&models.Comment{}).Count(&commentCount)
+	global.Db.Model(&models.Like{}).Count(&likeCount)
+	global.Db.Model(&models.Favorite{}).Count(&favoriteCount)
+	global.Db.Model(&models.UserFollow{}).Count(&followCount)
+
+	// 今日新增
+	var todayUserCount, todayArticleCount int64
+	global.Db.Model(&models.User{}).Where("created_at >= ?", today).Count(&todayUserCount)
+	global.Db.Model(&models.Article{}).Where("created_at >= ?", today).Count(&todayArticleCount)
+
+	// 区间内按时间桶统计
+	trendModels := []struct {
+		name  string
+		model interface{}
+	}{
+		{"user", &models.User{}},
+		{"article", &models.Article{}},
+		{"comment", &models.Comment{}},
+		{"like", &models.Like{}},
+		{"favorite", &models.Favorite{}},
+		{"follow", &models.UserFollow{}},
+	}
+	trends := gin.H{}
+	rangeTotals := gin.H{}
+	var userTrend []DailyCount
+	for _, item := range trendModels {
+		trend, total, err := countTrend(item.model, from, to, granularity, buckets)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "统计失败"})
+			return
+		}
+		if item.name == "user" {
+			userTrend = trend
+		}
+		trends[item.name+"_trend"] = trend
+		rangeTotals[item.name+"_count"] = total
+	}
+
+	activeUsers, activeTotal, err := activeTrend(from, to, granularity, buckets)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计活跃用户失败"})
+		return
+	}
+	trends["active_user_trend"] = activeUsers
+	rangeTotals["active_user_count"] = activeTotal
+
+	cohorts, err := retentionCohorts(from, to, granularity, buckets, userTrend)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计留存失败"})
+		return
+	}
+
+	response := gin.H{
+		"range": gin.H{
+			"from":        from,
+			"to":          to,
+			"granularity": granularity,
+		},
+		"overview": gin.H{
+			"user_count":          userCount,
+			"article_count":       articleCount,
+			"comment_count":       commentCount,
+			"like_count":          likeCount,
+			"favorite_count":      favoriteCount,
+			"follow_count":        followCount,
+			"today_user_count":    todayUserCount,
+			"today_article_count": todayArticleCount,
+		},
+		"range_totals": rangeTotals,
+		"trends":       trends,
+		"retention":    cohorts,
+	}
+
+	// 包含当前时间的区间数据仍在变化，缓存时间较短
+	ttl := time.Hour
+	if to.After(now) {
+		ttl = 5 * time.Minute
+	}
+	if data, err := json.Marshal(response); err == nil {
+		global.RedisDB.Set(cacheKey, data, ttl)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
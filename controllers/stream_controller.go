@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// 实时推送的事件类型
+const (
+	streamEventNotification    = "notification"       // 新通知，推送到 user:<id>
+	streamEventCommentCreated  = "comment.created"    // 正在浏览的文章有新评论，推送到 article:<id>
+	streamEventArticleLikes    = "article.like_count" // 文章点赞数变化，推送到 article:<id>
+	streamEventFeedArticle     = "feed.article"       // 关注的作者发布了新文章，推送到 author:<id>
+	streamEventMessage         = "message.created"    // 新私信，推送到双方的 user:<id>
+	streamEventMessageRead     = "message.read"       // 对方已读私信，推送给对方的 user:<id>
+	streamEventBufferSize      = 64                   // 每个连接未发送事件的缓冲数
+	streamDefaultHeartbeat     = 25 * time.Second
+	streamDefaultMaxArticles   = 20
+	streamDefaultResumeLimit   = 500
+	streamWebSocketReplyBuffer = 8
+)
+
+// streamControlMessage WebSocket 客户端发送的订阅指令
+type streamControlMessage struct {
+	Action    string `json:"action"` // subscribe 或 unsubscribe
+	ArticleID uint   `json:"article_id"`
+}
+
+// streamConnection 一个实时连接的状态
+type streamConnection struct {
+	userID     uint
+	subscriber *utils.StreamSubscriber
+	hidden     map[uint]bool // 连接建立时拉黑关系中和已静音的用户
+	articles   map[uint]bool
+}
+
+// allowed 过滤拉黑关系中和已静音用户触发的事件
+func (conn *streamConnection) allowed(event utils.StreamEvent) bool {
+	return event.ActorID == 0 || !conn.hidden[event.ActorID]
+}
+
+// subscribeArticle 订阅文章的评论和点赞数，不可见或超过数量上限时返回错误信息
+func (conn *streamConnection) subscribeArticle(articleID uint) string {
+	if conn.articles[articleID] {
+		return ""
+	}
+	if len(conn.articles) >= streamMaxArticles() {
+		return fmt.Sprintf("最多同时订阅 %d 篇文章", streamMaxArticles())
+	}
+	if _, _, status, message := visibleArticle(conn.userID, articleID); status != http.StatusOK {
+		return message
+	}
+	conn.articles[articleID] = true
+	conn.subscriber.Subscribe(utils.ArticleStreamChannel(articleID))
+	return ""
+}
+
+// unsubscribeArticle 取消订阅文章
+func (conn *streamConnection) unsubscribeArticle(articleID uint) {
+	delete(conn.articles, articleID)
+	conn.subscriber.Unsubscribe(utils.ArticleStreamChannel(articleID))
+}
+
+func streamHeartbeat() time.Duration {
+	if seconds := config.AppConfig.Stream.HeartbeatSeconds; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return streamDefaultHeartbeat
+}
+
+func streamMaxArticles() int {
+	if max := config.AppConfig.Stream.MaxArticles; max > 0 {
+		return max
+	}
+	return streamDefaultMaxArticles
+}
+
+func streamResumeLimit() int {
+	if limit := config.AppConfig.Stream.ResumeLimit; limit > 0 {
+		return limit
+	}
+	return streamDefaultResumeLimit
+}
+
+// newStreamConnection 订阅个人通知和关注作者的新文章，关注关系在连接期间变化时需重新连接
+func newStreamConnection(userID uint) *streamConnection {
+	conn := &streamConnection{
+		userID:     userID,
+		subscriber: utils.NewStreamSubscriber(streamEventBufferSize, utils.UserStreamChannel(userID)),
+		hidden:     hiddenUserIDs(userID),
+		articles:   map[uint]bool{},
+	}
+
+	var followedIDs []uint
+	global.Db.Model(&models.UserFollow{}).Where("follower_id = ?", userID).Pluck("followed_id", &followedIDs)
+	for _, id := range followedIDs {
+		if !conn.hidden[id] {
+			conn.subscriber.Subscribe(utils.AuthorStreamChannel(id))
+		}
+	}
+	return conn
+}
+
+// checkStreamOrigin 校验 WebSocket 握手的 Origin，防止其他网站借用户的浏览器建立连接。
+// 没有 Origin 的请求来自非浏览器客户端，直接放行；未配置 allowed_origins 时只允许同源
+func checkStreamOrigin(req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	allowed := config.AppConfig.Stream.AllowedOrigins
+	if len(allowed) == 0 {
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, req.Host) {
+			return nil
+		}
+		return fmt.Errorf("origin %s not allowed", origin)
+	}
+	for _, item := range allowed {
+		if item == "*" || strings.EqualFold(strings.TrimRight(item, "/"), origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %s not allowed", origin)
+}
+
+// CreateStreamTicket 签发建立实时推送连接的一次性票据，通过 /api/stream?ticket= 使用，
+// 避免把访问令牌放在查询参数中被记录到访问日志
+func CreateStreamTicket(c *gin.Context) {
+	var sessionID string
+	if claims, ok := c.MustGet("tokenClaims").(*utils.TokenClaims); ok {
+		sessionID = claims.SessionID
+	}
+	ticket, err := utils.GenerateStreamTicket(c.GetUint("userID"), sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成推送票据失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"ticket":     ticket,
+		"expires_in": int64(utils.StreamTicketTTL.Seconds()),
+	})
+}
+
+// Stream 实时推送，请求头 Upgrade 为 websocket 时使用 WebSocket，否则使用 SSE
+// 查询参数 articles 为逗号分隔的文章ID，Last-Event-ID 请求头或 last_event_id 参数用于断线续传
+func Stream(c *gin.Context) {
+	conn := newStreamConnection(c.GetUint("userID"))
+	defer conn.subscriber.Close()
+
+	if raw := c.Query("articles"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID"})
+				return
+			}
+			if message := conn.subscribeArticle(uint(id)); message != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": message, "article_id": id})
+				return
+			}
+		}
+	}
+
+	// 先订阅再读取历史记录，避免两者之间的事件丢失；重复的事件由客户端按ID去重
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	backlog, err := utils.StreamEventsSince(lastEventID, streamResumeLimit(), conn.subscriber.Subscribed)
+	if err != nil {
+		log.Printf("读取实时推送历史失败 user=%d: %v", conn.userID, err)
+	}
+
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		serveStreamWebSocket(c, conn, backlog)
+		return
+	}
+	serveStreamSSE(c, conn, backlog)
+}
+
+// serveStreamSSE 通过 Server-Sent Events 推送
+func serveStreamSSE(c *gin.Context, conn *streamConnection, backlog []utils.StreamEvent) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+
+	heartbeat := time.NewTicker(streamHeartbeat())
+	defer heartbeat.Stop()
+
+	write := func(w io.Writer, event utils.StreamEvent) error {
+		if !conn.allowed(event) {
+			return nil
+		}
+		return sse.Encode(w, sse.Event{Id: event.ID, Event: event.Type, Data: event})
+	}
+
+	started := false
+	c.Stream(func(w io.Writer) bool {
+		if !started {
+			started = true
+			fmt.Fprint(w, ": connected\n\n")
+			for _, event := range backlog {
+				if write(w, event) != nil {
+					return false
+				}
+			}
+			return true
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-conn.subscriber.Events:
+			return write(w, event) == nil
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// serveStreamWebSocket 通过 WebSocket 推送，客户端可发送指令订阅或取消订阅文章
+func serveStreamWebSocket(c *gin.Context, conn *streamConnection, backlog []utils.StreamEvent) {
+	server := websocket.Server{
+		Handshake: func(_ *websocket.Config, req *http.Request) error { return checkStreamOrigin(req) },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			// 读取指令的协程只解析请求，订阅变更和回复都交给写循环，避免并发写连接
+			commands := make(chan streamControlMessage, streamWebSocketReplyBuffer)
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				for {
+					var message streamControlMessage
+					if err := websocket.JSON.Receive(ws, &message); err != nil {
+						return
+					}
+					select {
+					case commands <- message:
+					default:
+					}
+				}
+			}()
+
+			for _, event := range backlog {
+				if conn.allowed(event) && websocket.JSON.Send(ws, event) != nil {
+					return
+				}
+			}
+
+			heartbeat := time.NewTicker(streamHeartbeat())
+			defer heartbeat.Stop()
+			for {
+				var err error
+				select {
+				case <-closed:
+					return
+				case event := <-conn.subscriber.Events:
+					if conn.allowed(event) {
+						err = websocket.JSON.Send(ws, event)
+					}
+				case command := <-commands:
+					err = websocket.JSON.Send(ws, conn.handleCommand(command))
+				case <-heartbeat.C:
+					err = websocket.JSON.Send(ws, gin.H{"type": "ping"})
+				}
+				if err != nil {
+					return
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// handleCommand 执行 WebSocket 客户端的订阅指令并返回回复
+func (conn *streamConnection) handleCommand(command streamControlMessage) gin.H {
+	if command.ArticleID == 0 {
+		return gin.H{"type": "error", "error": "无效的文章ID"}
+	}
+	switch command.Action {
+	case "subscribe":
+		if message := conn.subscribeArticle(command.ArticleID); message != "" {
+			return gin.H{"type": "error", "error": message, "article_id": command.ArticleID}
+		}
+		return gin.H{"type": "subscribed", "article_id": command.ArticleID}
+	case "unsubscribe":
+		conn.unsubscribeArticle(command.ArticleID)
+		return gin.H{"type": "unsubscribed", "article_id": command.ArticleID}
+	default:
+		return gin.H{"type": "error", "error": "未知的指令"}
+	}
+}
+
+// publishStreamEvent 发布实时事件，失败只记录日志
+func publishStreamEvent(channel, eventType string, actorID uint, data interface{}) {
+	if err := utils.PublishStreamEvent(channel, eventType, actorID, data); err != nil {
+		log.Printf("发布实时事件失败 %s %s: %v", channel, eventType, err)
+	}
+}
+
+// publishArticleLikeCount 推送文章最新的点赞数
+func publishArticleLikeCount(articleID uint) {
+	var article models.Article
+	if err := global.Db.Select("id, like_count").First(&article, articleID).Error; err != nil {
+		return
+	}
+	publishStreamEvent(utils.ArticleStreamChannel(articleID), streamEventArticleLikes, 0, gin.H{
+		"article_id": article.ID,
+		"like_count": article.LikeCount,
+	})
+}
+
+// publishNewComment 推送给正在浏览文章的用户
+func publishNewComment(comment *models.Comment) {
+	publishStreamEvent(utils.ArticleStreamChannel(comment.ArticleID), streamEventCommentCreated, comment.UserID,
+		newCommentResponse(comment))
+}
+
+// publishFeedArticle 推送给作者的粉丝
+func publishFeedArticle(article *models.Article) {
+	var author models.User
+	if err := global.Db.Select("id, nickname, avatar").First(&author, article.AuthorID).Error; err != nil {
+		return
+	}
+	publishStreamEvent(utils.AuthorStreamChannel(article.AuthorID), streamEventFeedArticle, article.AuthorID, gin.H{
+		"id":         article.ID,
+		"title":      article.Title,
+		"author":     newUserBrief(&author),
+		"created_at": article.CreatedAt,
+	})
+}
@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 推荐关注各项因素的权重
+const (
+	suggestionMutualWeight   = 3   // 每位关注的人也关注了对方
+	suggestionInteractWeight = 2   // 每篇点赞或收藏过的对方文章
+	suggestionTagWeight      = 1   // 每个感兴趣的共同标签
+	suggestionActiveWeight   = 2   // 最近有活跃记录
+	suggestionActiveDays     = 7   // 活跃记录统计天数
+	suggestionSignalLimit    = 200 // 每项因素最多取的候选人数
+)
+
+// SuggestionResponse 推荐关注的用户
+type SuggestionResponse struct {
+	UserBrief
+	Bio            string `json:"bio"`
+	FollowersCount uint   `json:"followers_count"`
+	MutualCount    int64  `json:"mutual_count"` // 关注了对方的、当前用户关注的人数
+	Reason         string `json:"reason"`
+}
+
+// suggestionScore 候选用户的得分和各项因素
+type suggestionScore struct {
+	score    int64
+	mutual   int64
+	interact int64
+	tags     int64
+	active   bool
+}
+
+// userScoreRow 按用户统计的查询结果
+type userScoreRow struct {
+	UserID uint
+	Total  int64
+}
+
+// reason 生成推荐理由
+func (s *suggestionScore) reason() string {
+	switch {
+	case s.mutual > 0:
+		return strconv.FormatInt(s.mutual, 10) + " 位你关注的人也关注了TA"
+	case s.interact > 0:
+		return "你赞过或收藏过TA的文章"
+	case s.tags > 0:
+		return "发布过你感兴趣的话题"
+	case s.active:
+		return "近期活跃的作者"
+	default:
+		return ""
+	}
+}
+
+// excludedSuggestionIDs 不参与推荐的用户：本人、已关注、已申请关注、拉黑或静音、已忽略
+func excludedSuggestionIDs(userID uint) []uint {
+	excluded := hiddenUserIDs(userID)
+	excluded[userID] = true
+
+	var ids []uint
+	global.Db.Model(&models.UserFollow{}).Where("follower_id = ?", userID).Pluck("followed_id", &ids)
+	for _, id := range ids {
+		excluded[id] = true
+	}
+	ids = nil
+	global.Db.Model(&models.FollowRequest{}).
+		Where("requester_id = ? AND status = ?", userID, models.FollowRequestPending).
+		Pluck("target_id", &ids)
+	for _, id := range ids {
+		excluded[id] = true
+	}
+	ids = nil
+	global.Db.Model(&models.SuggestionDismissal{}).Where("user_id = ?", userID).Pluck("dismissed_id", &ids)
+	for _, id := range ids {
+		excluded[id] = true
+	}
+
+	list := make([]uint, 0, len(excluded))
+	for id := range excluded {
+		list = append(list, id)
+	}
+	return list
+}
+
+// excludeSuggestionScope 排除不参与推荐的用户，需要在排序和 Limit 之前过滤，
+// 否则得分最高的候选人都已关注时，剩下的候选人会被 Limit 截掉
+func excludeSuggestionScope(column string, excluded []uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" NOT IN ?", excluded)
+	}
+}
+
+// interestTagIDs 当前用户感兴趣的标签：关注的标签和点赞、收藏过的文章的标签
+func interestTagIDs(userID uint) []uint {
+	var tagIDs, more []uint
+	global.Db.Model(&models.TagFollow{}).Where("user_id = ?", userID).Pluck("tag_id", &tagIDs)
+	global.Db.Table("article_tags").
+		Joins("JOIN likes ON likes.article_id = article_tags.article_id AND likes.deleted_at IS NULL").
+		Where("likes.user_id = ?", userID).
+		Distinct().Pluck("article_tags.tag_id", &more)
+	tagIDs = append(tagIDs, more...)
+	more = nil
+	global.Db.Table("article_tags").
+		Joins("JOIN favorites ON favorites.article_id = article_tags.article_id AND favorites.deleted_at IS NULL").
+		Where("favorites.user_id = ?", userID).
+		Distinct().Pluck("article_tags.tag_id", &more)
+	return append(tagIDs, more...)
+}
+
+// collectSuggestionScores 按各项因素统计候选用户得分，excluded 中的用户不参与统计
+func collectSuggestionScores(userID uint, excluded []uint) map[uint]*suggestionScore {
+	scores := map[uint]*suggestionScore{}
+	get := func(id uint) *suggestionScore {
+		if scores[id] == nil {
+			scores[id] = &suggestionScore{}
+		}
+		return scores[id]
+	}
+
+	// 关注的人所关注的人
+	var rows []userScoreRow
+	global.Db.Table("user_follows AS mine").
+		Select("theirs.followed_id AS user_id, COUNT(*) AS total").
+		Joins("JOIN user_follows AS theirs ON theirs.follower_id = mine.followed_id AND theirs.deleted_at IS NULL").
+		Where("mine.follower_id = ? AND mine.deleted_at IS NULL", userID).
+		Scopes(excludeSuggestionScope("theirs.followed_id", excluded)).
+		Group("theirs.followed_id").Order("total DESC").Limit(suggestionSignalLimit).
+		Scan(&rows)
+	for _, row := range rows {
+		s := get(row.UserID)
+		s.mutual = row.Total
+		s.score += row.Total * suggestionMutualWeight
+	}
+
+	// 点赞或收藏过其文章的作者
+	for _, table := range []string{"likes", "favorites"} {
+		rows = nil
+		global.Db.Table(table).
+			Select("articles.author_id AS user_id, COUNT(*) AS total").
+			Joins("JOIN articles ON articles.id = "+table+".article_id AND articles.deleted_at IS NULL").
+			Where(table+".user_id = ? AND "+table+".deleted_at IS NULL", userID).
+			Scopes(excludeSuggestionScope("articles.author_id", excluded)).
+			Group("articles.author_id").Order("total DESC").Limit(suggestionSignalLimit).
+			Scan(&rows)
+		for _, row := range rows {
+			s := get(row.UserID)
+			s.interact += row.Total
+			s.score += row.Total * suggestionInteractWeight
+		}
+	}
+
+	// 发布过感兴趣标签的作者
+	if tagIDs := interestTagIDs(userID); len(tagIDs) > 0 {
+		rows = nil
+		global.Db.Table("articles").
+			Select("articles.author_id AS user_id, COUNT(DISTINCT article_tags.tag_id) AS total").
+			Joins("JOIN article_tags ON article_tags.article_id = articles.id").
+			Where("article_tags.tag_id IN ? AND articles.deleted_at IS NULL AND articles.moderation_status = ?", tagIDs, models.ModerationAllow).
+			Scopes(excludeSuggestionScope("articles.author_id", excluded)).
+			Group("articles.author_id").Order("total DESC").Limit(suggestionSignalLimit).
+			Scan(&rows)
+		for _, row := range rows {
+			s := get(row.UserID)
+			s.tags = row.Total
+			s.score += row.Total * suggestionTagWeight
+		}
+	}
+
+	// 最近活跃的作者，新用户没有其他因素时也能得到推荐
+	rows = nil
+	global.Db.Table("user_activities").
+		Select("user_activities.user_id AS user_id, COUNT(*) AS total").
+		Joins("JOIN users ON users.id = user_activities.user_id AND users.posts_count > 0").
+		Where("user_activities.hour >= ?", time.Now().AddDate(0, 0, -suggestionActiveDays)).
+		Scopes(excludeSuggestionScope("user_activities.user_id", excluded)).
+		Group("user_activities.user_id").Order("total DESC").Limit(suggestionSignalLimit).
+		Scan(&rows)
+	for _, row := range rows {
+		s := get(row.UserID)
+		s.active = true
+		s.score += suggestionActiveWeight
+	}
+	return scores
+}
+
+// GetFollowSuggestions 推荐关注
+func GetFollowSuggestions(c *gin.Context) {
+	userID := c.GetUint("userID")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	scores := collectSuggestionScores(userID, excludedSuggestionIDs(userID))
+	candidateIDs := make([]uint, 0, len(scores))
+	for id := range scores {
+		candidateIDs = append(candidateIDs, id)
+	}
+
+	// 只推荐正常状态的账号，得分相同时粉丝多的在前
+	users := make([]models.User, 0)
+	if len(candidateIDs) > 0 {
+		global.Db.Select("id, nickname, avatar, bio, followers_count").
+			Where("id IN ? AND anonymized_at IS NULL AND deletion_scheduled_at IS NULL", candidateIDs).
+			Find(&users)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		a, b := scores[users[i].ID], scores[users[j].ID]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return users[i].FollowersCount > users[j].FollowersCount
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+
+	list := make([]SuggestionResponse, 0, len(users))
+	for i := range users {
+		score := scores[users[i].ID]
+		list = append(list, SuggestionResponse{
+			UserBrief:      *newUserBrief(&users[i]),
+			Bio:            users[i].Bio,
+			FollowersCount: users[i].FollowersCount,
+			MutualCount:    score.mutual,
+			Reason:         score.reason(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// DismissSuggestion 忽略推荐的用户，之后不再推荐
+func DismissSuggestion(c *gin.Context) {
+	targetID, ok := relationTarget(c)
+	if !ok {
+		return
+	}
+	dismissal := models.SuggestionDismissal{UserID: c.GetUint("userID"), DismissedID: targetID}
+	if err := global.Db.Where(&dismissal).FirstOrCreate(&dismissal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已忽略该推荐"})
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+)
+
+// TagToggleFollow 关注/取消关注标签
+func TagToggleFollow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID"})
+		return
+	}
+
+	var tag models.Tag
+	if err := global.Db.First(&tag, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	result := global.Db.Where("user_id = ? AND tag_id = ?", userID, tag.ID).Delete(&models.TagFollow{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+	if result.RowsAffected > 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "已取消关注标签", "is_following": false})
+		return
+	}
+
+	if err := global.Db.Create(&models.TagFollow{UserID: userID, TagID: tag.ID}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "关注标签失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "关注标签成功", "is_following": true})
+}
+
+// GetFollowingTags 获取当前用户关注的标签
+func GetFollowingTags(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var tags []models.Tag
+	if err := global.Db.Joins("JOIN tag_follows ON tag_follows.tag_id = tags.id").
+		Where("tag_follows.user_id = ?", userID).
+		Order("tag_follows.created_at DESC").
+		Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取关注标签失败"})
+		return
+	}
+
+	tagList := make([]gin.H, 0, len(tags))
+	for _, tag := range tags {
+		tagList = append(tagList, gin.H{
+			"id":   tag.ID,
+			"name": tag.Name,
+		})
+	}
+
+	c.JSON(http.StatusOK, tagList)
+}
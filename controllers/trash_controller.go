@@ -0,0 +1,604 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+)
+
+// 回收站中的内容类型
+const (
+	TrashArticles = "articles"
+	TrashComments = "comments"
+	TrashUsers    = "users"
+)
+
+// trashPurgeLockKey 定时清理任务的分布式锁，保证多实例下只有一个实例执行
+const trashPurgeLockKey = "trash:purge:lock"
+
+// errParentDeleted 父级对象仍在回收站中
+var errParentDeleted = errors.New("parent deleted")
+
+// deletionTime 生成本次级联删除统一使用的删除时间，恢复时按该时间匹配级联删除的数据
+func deletionTime() time.Time {
+	return time.Now().Truncate(time.Millisecond)
+}
+
+// syncToggleKeys 同步点赞/收藏在 Redis 中的状态标记
+func syncToggleKeys(pattern string, pairs [][2]uint, exists bool) {
+	for _, pair := range pairs {
+		key := fmt.Sprintf(pattern, pair[0], pair[1])
+		if exists {
+			global.RedisDB.Set(key, "1", 0)
+		} else {
+			global.RedisDB.Del(key)
+		}
+	}
+}
+
+// likePairs 查询满足条件的点赞记录的 (文章ID, 用户ID)
+func likePairs(tx *gorm.DB, model interface{}, query string, args ...interface{}) [][2]uint {
+	var rows []struct {
+		ArticleID uint
+		UserID    uint
+	}
+	tx.Unscoped().Model(model).Select("article_id, user_id").Where(query, args...).Scan(&rows)
+
+	pairs := make([][2]uint, 0, len(rows))
+	for _, row := range rows {
+		pairs = append(pairs, [2]uint{row.ArticleID, row.UserID})
+	}
+	return pairs
+}
+
+// recountArticles 按现存数据重新计算文章的点赞、收藏、评论数
+func recountArticles(tx *gorm.DB, articleIDs []uint) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+	return tx.Exec(`UPDATE articles SET
+		like_count = (SELECT COUNT(*) FROM likes WHERE likes.article_id = articles.id AND likes.deleted_at IS NULL),
+		favorite_count = (SELECT COUNT(*) FROM favorites WHERE favorites.article_id = articles.id AND favorites.deleted_at IS NULL),
+		comment_count = (SELECT COUNT(*) FROM comments WHERE comments.article_id = articles.id AND comments.deleted_at IS NULL)
+		WHERE id IN ?`, articleIDs).Error
+}
+
+// recountComments 按现存数据重新计算评论的点赞数
+func recountComments(tx *gorm.DB, commentIDs []uint) error {
+	if len(commentIDs) == 0 {
+		return nil
+	}
+	return tx.Exec(`UPDATE comments SET
+		like_count = (SELECT COUNT(*) FROM comment_likes WHERE comment_likes.comment_id = comments.id AND comment_likes.deleted_at IS NULL)
+		WHERE id IN ?`, commentIDs).Error
+}
+
+// softDeleteArticle 软删除文章及其点赞、收藏、评论
+func softDeleteArticle(tx *gorm.DB, articleID uint, now time.Time) error {
+	likes := likePairs(tx, &models.Like{}, "article_id = ? AND deleted_at IS NULL", articleID)
+	favorites := likePairs(tx, &models.Favorite{}, "article_id = ? AND deleted_at IS NULL", articleID)
+
+	commentIDs := tx.Model(&models.Comment{}).Select("id").Where("article_id = ?", articleID)
+	steps := []*gorm.DB{
+		tx.Model(&models.CommentLike{}).Where("comment_id IN (?)", commentIDs).Update("deleted_at", now),
+		tx.Model(&models.Comment{}).Where("article_id = ?", articleID).Update("deleted_at", now),
+		tx.Model(&models.Like{}).Where("article_id = ?", articleID).Update("deleted_at", now),
+		tx.Model(&models.Favorite{}).Where("article_id = ?", articleID).Update("deleted_at", now),
+		tx.Model(&models.Article{}).Where("id = ?", articleID).Update("deleted_at", now),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+
+	syncToggleKeys("article:like:%d:%d", likes, false)
+	syncToggleKeys("article:favorite:%d:%d", favorites, false)
+	return nil
+}
+
+// restoreArticle 恢复文章以及与其一同被删除的点赞、收藏、评论
+func restoreArticle(tx *gorm.DB, article models.Article) error {
+	var author models.User
+	if err := tx.Unscoped().Select("id, deleted_at").First(&author, article.AuthorID).Error; err == nil && author.DeletedAt.Valid {
+		return errParentDeleted
+	}
+
+	deletedAt := article.DeletedAt.Time
+	commentIDs := tx.Unscoped().Model(&models.Comment{}).Select("id").Where("article_id = ? AND deleted_at = ?", article.ID, deletedAt)
+	steps := []*gorm.DB{
+		tx.Unscoped().Model(&models.CommentLike{}).Where("comment_id IN (?) AND deleted_at = ?", commentIDs, deletedAt).Update("deleted_at", nil),
+		tx.Unscoped().Model(&models.Comment{}).Where("article_id = ? AND deleted_at = ?", article.ID, deletedAt).Update("deleted_at", nil),
+		tx.Unscoped().Model(&models.Like{}).Where("article_id = ? AND deleted_at = ?", article.ID, deletedAt).Update("deleted_at", nil),
+		tx.Unscoped().Model(&models.Favorite{}).Where("article_id = ? AND deleted_at = ?", article.ID, deletedAt).Update("deleted_at", nil),
+		tx.Unscoped().Model(&models.Article{}).Where("id = ?", article.ID).Update("deleted_at", nil),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+
+	// 文章图片(article_pictures)和标签关联(article_tags)在软删除期间保持不变，恢复文章后自动生效
+	syncToggleKeys("article:like:%d:%d", likePairs(tx, &models.Like{}, "article_id = ? AND deleted_at IS NULL", article.ID), true)
+	syncToggleKeys("article:favorite:%d:%d", likePairs(tx, &models.Favorite{}, "article_id = ? AND deleted_at IS NULL", article.ID), true)
+
+	var commentIDList []uint
+	tx.Model(&models.Comment{}).Where("article_id = ?", article.ID).Pluck("id", &commentIDList)
+	if err := recountComments(tx, commentIDList); err != nil {
+		return err
+	}
+	return recountArticles(tx, []uint{article.ID})
+}
+
+// softDeleteComment 软删除评论及其点赞
+func softDeleteComment(tx *gorm.DB, comment models.Comment, now time.Time) error {
+	if err := tx.Model(&models.CommentLike{}).Where("comment_id = ?", comment.ID).Update("deleted_at", now).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Comment{}).Where("id = ?", comment.ID).Update("deleted_at", now).Error; err != nil {
+		return err
+	}
+	return recountArticles(tx, []uint{comment.ArticleID})
+}
+
+// restoreComment 恢复评论及与其一同被删除的点赞
+func restoreComment(tx *gorm.DB, comment models.Comment) error {
+	var parentCount int64
+	tx.Model(&models.Article{}).Where("id = ?", comment.ArticleID).Count(&parentCount)
+	if parentCount == 0 {
+		return errParentDeleted
+	}
+
+	if err := tx.Unscoped().Model(&models.CommentLike{}).
+		Where("comment_id = ? AND deleted_at = ?", comment.ID, comment.DeletedAt.Time).
+		Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.Comment{}).Where("id = ?", comment.ID).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	if err := recountComments(tx, []uint{comment.ID}); err != nil {
+		return err
+	}
+	return recountArticles(tx, []uint{comment.ArticleID})
+}
+
+// softDeleteUser 软删除用户及其文章、评论、点赞、收藏和关注关系
+func softDeleteUser(tx *gorm.DB, userID uint, now time.Time) error {
+	var articleIDs []uint
+	tx.Model(&models.Article{}).Where("author_id = ?", userID).Pluck("id", &articleIDs)
+	for _, articleID := range articleIDs {
+		if err := softDeleteArticle(tx, articleID, now); err != nil {
+			return err
+		}
+	}
+
+	// 受影响的其他文章和评论，删除后需要重新计数
+	var touchedArticles, touchedComments []uint
+	tx.Model(&models.Like{}).Where("user_id = ?", userID).Distinct().Pluck("article_id", &touchedArticles)
+	var more []uint
+	tx.Model(&models.Favorite{}).Where("user_id = ?", userID).Distinct().Pluck("article_id", &more)
+	touchedArticles = append(touchedArticles, more...)
+	more = nil
+	tx.Model(&models.Comment{}).Where("user_id = ?", userID).Distinct().Pluck("article_id", &more)
+	touchedArticles = append(touchedArticles, more...)
+	tx.Model(&models.CommentLike{}).Where("user_id = ?", userID).Distinct().Pluck("comment_id", &touchedComments)
+
+	likes := likePairs(tx, &models.Like{}, "user_id = ? AND deleted_at IS NULL", userID)
+	favorites := likePairs(tx, &models.Favorite{}, "user_id = ? AND deleted_at IS NULL", userID)
+
+	steps := []*gorm.DB{
+		tx.Model(&models.CommentLike{}).Where("user_id = ?", userID).Update("deleted_at", now),
+		tx.Model(&models.Comment{}).Where("user_id = ?", userID).Update("deleted_at", now),
+		tx.Model(&models.Like{}).Where("user_id = ?", userID).Update("deleted_at", now),
+		tx.Model(&models.Favorite{}).Where("user_id = ?", userID).Update("deleted_at", now),
+		tx.Model(&models.UserFollow{}).Where("follower_id = ? OR followed_id = ?", userID, userID).Update("deleted_at", now),
+		tx.Model(&models.User{}).Where("id = ?", userID).Update("deleted_at", now),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+
+	syncToggleKeys("article:like:%d:%d", likes, false)
+	syncToggleKeys("article:favorite:%d:%d", favorites, false)
+
+	if err := recountComments(tx, touchedComments); err != nil {
+		return err
+	}
+	return recountArticles(tx, touchedArticles)
+}
+
+// restoreUser 恢复用户以及与其一同被删除的内容
+func restoreUser(tx *gorm.DB, user models.User) error {
+	deletedAt := user.DeletedAt.Time
+
+	if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+
+	var articles []models.Article
+	tx.Unscoped().Where("author_id = ? AND deleted_at = ?", user.ID, deletedAt).Find(&articles)
+	for _, article := range articles {
+		if err := restoreArticle(tx, article); err != nil {
+			return err
+		}
+	}
+
+	// 对方账号仍在回收站中的关注关系暂不恢复
+	liveUsers := tx.Model(&models.User{}).Select("id")
+	steps := []*gorm.DB{
+		tx.Unscoped().Model(&models.CommentLike{}).
+			Where("user_id = ? AND deleted_at = ?", user.ID, deletedAt).Update("deleted_at", nil),
+		tx.Unscoped().Model(&models.Comment{}).
+			Where("user_id = ? AND deleted_at = ? AND article_id IN (?)", user.ID, deletedAt, tx.Model(&models.Article{}).Select("id")).
+			Update("deleted_at", nil),
+		tx.Unscoped().Model(&models.Like{}).
+			Where("user_id = ? AND deleted_at = ?", user.ID, deletedAt).Update("deleted_at", nil),
+		tx.Unscoped().Model(&models.Favorite{}).
+			Where("user_id = ? AND deleted_at = ?", user.ID, deletedAt).Update("deleted_at", nil),
+		tx.Unscoped().Model(&models.UserFollow{}).
+			Where("(follower_id = ? OR followed_id = ?) AND deleted_at = ?", user.ID, user.ID, deletedAt).
+			Where("follower_id IN (?) AND followed_id IN (?)", liveUsers, liveUsers).
+			Update("deleted_at", nil),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+
+	syncToggleKeys("article:like:%d:%d", likePairs(tx, &models.Like{}, "user_id = ? AND deleted_at IS NULL", user.ID), true)
+	syncToggleKeys("article:favorite:%d:%d", likePairs(tx, &models.Favorite{}, "user_id = ? AND deleted_at IS NULL", user.ID), true)
+
+	var touchedArticles, touchedComments []uint
+	tx.Model(&models.Like{}).Where("user_id = ?", user.ID).Distinct().Pluck("article_id", &touchedArticles)
+	var more []uint
+	tx.Model(&models.Favorite{}).Where("user_id = ?", user.ID).Distinct().Pluck("article_id", &more)
+	touchedArticles = append(touchedArticles, more...)
+	more = nil
+	tx.Model(&models.Comment{}).Where("user_id = ?", user.ID).Distinct().Pluck("article_id", &more)
+	touchedArticles = append(touchedArticles, more...)
+	tx.Model(&models.CommentLike{}).Where("user_id = ?", user.ID).Distinct().Pluck("comment_id", &touchedComments)
+
+	if err := recountComments(tx, touchedComments); err != nil {
+		return err
+	}
+	return recountArticles(tx, touchedArticles)
+}
+
+// purgeArticle 彻底删除文章及其所有关联数据
+func purgeArticle(tx *gorm.DB, articleID uint) error {
+	commentIDs := tx.Unscoped().Model(&models.Comment{}).Select("id").Where("article_id = ?", articleID)
+	steps := []*gorm.DB{
+		tx.Unscoped().Where("comment_id IN (?)", commentIDs).Delete(&models.CommentLike{}),
+		tx.Unscoped().Where("target_type = ? AND target_id IN (?)", models.ModerationTargetComment, commentIDs).Delete(&models.ModerationRecord{}),
+		tx.Unscoped().Where("article_id = ?", articleID).Delete(&models.Comment{}),
+		tx.Where("article_id = ?", articleID).Delete(&models.Mention{}),
+		tx.Unscoped().Where("article_id = ?", articleID).Delete(&models.Like{}),
+		tx.Unscoped().Where("article_id = ?", articleID).Delete(&models.Favorite{}),
+		tx.Where("article_id = ?", articleID).Delete(&models.ArticlePicture{}),
+		tx.Exec("DELETE FROM article_tags WHERE article_id = ?", articleID),
+		tx.Unscoped().Where("target_type = ? AND target_id = ?", models.ModerationTargetArticle, articleID).Delete(&models.ModerationRecord{}),
+		tx.Unscoped().Delete(&models.Article{}, articleID),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+	return nil
+}
+
+// purgeComment 彻底删除评论及其点赞
+func purgeComment(tx *gorm.DB, commentID uint) error {
+	steps := []*gorm.DB{
+		tx.Unscoped().Where("comment_id = ?", commentID).Delete(&models.CommentLike{}),
+		tx.Unscoped().Where("target_type = ? AND target_id = ?", models.ModerationTargetComment, commentID).Delete(&models.ModerationRecord{}),
+		tx.Where("source_type = ? AND source_id = ?", models.MentionSourceComment, commentID).Delete(&models.Mention{}),
+		tx.Unscoped().Delete(&models.Comment{}, commentID),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return step.Error
+		}
+	}
+	return nil
+}
+
+// purgeUser 彻底删除用户及其全部数据，返回需要从 MinIO 删除的图片对象
+func purgeUser(tx *gorm.DB, userID uint) ([]string, error) {
+	var articleIDs []uint
+	tx.Unscoped().Model(&models.Article{}).Where("author_id = ?", userID).Pluck("id", &articleIDs)
+	for _, articleID := range articleIDs {
+		if err := purgeArticle(tx, articleID); err != nil {
+			return nil, err
+		}
+	}
+
+	var commentIDs []uint
+	tx.Unscoped().Model(&models.Comment{}).Where("user_id = ?", userID).Pluck("id", &commentIDs)
+	for _, commentID := range commentIDs {
+		if err := purgeComment(tx, commentID); err != nil {
+			return nil, err
+		}
+	}
+
+	var pictures []models.Picture
+	tx.Unscoped().Select("id, url").Where("user_id = ?", userID).Find(&pictures)
+	objects := make([]string, 0, len(pictures))
+	pictureIDs := make([]uint, 0, len(pictures))
+	for _, picture := range pictures {
+		objects = append(objects, "images/"+strings.TrimPrefix(picture.URL, "/static/images/"))
+		pictureIDs = append(pictureIDs, picture.ID)
+	}
+
+	steps := []*gorm.DB{
+		tx.Unscoped().Where("user_id = ?", userID).Delete(&models.CommentLike{}),
+		tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Like{}),
+		tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Favorite{}),
+		tx.Unscoped().Where("follower_id = ? OR followed_id = ?", userID, userID).Delete(&models.UserFollow{}),
+		tx.Where("mentioner_id = ? OR mentioned_id = ?", userID, userID).Delete(&models.Mention{}),
+	}
+	if len(pictureIDs) > 0 {
+		steps = append(steps,
+			tx.Where("picture_id IN ?", pictureIDs).Delete(&models.ArticlePicture{}),
+			tx.Unscoped().Where("target_type = ? AND target_id IN ?", models.ModerationTargetPicture, pictureIDs).Delete(&models.ModerationRecord{}),
+			tx.Unscoped().Where("id IN ?", pictureIDs).Delete(&models.Picture{}),
+		)
+	}
+	steps = append(steps, tx.Unscoped().Delete(&models.User{}, userID))
+	for _, step := range steps {
+		if step.Error != nil {
+			return nil, step.Error
+		}
+	}
+	return objects, nil
+}
+
+// removeObjects 从 MinIO 删除图片对象（尽力而为）
+func removeObjects(objects []string) {
+	for _, object := range objects {
+		if err := global.MinIOClient.RemoveObject(context.Background(), global.MinIOConf.BucketName, object, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("删除图片对象 %s 失败: %v\n", object, err)
+		}
+	}
+}
+
+// purgeTrashItem 彻底删除回收站中的单个对象
+func purgeTrashItem(trashType string, id uint) error {
+	var objects []string
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		switch trashType {
+		case TrashArticles:
+			return purgeArticle(tx, id)
+		case TrashComments:
+			return purgeComment(tx, id)
+		case TrashUsers:
+			var err error
+			objects, err = purgeUser(tx, id)
+			return err
+		}
+		return nil
+	})
+	if err == nil {
+		removeObjects(objects)
+	}
+	return err
+}
+
+// trashModel 返回回收站类型对应的模型
+func trashModel(trashType string) interface{} {
+	switch trashType {
+	case TrashArticles:
+		return &models.Article{}
+	case TrashComments:
+		return &models.Comment{}
+	case TrashUsers:
+		return &models.User{}
+	}
+	return nil
+}
+
+// AdminGetTrash 浏览回收站中的文章、评论或用户
+func AdminGetTrash(c *gin.Context) {
+	trashType := c.Param("type")
+	model := trashModel(trashType)
+	if model == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的回收站类型"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	query := global.Db.Unscoped().Model(model).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	query.Count(&total)
+
+	items := make([]gin.H, 0)
+	switch trashType {
+	case TrashArticles:
+		var articles []models.Article
+		query.Select("id, title, author_id, created_at, deleted_at").
+			Offset(offset).Limit(limit).Order("deleted_at DESC").Find(&articles)
+		for _, article := range articles {
+			items = append(items, gin.H{
+				"id":         article.ID,
+				"title":      article.Title,
+				"author_id":  article.AuthorID,
+				"created_at": article.CreatedAt,
+				"deleted_at": article.DeletedAt.Time,
+			})
+		}
+	case TrashComments:
+		var comments []models.Comment
+		query.Select("id, content, user_id, article_id, created_at, deleted_at").
+			Offset(offset).Limit(limit).Order("deleted_at DESC").Find(&comments)
+		for _, comment := range comments {
+			items = append(items, gin.H{
+				"id":         comment.ID,
+				"content":    comment.Content,
+				"user_id":    comment.UserID,
+				"article_id": comment.ArticleID,
+				"created_at": comment.CreatedAt,
+				"deleted_at": comment.DeletedAt.Time,
+			})
+		}
+	case TrashUsers:
+		var users []models.User
+		query.Select("id, username, nickname, avatar, email, phone, created_at, deleted_at").
+			Offset(offset).Limit(limit).Order("deleted_at DESC").Find(&users)
+		for _, user := range users {
+			items = append(items, gin.H{
+				"id":         user.ID,
+				"username":   user.Username,
+				"nickname":   user.Nickname,
+				"avatar":     user.Avatar,
+				"email":      user.Email,
+				"phone":      user.Phone,
+				"created_at": user.CreatedAt,
+				"deleted_at": user.DeletedAt.Time,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"page":  page,
+		"limit": limit,
+		"items": items,
+	})
+}
+
+// AdminRestoreTrash 从回收站恢复对象及其级联删除的数据
+func AdminRestoreTrash(c *gin.Context) {
+	trashType := c.Param("type")
+	model := trashModel(trashType)
+	if model == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的回收站类型"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+
+	if err := global.Db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(model).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "回收站中不存在该对象"})
+		return
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		switch item := model.(type) {
+		case *models.Article:
+			return restoreArticle(tx, *item)
+		case *models.Comment:
+			return restoreComment(tx, *item)
+		case *models.User:
+			return restoreUser(tx, *item)
+		}
+		return nil
+	})
+	if errors.Is(err, errParentDeleted) {
+		c.JSON(http.StatusConflict, gin.H{"error": "所属的文章或用户仍在回收站中，请先恢复"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "恢复成功"})
+}
+
+// AdminPurgeTrash 从回收站中彻底删除对象
+func AdminPurgeTrash(c *gin.Context) {
+	trashType := c.Param("type")
+	model := trashModel(trashType)
+	if model == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的回收站类型"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的ID"})
+		return
+	}
+
+	// 只允许彻底删除已在回收站中的对象
+	var count int64
+	global.Db.Unscoped().Model(model).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "回收站中不存在该对象"})
+		return
+	}
+
+	if err := purgeTrashItem(trashType, uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "彻底删除失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已彻底删除"})
+}
+
+// PurgeExpiredTrash 彻底删除超过保留期的回收站数据，返回删除数量
+func PurgeExpiredTrash(retention time.Duration) int {
+	cutoff := time.Now().Add(-retention)
+	purged := 0
+
+	// 先清理用户，再清理文章和评论，避免重复处理级联数据
+	for _, trashType := range []string{TrashUsers, TrashArticles, TrashComments} {
+		var ids []uint
+		global.Db.Unscoped().Model(trashModel(trashType)).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Pluck("id", &ids)
+		for _, id := range ids {
+			if err := purgeTrashItem(trashType, id); err != nil {
+				log.Printf("清理回收站 %s#%d 失败: %v\n", trashType, id, err)
+				continue
+			}
+			purged++
+		}
+	}
+	return purged
+}
+
+// StartTrashPurger 启动回收站定时清理任务
+func StartTrashPurger(retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// 多实例部署时只由抢到锁的实例执行
+			if !global.RedisDB.SetNX(trashPurgeLockKey, "1", interval/2).Val() {
+				continue
+			}
+			if purged := PurgeExpiredTrash(retention); purged > 0 {
+				log.Printf("回收站定时清理完成，共删除 %d 条数据\n", purged)
+			}
+		}
+	}()
+}
@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount    = 10               // 每次生成的恢复码数量
+	twoFactorSetupTTL    = 10 * time.Minute // 开启两步验证前待确认密钥的有效期
+	maxChallengeAttempts = 5                // 每个登录挑战允许的验证码尝试次数
+)
+
+var errTwoFactorAlreadyEnabled = errors.New("two factor already enabled")
+
+func twoFactorIssuer() string {
+	if issuer := config.AppConfig.TwoFactor.Issuer; issuer != "" {
+		return issuer
+	}
+	return "GreenBook"
+}
+
+func twoFactorChallengeTTL() time.Duration {
+	if seconds := config.AppConfig.TwoFactor.ChallengeTTL; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+func twoFactorSetupKey(userID uint) string {
+	return fmt.Sprintf("2fa:setup:%d", userID)
+}
+
+// verifyTOTPOnce 校验验证码，同一验证码在有效期内只能使用一次
+func verifyTOTPOnce(subject, secret, code string) bool {
+	step, ok := utils.VerifyTOTP(secret, code, time.Now())
+	if !ok {
+		return false
+	}
+	return global.RedisDB.SetNX(fmt.Sprintf("2fa:used:%s:%d", subject, step), "1", 3*time.Minute).Val()
+}
+
+// verifyUserTOTP 校验用户的动态验证码
+func verifyUserTOTP(user *models.User, code string) bool {
+	return verifyTOTPOnce(fmt.Sprintf("%d", user.ID), user.TwoFactorSecret, code)
+}
+
+// useRecoveryCode 使用一次恢复码，成功后该恢复码作废
+func useRecoveryCode(userID uint, code string) bool {
+	result := global.Db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// verifySecondFactor 校验动态验证码或恢复码
+func verifySecondFactor(user *models.User, code, recoveryCode string) bool {
+	if code != "" {
+		return verifyUserTOTP(user, code)
+	}
+	if recoveryCode != "" {
+		return useRecoveryCode(user.ID, recoveryCode)
+	}
+	return false
+}
+
+// replaceRecoveryCodes 作废旧恢复码并生成新的一组，明文只在此时返回一次
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: utils.HashRecoveryCode(code)})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// remainingRecoveryCodes 剩余可用的恢复码数量
+func remainingRecoveryCodes(userID uint) int64 {
+	var count int64
+	global.Db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count)
+	return count
+}
+
+// GetTwoFactorStatus 获取两步验证状态
+func GetTwoFactorStatus(c *gin.Context) {
+	var user models.User
+	if err := global.Db.First(&user, c.GetUint("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	response := gin.H{
+		"enabled":    user.TwoFactorEnabled,
+		"enabled_at": user.TwoFactorEnabledAt,
+	}
+	if user.TwoFactorEnabled {
+		response["recovery_codes_remaining"] = remainingRecoveryCodes(user.ID)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SetupTwoFactor 生成待确认的 TOTP 密钥，返回 otpauth 链接和二维码
+func SetupTwoFactor(c *gin.Context) {
+	var user models.User
+	if err := global.Db.First(&user, c.GetUint("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if user.TwoFactorEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "两步验证已开启"})
+		return
+	}
+
+	secret := utils.GenerateTOTPSecret()
+	if err := global.RedisDB.Set(twoFactorSetupKey(user.ID), secret, twoFactorSetupTTL).Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+		return
+	}
+
+	uri := utils.TOTPURI(twoFactorIssuer(), user.Username, secret)
+	png, err := utils.QRCodePNG(uri, 256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成二维码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
+		"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		"expires_in":  int(twoFactorSetupTTL.Seconds()),
+	})
+}
+
+// GetTwoFactorSetupQRCode 以 PNG 图片返回待确认密钥的二维码
+func GetTwoFactorSetupQRCode(c *gin.Context) {
+	var user models.User
+	if err := global.Db.Select("id, username").First(&user, c.GetUint("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	secret, err := global.RedisDB.Get(twoFactorSetupKey(user.ID)).Result()
+	if err != nil || secret == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "请先生成两步验证密钥"})
+		return
+	}
+
+	png, err := utils.QRCodePNG(utils.TOTPURI(twoFactorIssuer(), user.Username, secret), 256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成二维码失败"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// TwoFactorCodeRequest 两步验证码请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// EnableTwoFactor 校验验证码后开启两步验证，并返回恢复码
+func EnableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	secret, err := global.RedisDB.Get(twoFactorSetupKey(userID)).Result()
+	if err != nil || secret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "密钥已过期，请重新生成"})
+		return
+	}
+	if !verifyTOTPOnce(fmt.Sprintf("%d", userID), secret, req.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	var codes []string
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND two_factor_enabled = ?", userID, false).
+			Updates(map[string]interface{}{
+				"two_factor_enabled":    true,
+				"two_factor_secret":     secret,
+				"two_factor_enabled_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTwoFactorAlreadyEnabled
+		}
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err == errTwoFactorAlreadyEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "两步验证已开启"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "开启两步验证失败"})
+		return
+	}
+	global.RedisDB.Del(twoFactorSetupKey(userID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "两步验证已开启，请妥善保存恢复码",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactorRequest 关闭两步验证请求，需要密码和验证码（或恢复码）
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// DisableTwoFactor 关闭两步验证
+func DisableTwoFactor(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.First(&user, c.GetUint("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if !user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "两步验证未开启"})
+		return
+	}
+	if !utils.CheckPassword(req.Password, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+		return
+	}
+	if !verifySecondFactor(&user, req.Code, req.RecoveryCode) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"two_factor_enabled":    false,
+			"two_factor_secret":     "",
+			"two_factor_enabled_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "关闭两步验证失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "两步验证已关闭"})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.First(&user, c.GetUint("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if !user.TwoFactorEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "两步验证未开启"})
+		return
+	}
+	if !verifyUserTOTP(&user, req.Code) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	var codes []string
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成恢复码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// startTwoFactorChallenge 密码校验通过后签发登录挑战令牌，完成第二步后才签发访问令牌
+func startTwoFactorChallenge(c *gin.Context, user *models.User, method string) {
+	binding := utils.PasswordFingerprint(user.Password) + ":" + method
+	token, err := utils.GenerateActionToken(user.ID, utils.TokenTypeTwoFactor, binding, twoFactorChallengeTTL())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"two_factor_required": true,
+		"challenge_token":     token,
+		"expires_in":          int(twoFactorChallengeTTL().Seconds()),
+	})
+}
+
+// TwoFactorLoginRequest 登录第二步请求，code 与 recovery_code 二选一
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// VerifyTwoFactorLogin 登录第二步：校验动态验证码或恢复码后签发令牌
+func VerifyTwoFactorLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	claims, err := utils.ValidateActionToken(req.ChallengeToken, utils.TokenTypeTwoFactor)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "登录已过期，请重新登录"})
+		return
+	}
+
+	var user models.User
+	if err := global.Db.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+	// 挑战期间修改过密码或关闭了两步验证时作废
+	fingerprint, method, _ := strings.Cut(claims.Binding, ":")
+	if !user.TwoFactorEnabled || utils.PasswordFingerprint(user.Password) != fingerprint || method == "" {
+		utils.RevokeActionToken(claims)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "登录已过期，请重新登录"})
+		return
+	}
+
+	// 限制单个挑战的尝试次数
+	attemptsKey := fmt.Sprintf("2fa:attempts:%s", claims.Id)
+	attempts, err := global.RedisDB.Incr(attemptsKey).Result()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "校验失败"})
+		return
+	}
+	global.RedisDB.Expire(attemptsKey, twoFactorChallengeTTL())
+	if attempts > maxChallengeAttempts {
+		utils.RevokeActionToken(claims)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误次数过多，请重新登录"})
+		return
+	}
+
+	if !verifySecondFactor(&user, strings.TrimSpace(req.Code), strings.TrimSpace(req.RecoveryCode)) {
+		loginFailed(c, LoginScopeUser, user.Username, "wrong_2fa_code", http.StatusUnauthorized, "验证码错误")
+		return
+	}
+
+	// 并发提交时只有一个请求能完成登录
+	if revoked, err := utils.RevokeActionToken(claims); err != nil || !revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "登录已过期，请重新登录"})
+		return
+	}
+	if req.Code == "" {
+		log.Printf("user %d logged in with a recovery code, %d remaining", user.ID, remainingRecoveryCodes(user.ID))
+	}
+
+	completeLogin(c, &user, method)
+}
+
+// verifyAdminTwoFactor 管理员两步验证策略，ok 为 false 时已写入响应；verified 表示确实校验了验证码
+func verifyAdminTwoFactor(c *gin.Context, username, code string) (ok, verified bool) {
+	policy := config.AppConfig.TwoFactor
+	if !policy.RequireForAdmin {
+		return true, false
+	}
+	if policy.AdminSecret == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "管理员两步验证未配置"})
+		return false, false
+	}
+	if code == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "请输入两步验证码", "two_factor_required": true})
+		return false, false
+	}
+	if !verifyTOTPOnce("admin:"+username, policy.AdminSecret, code) {
+		loginFailed(c, LoginScopeAdmin, username, "wrong_2fa_code", http.StatusUnauthorized, "验证码错误")
+		return false, false
+	}
+	return true, true
+}
@@ -175,7 +175,7 @@ func GetCurrentUserInfo(c *gin.Context) {
 
 	// 返回用户信息和统计数据
 	c.JSON(http.StatusOK, gin.H{
-		"user":              user,
+		"user":              newUserPrivate(&user),
 		"user_articles":     userArticleList,
 		"favorite_articles": favoriteArticleList,
 		"liked_articles":    likedArticleList,
@@ -193,7 +193,7 @@ func GetUserProfile(c *gin.Context) {
 
 	// 查询用户公开信息，过滤敏感字段
 	var user models.User
-	if err := global.Db.Select("id, nickname, avatar, gender, created_at").First(&user, id).Error; err != nil {
+	if err := global.Db.Select("nickname, avatar, gender, bio, location, cover_image, created_at, "+privacyColumns).First(&user, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
 		return
 	}
@@ -223,22 +223,104 @@ func GetUserProfile(c *gin.Context) {
 		isFollowing = followCount > 0
 	}
 
-	// 查询该用户的文章列表
-	var userArticles []models.Article
-	if err := global.Db.Select("id, title, author_id, like_count, created_at").
-		Preload("Author", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, nickname, avatar")
-		}).
-		Where("author_id = ?", id).
-		Order("created_at DESC").
-		Find(&userArticles).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户文章失败"})
+	// 按隐私设置决定可以展示的内容
+	viewerID := c.GetUint("userID")
+
+	// 被对方拉黑时不显示对方主页
+	if hasBlocked(user.ID, viewerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
 		return
 	}
+	// 拉黑对方后不再显示对方的内容
+	isBlocking := hasBlocked(viewerID, user.ID)
+
+	// 私密账号显示是否已发送关注请求
+	var followRequested bool
+	if user.PrivateAccount && !isFollowing {
+		var requestCount int64
+		global.Db.Model(&models.FollowRequest{}).
+			Where("requester_id = ? AND target_id = ? AND status = ?", viewerID, user.ID, models.FollowRequestPending).
+			Count(&requestCount)
+		followRequested = requestCount > 0
+	}
+	contentVisible := !isBlocking && canViewContent(viewerID, &user)
+	favoritesVisible := !isBlocking && canViewFavorites(viewerID, &user)
+	likesVisible := !isBlocking && canViewLikes(viewerID, &user)
 
-	// 构建用户文章响应数据
+	// 查询该用户的文章列表，私密账号仅粉丝可见
 	userArticleList := make([]gin.H, 0)
-	for _, article := range userArticles {
+	if contentVisible {
+		var userArticles []models.Article
+		if err := global.Db.Select("id, title, author_id, like_count, created_at").
+			Where("author_id = ?", id).
+			Where("moderation_status = ? OR author_id = ?", models.ModerationAllow, currentUserID).
+			Order("created_at DESC").
+			Find(&userArticles).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户文章失败"})
+			return
+		}
+		userArticleList = profileArticleList(userArticles, viewerID)
+	}
+
+	// 查询被查看用户的收藏文章列表
+	userFavoriteList := make([]gin.H, 0)
+	if favoritesVisible {
+		var userFavoriteArticles []models.Article
+		if err := global.Db.Select("articles.id, articles.title, articles.author_id, articles.like_count, articles.created_at").
+			Joins("JOIN favorites ON favorites.article_id = articles.id AND favorites.deleted_at IS NULL").
+			Where("favorites.user_id = ?", id).
+			Where("articles.moderation_status = ?", models.ModerationAllow).
+			Scopes(visibleArticlesScope(viewerID)).
+			Order("favorites.created_at DESC").
+			Find(&userFavoriteArticles).Error; err == nil {
+			userFavoriteList = profileArticleList(userFavoriteArticles, viewerID)
+		}
+	}
+
+	// 查询被查看用户点赞的文章列表
+	userLikedList := make([]gin.H, 0)
+	if likesVisible {
+		var userLikedArticles []models.Article
+		if err := global.Db.Select("articles.id, articles.title, articles.author_id, articles.like_count, articles.created_at").
+			Joins("JOIN likes ON likes.article_id = articles.id AND likes.deleted_at IS NULL").
+			Where("likes.user_id = ?", id).
+			Where("articles.moderation_status = ?", models.ModerationAllow).
+			Scopes(visibleArticlesScope(viewerID)).
+			Order("likes.created_at DESC").
+			Find(&userLikedArticles).Error; err == nil {
+			userLikedList = profileArticleList(userLikedArticles, viewerID)
+		}
+	}
+
+	// 返回用户公开信息和统计数据
+	profile := newUserPublic(&user)
+	profile.FollowingCount = uint(followingCount)
+	profile.FollowersCount = uint(followerCount)
+	profile.PostsCount = uint(articleCount)
+	c.JSON(http.StatusOK, gin.H{
+		"user":              UserProfile{UserPublic: profile, PrivateAccount: user.PrivateAccount},
+		"is_following":      isFollowing,
+		"follow_requested":  followRequested,
+		"is_blocking":       isBlocking,
+		"is_muted":          hasMuted(viewerID, user.ID),
+		"user_articles":     userArticleList,
+		"favorite_articles": userFavoriteList,
+		"liked_articles":    userLikedList,
+		// 当前用户可以查看的内容，前端据此显示"私密账号"或"已隐藏"
+		"visibility": gin.H{
+			"articles":  contentVisible,
+			"favorites": favoritesVisible,
+			"likes":     likesVisible,
+			"following": !isBlocking && canViewFollowList(viewerID, &user, false),
+			"followers": !isBlocking && canViewFollowList(viewerID, &user, true),
+		},
+	})
+}
+
+// profileArticleList 构建主页中的文章列表
+func profileArticleList(articles []models.Article, viewerID uint) []gin.H {
+	list := make([]gin.H, 0, len(articles))
+	for _, article := range articles {
 		// 获取封面图URL（order=0的图片）
 		var coverImageURL string
 		var picture models.Picture
@@ -251,13 +333,13 @@ func GetUserProfile(c *gin.Context) {
 
 		// 检查当前用户是否点赞了这篇文章
 		var isLiked bool
-		if currentUserID != nil {
+		if viewerID != 0 {
 			var likeCount int64
-			global.Db.Model(&models.Like{}).Where("user_id = ? AND article_id = ? AND deleted_at IS NULL", currentUserID, article.ID).Count(&likeCount)
+			global.Db.Model(&models.Like{}).Where("user_id = ? AND article_id = ? AND deleted_at IS NULL", viewerID, article.ID).Count(&likeCount)
 			isLiked = likeCount > 0
 		}
 
-		userArticleList = append(userArticleList, gin.H{
+		list = append(list, gin.H{
 			"id":         article.ID,
 			"title":      article.Title,
 			"cover_url":  coverImageURL,
@@ -265,65 +347,7 @@ func GetUserProfile(c *gin.Context) {
 			"is_liked":   isLiked,
 		})
 	}
-
-	// 查询被查看用户的收藏文章列表
-	var userFavoriteArticles []models.Article
-	userFavoriteList := make([]gin.H, 0)
-	if err := global.Db.Select("articles.id, articles.title, articles.author_id, articles.like_count, articles.created_at").
-		Preload("Author", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, nickname, avatar")
-		}).
-		Joins("JOIN favorites ON favorites.article_id = articles.id AND favorites.deleted_at IS NULL").
-		Where("favorites.user_id = ?", id).
-		Order("favorites.created_at DESC").
-		Find(&userFavoriteArticles).Error; err == nil {
-
-		// 构建收藏文章响应数据
-		for _, article := range userFavoriteArticles {
-			// 获取封面图URL（order=0的图片）
-			var coverImageURL string
-			var picture models.Picture
-			if err := global.Db.Select("pictures.url").
-				Joins("JOIN article_pictures ON article_pictures.picture_id = pictures.id").
-				Where("article_pictures.article_id = ? AND article_pictures.`order` = 0", article.ID).
-				First(&picture).Error; err == nil {
-				coverImageURL = picture.URL
-			}
-
-			// 检查当前用户是否点赞了这篇文章
-			var isLiked bool
-			if currentUserID != nil {
-				var likeCount int64
-				global.Db.Model(&models.Like{}).Where("user_id = ? AND article_id = ? AND deleted_at IS NULL", currentUserID, article.ID).Count(&likeCount)
-				isLiked = likeCount > 0
-			}
-
-			userFavoriteList = append(userFavoriteList, gin.H{
-				"id":         article.ID,
-				"title":      article.Title,
-				"cover_url":  coverImageURL,
-				"like_count": article.LikeCount,
-				"is_liked":   isLiked,
-			})
-		}
-	}
-
-	// 返回用户公开信息和统计数据
-	c.JSON(http.StatusOK, gin.H{
-		"user": gin.H{
-			"id":              user.ID,
-			"nickname":        user.Nickname,
-			"avatar":          user.Avatar,
-			"gender":          user.Gender,
-			"created_at":      user.CreatedAt,
-			"following_count": followingCount,
-			"followers_count": followerCount,
-			"posts_count":     articleCount,
-		},
-		"is_following":      isFollowing,
-		"user_articles":     userArticleList,
-		"favorite_articles": userFavoriteList,
-	})
+	return list
 }
 
 // UpdateUserAvatarRequest 更新用户头像请求结构
@@ -2,9 +2,10 @@ package controllers
 
 import (
 	"errors"
-	"fmt"
+	"log"
 	"net/http"
 
+	"github.com/appabin/greenbook/config"
 	"github.com/appabin/greenbook/global"
 	"github.com/appabin/greenbook/models"
 	"github.com/appabin/greenbook/utils"
@@ -13,8 +14,9 @@ import (
 )
 
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password" binding:"required"`
+	CaptchaToken string `json:"captcha_token"` // 失败次数过多后需要
 }
 
 type RegisterRequest struct {
@@ -33,11 +35,16 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// 检查锁定状态和验证码
+	if !checkLoginGuard(c, LoginScopeUser, req.Username, req.CaptchaToken) {
+		return
+	}
+
 	// 查询用户
 	var user models.User
 	if err := global.Db.Where("username = ?", req.Username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+			loginFailed(c, LoginScopeUser, req.Username, "user_not_found", http.StatusUnauthorized, "用户不存在")
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库查询失败"})
 		}
@@ -46,12 +53,33 @@ func Login(c *gin.Context) {
 
 	// 验证密码
 	if !utils.CheckPassword(req.Password, user.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+		loginFailed(c, LoginScopeUser, req.Username, "wrong_password", http.StatusUnauthorized, "密码错误")
+		return
+	}
+
+	// 开启邮箱验证要求时，未验证的账号不能登录
+	if config.AppConfig.Account.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "邮箱尚未验证，请先完成邮箱验证", "email_not_verified": true})
+		return
+	}
+
+	// 开启两步验证的账号需要完成第二步
+	if user.TwoFactorEnabled {
+		startTwoFactorChallenge(c, &user, LoginMethodPassword)
 		return
 	}
 
-	// 生成JWT token（关键修改点：使用UserID）
-	token, err := utils.GenerateJWT(fmt.Sprintf("%d", user.ID))
+	completeLogin(c, &user, LoginMethodPassword)
+}
+
+// completeLogin 登录校验全部通过后签发令牌并返回用户信息
+func completeLogin(c *gin.Context, user *models.User, method string) {
+	if user.Username != "" {
+		loginSucceeded(LoginScopeUser, user.Username)
+	}
+
+	// 签发访问令牌和刷新令牌
+	pair, err := issueTokens(c, user.ID, method)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
 		return
@@ -67,10 +95,16 @@ func Login(c *gin.Context) {
 		"avatar":    user.Avatar,
 		"createdAt": user.CreatedAt,
 	}
+	// 冷静期内登录时提示可撤销注销
+	if user.DeletionScheduledAt != nil {
+		userSafe["deletion_scheduled_at"] = user.DeletionScheduledAt
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  userSafe,
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+		"user":          userSafe,
 	})
 }
 
@@ -82,6 +116,11 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// 限制注册频率
+	if !checkRegisterLimit(c) {
+		return
+	}
+
 	// 检查用户名唯一性
 	var existingUser models.User
 	if err := global.Db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
@@ -114,8 +153,22 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// 生成JWT token（关键修改点：使用UserID）
-	token, err := utils.GenerateJWT(fmt.Sprintf("%d", user.ID))
+	// 发送邮箱验证邮件
+	if err := sendVerificationEmail(&user); err != nil {
+		log.Printf("生成邮箱验证令牌失败 user=%d: %v", user.ID, err)
+	}
+
+	// 开启邮箱验证要求时，验证完成后才能登录
+	if config.AppConfig.Account.RequireVerifiedEmail {
+		c.JSON(http.StatusCreated, gin.H{
+			"message":                     "注册成功，请查收验证邮件完成激活",
+			"email_verification_required": true,
+		})
+		return
+	}
+
+	// 签发访问令牌和刷新令牌
+	pair, err := issueTokens(c, user.ID, LoginMethodPassword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
 		return
@@ -132,7 +185,9 @@ func Register(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  userSafe,
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    pair.ExpiresIn,
+		"user":          userSafe,
 	})
 }
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// WeChatDataRequest 小程序加密数据，code 不为空时先用它刷新 session_key
+type WeChatDataRequest struct {
+	EncryptedData string `json:"encryptedData" binding:"required"`
+	IV            string `json:"iv" binding:"required"`
+	Code          string `json:"code"`
+}
+
+// wechatProvider 获取微信小程序登录配置，返回 false 时已写入响应
+func wechatProvider(c *gin.Context) (*utils.WeChatMiniProvider, bool) {
+	provider, ok := utils.AuthProviders["wechat"].(*utils.WeChatMiniProvider)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "微信登录未配置"})
+		return nil, false
+	}
+	return provider, true
+}
+
+// wechatSessionKey 获取解密使用的 session_key，传入 code 时重新换取并校验是否为同一微信账号
+func wechatSessionKey(c *gin.Context, provider *utils.WeChatMiniProvider, user *models.User, code string) (string, bool) {
+	if user.OpenID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未绑定微信"})
+		return "", false
+	}
+	if code == "" {
+		if user.SessionKey == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "微信会话已过期，请重新获取code"})
+			return "", false
+		}
+		return user.SessionKey, true
+	}
+
+	identity, err := exchangeIdentity(c, provider, utils.AuthExchange{Code: code})
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "微信授权失败", "detail": err.Error()})
+		return "", false
+	}
+	if identity.Subject != *user.OpenID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "微信账号与当前用户不一致"})
+		return "", false
+	}
+	global.Db.Model(user).Update("session_key", identity.SessionKey)
+	return identity.SessionKey, true
+}
+
+// respondWeChatDecryptError 解密失败时写入响应
+func respondWeChatDecryptError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrWeChatWatermarkAppID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "数据来源不正确"})
+	case errors.Is(err, utils.ErrWeChatWatermarkExpired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "数据已过期，请重新获取"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "数据解密失败，请重新获取code后重试"})
+	}
+}
+
+// decryptWeChatRequest 校验用户并解密请求中的数据，返回 false 时已写入响应
+func decryptWeChatRequest(c *gin.Context, user *models.User, v interface{}) bool {
+	var req WeChatDataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return false
+	}
+
+	provider, ok := wechatProvider(c)
+	if !ok {
+		return false
+	}
+	if err := global.Db.First(user, c.GetUint("userID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return false
+	}
+	sessionKey, ok := wechatSessionKey(c, provider, user, req.Code)
+	if !ok {
+		return false
+	}
+
+	if err := provider.DecryptData(sessionKey, req.EncryptedData, req.IV, v); err != nil {
+		respondWeChatDecryptError(c, err)
+		return false
+	}
+	return true
+}
+
+// RefreshWeChatProfile 使用微信用户信息更新昵称、头像和性别
+func RefreshWeChatProfile(c *gin.Context) {
+	var user models.User
+	var info utils.WeChatUserInfo
+	if !decryptWeChatRequest(c, &user, &info) {
+		return
+	}
+	if info.OpenID != "" && info.OpenID != *user.OpenID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "微信账号与当前用户不一致"})
+		return
+	}
+
+	update := &profileUpdate{updates: map[string]interface{}{}}
+	skipped := []string{}
+
+	nickname := truncateRunes(strings.TrimSpace(info.NickName), config.AppConfig.Profile.NicknameMaxLength)
+	if nickname != "" && nickname != user.Nickname {
+		// 昵称已被其他用户使用时保留原昵称
+		if config.AppConfig.Profile.NicknameUnique && valueTaken("nickname", nickname, user.ID, false) {
+			skipped = append(skipped, "nickname")
+		} else {
+			update.set("nickname", user.Nickname, nickname, nil)
+		}
+	}
+	if info.AvatarURL != "" {
+		update.set("avatar", user.Avatar, truncate(info.AvatarURL, 500), nil)
+	}
+	if info.Gender <= 2 {
+		update.set("gender", user.Gender, info.Gender, nil)
+	}
+
+	if len(update.changes) > 0 {
+		if err := update.save(&user, c.ClientIP()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新资料失败"})
+			return
+		}
+		global.Db.First(&user, user.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "资料已同步",
+		"skipped": skipped,
+		"profile": newUserPrivate(&user),
+	})
+}
+
+// RefreshWeChatPhone 使用微信授权的手机号更新绑定手机号
+func RefreshWeChatPhone(c *gin.Context) {
+	var user models.User
+	var info utils.WeChatPhoneInfo
+	if !decryptWeChatRequest(c, &user, &info) {
+		return
+	}
+
+	phone := strings.TrimSpace(info.PurePhoneNumber)
+	if !phonePattern.MatchString(phone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "手机号格式不正确"})
+		return
+	}
+
+	update := &profileUpdate{updates: map[string]interface{}{}}
+	update.set("phone", user.Phone, phone, nil)
+	if update.changed("phone") {
+		if valueTaken("phone", phone, user.ID, true) {
+			c.JSON(http.StatusConflict, gin.H{"error": "手机号已被使用"})
+			return
+		}
+		if err := update.save(&user, c.ClientIP()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新手机号失败"})
+			return
+		}
+		global.Db.First(&user, user.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "手机号已更新", "profile": newUserPrivate(&user)})
+}
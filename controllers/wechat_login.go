@@ -2,7 +2,7 @@ package controllers
 
 import (
 	"errors"
-	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/appabin/greenbook/global"
@@ -28,32 +28,36 @@ func WeChatLogin(c *gin.Context) {
 		return
 	}
 
-	// 获取微信配置（建议从配置读取）
-	appID := "YOUR_APP_ID"
-	appSecret := "YOUR_APP_SECRET"
+	// 微信配置见 config.yml 的 wechat 段
+	provider, ok := wechatProvider(c)
+	if !ok {
+		return
+	}
 
 	// 获取微信会话信息
-	sessionRes, err := utils.GetWeChatSession(appID, appSecret, req.Code)
+	identity, err := exchangeIdentity(c, provider, utils.AuthExchange{Code: req.Code})
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "微信登录失败", "detail": err.Error()})
 		return
 	}
-	//可能有问题
-	// 查询已有用户
-	var user models.User
-	dbResult := global.Db.Where("open_id = ?", sessionRes.OpenID).First(&user)
+
+	// 查询已绑定的用户
+	user, err := findIdentityUser(identity)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user, err = findLegacyWeChatUser(identity)
+	}
 
 	// 处理首次登录用户
-	if dbResult.Error != nil && errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		// 解密手机号（首次登录需要）
 		var phone string
 		if req.EncryptedData != "" && req.IV != "" {
-			decrypted, err := utils.DecryptWeChatData(sessionRes.SessionKey, req.EncryptedData, req.IV)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "数据解密失败"})
+			var info utils.WeChatPhoneInfo
+			if err := provider.DecryptData(identity.SessionKey, req.EncryptedData, req.IV, &info); err != nil {
+				respondWeChatDecryptError(c, err)
 				return
 			}
-			phone = fmt.Sprintf("%v", decrypted["purePhoneNumber"])
+			phone = info.PurePhoneNumber
 		} else if req.Phone != "" {
 			phone = req.Phone
 		} else {
@@ -62,42 +66,43 @@ func WeChatLogin(c *gin.Context) {
 		}
 
 		// 创建新用户
-		user = models.User{
-			OpenID:     &sessionRes.OpenID,
-			UnionID:    sessionRes.UnionID,
-			SessionKey: sessionRes.SessionKey,
-			Phone:      phone,
+		user = &models.User{
+			Phone: phone,
 			// 设置默认值
 			Nickname: "微信用户",
 			Avatar:   "https://default.avatar",
 		}
-
-		if err := global.Db.Create(&user).Error; err != nil {
+		err = global.Db.Transaction(func(tx *gorm.DB) error {
+			if err := createUser(tx, user); err != nil {
+				return err
+			}
+			_, err := linkIdentity(tx, user.ID, identity)
+			return err
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "用户创建失败"})
 			return
 		}
-	} else if dbResult.Error == nil {
-		// 更新会话信息
-		global.Db.Model(&user).Updates(map[string]interface{}{
-			"session_key": sessionRes.SessionKey,
-		})
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库查询失败"})
+		return
 	}
 
-	// 生成JWT
-	token, err := utils.GenerateJWT(fmt.Sprintf("%d", user.ID))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+	if user.TwoFactorEnabled {
+		startTwoFactorChallenge(c, user, LoginMethodWeChat)
 		return
 	}
+	completeLogin(c, user, LoginMethodWeChat)
+}
 
-	// 返回结果（过滤敏感字段）
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user": gin.H{
-			"id":       user.ID,
-			"nickname": user.Nickname,
-			"avatar":   user.Avatar,
-			"phone":    user.Phone,
-		},
-	})
+// findLegacyWeChatUser 查找只在用户表中保存了 openid 的旧用户，并补全绑定记录
+func findLegacyWeChatUser(identity *utils.ExternalIdentity) (*models.User, error) {
+	var user models.User
+	if err := global.Db.Where("open_id = ?", identity.Subject).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if _, err := linkIdentity(global.Db, user.ID, identity); err != nil {
+		log.Printf("wechat: backfill identity of user %d failed: %v", user.ID, err)
+	}
+	return &user, nil
 }
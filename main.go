@@ -3,10 +3,13 @@ package main
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/controllers"
 	"github.com/appabin/greenbook/global"
 	"github.com/appabin/greenbook/router"
+	"github.com/appabin/greenbook/utils"
 )
 
 func main() {
@@ -29,6 +32,34 @@ func main() {
 	global.InitMinIO()
 	log.Println("=== MinIO 初始化成功 ===")
 
+	// 初始化内容审核
+	moderator, err := utils.NewModerator(
+		config.AppConfig.Moderation.Provider,
+		config.AppConfig.Moderation.Endpoint,
+		time.Duration(config.AppConfig.Moderation.Timeout)*time.Second,
+	)
+	if err != nil {
+		log.Fatalln("内容审核初始化失败:", err)
+	}
+	utils.ContentModerator = moderator
+	log.Printf("=== 内容审核服务: %s ===\n", moderator.Name())
+
+	// 启动回收站定时清理
+	controllers.StartTrashPurger(
+		time.Duration(config.AppConfig.Trash.RetentionDays)*24*time.Hour,
+		time.Duration(config.AppConfig.Trash.PurgeIntervalHours)*time.Hour,
+	)
+
+	// 启动账号注销和导出文件清理任务
+	controllers.StartAccountWorker(time.Duration(config.AppConfig.Account.WorkerIntervalMinutes) * time.Minute)
+
+	// 启动实时推送，订阅其他实例发布的事件
+	utils.StartStreamHub(
+		config.AppConfig.Stream.PubSubChannel,
+		config.AppConfig.Stream.HistoryKey,
+		config.AppConfig.Stream.HistorySize,
+	)
+
 	r := router.SetupRouter()
 	
 	r.Run(":" + config.AppConfig.App.Port)
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"gorm.io/gorm/clause"
+)
+
+// RecordActivity 记录用户在当前时段的活跃情况，同一时段内只写库一次
+func RecordActivity(userID uint) {
+	hour := time.Now().Truncate(time.Hour)
+	key := fmt.Sprintf("activity:%d:%s", userID, hour.Format("2006010215"))
+
+	if !global.RedisDB.SetNX(key, "1", 2*time.Hour).Val() {
+		return
+	}
+
+	go func() {
+		global.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UserActivity{
+			UserID: userID,
+			Hour:   hour,
+		})
+	}()
+}
+
+// TouchSession 更新登录会话的最近活跃时间和IP，每个会话每5分钟最多写库一次
+func TouchSession(sessionID, ip, userAgent string) {
+	if sessionID == "" {
+		return
+	}
+	key := fmt.Sprintf("session:touch:%s", sessionID)
+	if !global.RedisDB.SetNX(key, "1", 5*time.Minute).Val() {
+		return
+	}
+
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+	go func() {
+		global.Db.Model(&models.UserSession{}).
+			Where("id = ? AND revoked_at IS NULL", sessionID).
+			Updates(map[string]interface{}{
+				"ip":           ip,
+				"user_agent":   userAgent,
+				"last_seen_at": time.Now(),
+			})
+	}()
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/appabin/greenbook/config"
 	"github.com/appabin/greenbook/global"
 	"github.com/appabin/greenbook/models"
 	"github.com/appabin/greenbook/utils"
@@ -21,7 +22,7 @@ func AuthMiddleWare() gin.HandlerFunc {
 			return
 		}
 
-		userIDStr, err := utils.ParseJWT(token)
+		claims, err := utils.ParseToken(token, utils.TokenTypeAccess)
 		if err != nil {
 			ctx.JSON(http.StatusUnauthorized, gin.H{
 				"error":  "无效的Token",
@@ -31,33 +32,123 @@ func AuthMiddleWare() gin.HandlerFunc {
 			return
 		}
 
-		// 转换userID为uint
-		userID, err := strconv.ParseUint(userIDStr, 10, 32)
+		if !authenticate(ctx, claims) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// AdminAuthMiddleWare 校验管理员令牌，普通用户的访问令牌不能访问管理后台
+func AdminAuthMiddleWare() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.GetHeader("Authorization")
+		if token == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "缺少Authorization头"})
+			ctx.Abort()
+			return
+		}
+
+		claims, err := utils.ParseToken(token, utils.TokenTypeAdmin)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "无效的管理员Token"})
+			ctx.Abort()
+			return
+		}
+		revoked, err := utils.IsTokenRevoked(claims)
 		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "无效的用户ID格式"})
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "校验Token失败"})
+			ctx.Abort()
+			return
+		}
+		if revoked {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Token已失效，请重新登录"})
+			ctx.Abort()
+			return
+		}
+		// 开启管理员两步验证后，未通过两步验证签发的令牌（包括开启前签发的）一律拒绝
+		if config.AppConfig.TwoFactor.RequireForAdmin && !claims.TwoFactor {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "需要通过两步验证后重新登录", "two_factor_required": true})
 			ctx.Abort()
 			return
 		}
 
-		// 查询用户是否存在
-		var user models.User
-		if err := global.Db.
-			Select("id, username"). // 获取必要字段
-			Where("id = ?", uint(userID)).
-			First(&user).Error; err != nil {
+		ctx.Set("adminName", claims.UserID)
+		ctx.Set("tokenClaims", claims)
+		ctx.Next()
+	}
+}
 
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
-			} else {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "数据库查询失败"})
-			}
-			ctx.Abort()
+// authenticate 校验令牌未被吊销且用户存在，并将用户信息写入上下文，返回 false 时已写入响应
+func authenticate(ctx *gin.Context, claims *utils.TokenClaims) bool {
+	// 检查令牌是否已被吊销（退出登录等）
+	revoked, err := utils.IsTokenRevoked(claims)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "校验Token失败"})
+		ctx.Abort()
+		return false
+	}
+	if revoked {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Token已失效，请重新登录"})
+		ctx.Abort()
+		return false
+	}
+
+	// 转换userID为uint
+	userID, err := strconv.ParseUint(claims.UserID, 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "无效的用户ID格式"})
+		ctx.Abort()
+		return false
+	}
+
+	// 查询用户是否存在
+	var user models.User
+	if err := global.Db.
+		Select("id, username"). // 获取必要字段
+		Where("id = ?", uint(userID)).
+		First(&user).Error; err != nil {
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "数据库查询失败"})
+		}
+		ctx.Abort()
+		return false
+	}
+
+	// 记录活跃用户（用于统计DAU）
+	RecordActivity(user.ID)
+	TouchSession(claims.SessionID, ctx.ClientIP(), ctx.Request.UserAgent())
+
+	// 设置上下文信息
+	ctx.Set("username", user.Username)
+	ctx.Set("userID", user.ID)
+	ctx.Set("tokenClaims", claims)
+	return true
+}
+
+// StreamAuth 实时推送的认证。浏览器的 EventSource 和 WebSocket 无法设置请求头，
+// 可以通过 ticket 参数传递 POST /api/stream/ticket 签发的一次性票据，没有票据时按请求头认证
+func StreamAuth() gin.HandlerFunc {
+	auth := AuthMiddleWare()
+	return func(ctx *gin.Context) {
+		ticket := ctx.Query("ticket")
+		if ticket == "" {
+			auth(ctx)
 			return
 		}
 
-		// 设置上下文信息
-		ctx.Set("username", user.Username)
-		ctx.Set("userID", user.ID)
+		claims, err := utils.ConsumeActionToken(ticket, utils.TokenTypeStream)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "推送票据无效或已使用"})
+			ctx.Abort()
+			return
+		}
+		if !authenticate(ctx, claims) {
+			return
+		}
 		ctx.Next()
 	}
 }
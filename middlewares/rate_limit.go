@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ceilSeconds 向上取整为秒
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// RateLimit 按配置中的策略限流，已登录请求按用户计数，否则按IP计数
+// 策略未配置或 Redis 不可用时放行
+func RateLimit(policyName string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		policy, ok := utils.RateLimitPolicies[policyName]
+		if !utils.RateLimitEnabled || !ok {
+			ctx.Next()
+			return
+		}
+
+		subject := "ip:" + ctx.ClientIP()
+		if policy.KeyBy != utils.RateLimitByIP {
+			if userID := ctx.GetUint("userID"); userID != 0 {
+				subject = fmt.Sprintf("user:%d", userID)
+			}
+		}
+
+		result, err := utils.AllowRate(fmt.Sprintf("ratelimit:%s:%s", policyName, subject), policy)
+		if err != nil {
+			log.Printf("rate limit %s: %v", policyName, err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", policy.Limit, ceilSeconds(policy.Period), result.Limit))
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "请求过于频繁，请稍后再试",
+				"retry_after": retryAfter,
+			})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 公告受众
+const (
+	AudienceAll          = "all"           // 所有用户
+	AudienceNewUsers     = "new_users"     // 新注册用户
+	AudienceTagFollowers = "tag_followers" // 关注指定标签的用户
+)
+
+// Announcement 站内公告
+type Announcement struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Title       string     `gorm:"size:255;not null" json:"title"`                 // 标题
+	Content     string     `gorm:"type:text;not null" json:"content"`              // 内容
+	Type        string     `gorm:"size:20;not null;default:'general'" json:"type"` // 类型(general/maintenance/rules/event)
+	Audience    string     `gorm:"size:20;not null;default:'all'" json:"audience"` // 受众(all/new_users/tag_followers)
+	TagID       *uint      `gorm:"index" json:"tag_id"`                            // 受众为标签关注者时的标签ID
+	NewUserDays int        `gorm:"default:7" json:"new_user_days"`                 // 受众为新用户时，开始展示前多少天内注册的用户
+	StartsAt    time.Time  `gorm:"not null;index" json:"starts_at"`                // 开始展示时间
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at"`                        // 过期时间，为空表示不过期
+}
+
+// AnnouncementRead 公告已读回执
+type AnnouncementRead struct {
+	AnnouncementID uint      `gorm:"primaryKey" json:"announcement_id"` // 公告ID
+	UserID         uint      `gorm:"primaryKey;index" json:"user_id"`   // 用户ID
+	ReadAt         time.Time `json:"read_at"`                           // 阅读时间
+}
+
+func (Announcement) TableName() string {
+	return "announcements"
+}
+
+func (AnnouncementRead) TableName() string {
+	return "announcement_reads"
+}
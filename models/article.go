@@ -27,6 +27,8 @@ type Article struct {
 	LikeCount     int `gorm:"default:0" json:"like_count"`     // 点赞数
 	FavoriteCount int `gorm:"default:0" json:"favorite_count"` // 收藏数
 	CommentCount  int `gorm:"default:0" json:"comment_count"`  // 评论数
+
+	ModerationStatus string `gorm:"size:20;default:'allow';index" json:"moderation_status"` // 审核状态(allow/hold/hide)
 }
 
 // Tag 标签模型
@@ -40,6 +42,13 @@ type Tag struct {
 	Articles []Article `gorm:"many2many:article_tags" json:"articles"`   // 关联的文章
 }
 
+// TagFollow 标签关注模型
+type TagFollow struct {
+	UserID    uint      `gorm:"primaryKey;index" json:"user_id"` // 关注者ID
+	TagID     uint      `gorm:"primaryKey;index" json:"tag_id"`  // 标签ID
+	CreatedAt time.Time `json:"created_at"`                      // 关注时间
+}
+
 // Like 点赞模型
 type Like struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -72,6 +81,10 @@ func (Tag) TableName() string {
 	return "tags"
 }
 
+func (TagFollow) TableName() string {
+	return "tag_follows"
+}
+
 func (Like) TableName() string {
 	return "likes"
 }
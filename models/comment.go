@@ -19,6 +19,8 @@ type Comment struct {
 	Article   Article        `gorm:"foreignKey:ArticleID" json:"article"` // 评论文章
 
 	LikeCount int `gorm:"default:0" json:"like_count"` // 点赞数
+
+	ModerationStatus string `gorm:"size:20;default:'allow';index" json:"moderation_status"` // 审核状态(allow/hold/hide)
 }
 
 // CommentLike 评论点赞模型
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// 数据导出状态
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+	DataExportExpired = "expired"
+)
+
+// DataExport 个人数据导出任务，导出文件保存在 MinIO 中
+type DataExport struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Status      string     `gorm:"size:20;not null;index" json:"status"` // pending/ready/failed/expired
+	ObjectName  string     `gorm:"size:255" json:"-"`                    // MinIO 对象名
+	Size        int64      `json:"size"`                                 // 文件大小（字节）
+	Error       string     `gorm:"size:500" json:"-"`                    // 失败原因，仅记录日志用
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at"` // 下载链接失效时间，过期后删除文件
+}
+
+// TableName 设置表名
+func (DataExport) TableName() string {
+	return "data_exports"
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// 关注请求状态
+const (
+	FollowRequestPending  = "pending"  // 等待对方处理
+	FollowRequestApproved = "approved" // 已同意，已创建关注关系
+	FollowRequestDenied   = "denied"   // 已拒绝
+	FollowRequestCanceled = "canceled" // 申请人已撤回
+)
+
+// FollowRequest 关注私密账号时的请求，对方同意后才创建 UserFollow
+type FollowRequest struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	RequesterID uint       `gorm:"not null;uniqueIndex:idx_follow_request_pair" json:"requester_id"`                              // 申请人
+	TargetID    uint       `gorm:"not null;uniqueIndex:idx_follow_request_pair;index:idx_follow_request_target" json:"target_id"` // 被关注的私密账号
+	Status      string     `gorm:"size:20;not null;default:'pending';index:idx_follow_request_target" json:"status"`
+	RespondedAt *time.Time `json:"responded_at"` // 同意、拒绝或撤回的时间
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Requester User `gorm:"foreignKey:RequesterID" json:"-"`
+	Target    User `gorm:"foreignKey:TargetID" json:"-"`
+}
+
+// TableName 设置表名
+func (FollowRequest) TableName() string {
+	return "follow_requests"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// LoginAttempt 登录失败记录
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Scope     string    `gorm:"size:20;index" json:"scope"`     // user 或 admin
+	Username  string    `gorm:"size:100;index" json:"username"` // 尝试登录的用户名
+	IP        string    `gorm:"size:64;index" json:"ip"`        // 来源IP
+	UserAgent string    `gorm:"size:500" json:"user_agent"`     // User-Agent
+	Reason    string    `gorm:"size:50" json:"reason"`          // 失败原因
+	CreatedAt time.Time `gorm:"index" json:"created_at"`        // 尝试时间
+}
+
+// TableName 设置表名
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
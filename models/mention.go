@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// 提及所在的内容类型
+const (
+	MentionSourceArticle = "article"
+	MentionSourceComment = "comment"
+)
+
+// Mention 文章或评论中的一处 @提及，Offset 和 Length 按字符计算，用于客户端渲染链接
+type Mention struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	SourceType  string    `gorm:"size:20;not null;index:idx_mention_source" json:"source_type"` // article 或 comment
+	SourceID    uint      `gorm:"not null;index:idx_mention_source" json:"source_id"`
+	ArticleID   uint      `gorm:"not null;index" json:"article_id"` // 所在文章，评论中的提及按文章批量查询
+	MentionerID uint      `gorm:"not null;index" json:"mentioner_id"`
+	MentionedID uint      `gorm:"not null;index" json:"mentioned_id"`
+	Offset      int       `gorm:"not null" json:"offset"`
+	Length      int       `gorm:"not null" json:"length"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	Mentioned User `gorm:"foreignKey:MentionedID" json:"-"`
+}
+
+// TableName 设置表名
+func (Mention) TableName() string {
+	return "mentions"
+}
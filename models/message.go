@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// 私信类型
+const (
+	MessageText    = "text"    // 文字
+	MessagePicture = "picture" // 图片，使用已上传的图片
+)
+
+// Conversation 两个用户之间的私信会话，UserLowID 为两人中较小的用户ID
+type Conversation struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserLowID     uint       `gorm:"not null;uniqueIndex:idx_conversation_pair" json:"user_low_id"`
+	UserHighID    uint       `gorm:"not null;uniqueIndex:idx_conversation_pair;index" json:"user_high_id"`
+	LastMessageID uint       `gorm:"default:0" json:"last_message_id"` // 最后一条消息
+	LastMessageAt *time.Time `json:"last_message_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ConversationMember 会话中每个用户各自的状态
+type ConversationMember struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	ConversationID    uint       `gorm:"not null;uniqueIndex:idx_conversation_member" json:"conversation_id"`
+	UserID            uint       `gorm:"not null;uniqueIndex:idx_conversation_member;index:idx_member_user_last" json:"user_id"`
+	PeerID            uint       `gorm:"not null;index" json:"peer_id"`                     // 对方用户
+	UnreadCount       int        `gorm:"not null;default:0" json:"unread_count"`            // 未读消息数
+	LastReadMessageID uint       `gorm:"not null;default:0" json:"last_read_message_id"`    // 已读到的消息，用于对方显示已读回执
+	LastMessageAt     *time.Time `gorm:"index:idx_member_user_last" json:"last_message_at"` // 会话列表排序
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Peer User `gorm:"foreignKey:PeerID" json:"-"`
+}
+
+// Message 私信消息
+type Message struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ConversationID uint      `gorm:"not null;index:idx_message_conversation" json:"conversation_id"`
+	SenderID       uint      `gorm:"not null;index" json:"sender_id"`
+	Type           string    `gorm:"size:20;not null" json:"type"` // text 或 picture
+	Content        string    `gorm:"type:text" json:"content"`
+	PictureID      *uint     `json:"picture_id"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	Picture *Picture `gorm:"foreignKey:PictureID" json:"-"`
+}
+
+// MessageDeletion 用户删除的消息，仅对本人隐藏
+type MessageDeletion struct {
+	MessageID uint      `gorm:"primaryKey" json:"message_id"`
+	UserID    uint      `gorm:"primaryKey;index" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (Conversation) TableName() string {
+	return "conversations"
+}
+
+// TableName 设置表名
+func (ConversationMember) TableName() string {
+	return "conversation_members"
+}
+
+// TableName 设置表名
+func (Message) TableName() string {
+	return "messages"
+}
+
+// TableName 设置表名
+func (MessageDeletion) TableName() string {
+	return "message_deletions"
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 内容审核结论
+const (
+	ModerationAllow = "allow" // 正常展示
+	ModerationHold  = "hold"  // 待人工复核，仅作者可见
+	ModerationHide  = "hide"  // 隐藏，仅作者可见
+)
+
+// 审核对象类型
+const (
+	ModerationTargetArticle = "article"
+	ModerationTargetComment = "comment"
+	ModerationTargetPicture = "picture"
+)
+
+// ModerationRecord 内容审核记录
+type ModerationRecord struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	TargetType string `gorm:"size:20;not null;index:idx_moderation_target" json:"target_type"` // 审核对象类型
+	TargetID   uint   `gorm:"not null;index:idx_moderation_target" json:"target_id"`           // 审核对象ID
+	Provider   string `gorm:"size:50" json:"provider"`                                         // 审核服务名称
+	Labels     string `gorm:"type:text" json:"labels"`                                         // 标签（JSON数组）
+	Scores     string `gorm:"type:text" json:"scores"`                                         // 标签得分（JSON对象）
+	Action     string `gorm:"size:20;not null" json:"action"`                                  // 审核结论
+	Error      string `gorm:"size:500" json:"error"`                                           // 调用审核服务的错误信息
+	ReviewerID string `gorm:"size:50" json:"reviewer_id"`                                      // 人工复核人，为空表示自动审核
+}
+
+func (ModerationRecord) TableName() string {
+	return "moderation_records"
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotificationLike           = "like"            // 点赞了文章
+	NotificationFavorite       = "favorite"        // 收藏了文章
+	NotificationComment        = "comment"         // 评论了文章
+	NotificationCommentLike    = "comment_like"    // 点赞了评论
+	NotificationFollow         = "follow"          // 关注了我
+	NotificationFollowRequest  = "follow_request"  // 请求关注私密账号
+	NotificationFollowAccepted = "follow_accepted" // 同意了我的关注请求
+	NotificationMention        = "mention"         // 提到了我
+)
+
+// NotificationTypes 全部通知类型，用于通知偏好设置
+var NotificationTypes = []string{
+	NotificationLike,
+	NotificationFavorite,
+	NotificationComment,
+	NotificationCommentLike,
+	NotificationFollow,
+	NotificationFollowRequest,
+	NotificationFollowAccepted,
+	NotificationMention,
+}
+
+// 通知对象类型
+const (
+	NotificationTargetArticle = "article"
+	NotificationTargetComment = "comment"
+	NotificationTargetUser    = "user"
+)
+
+// Notification 站内通知，同一对象上的同类未读通知合并为一条
+type Notification struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index:idx_notification_user_updated;index:idx_notification_group" json:"user_id"` // 接收者
+	Type       string     `gorm:"size:30;not null;index:idx_notification_group" json:"type"`                                // 通知类型
+	TargetType string     `gorm:"size:20;not null;index:idx_notification_group" json:"target_type"`                         // 对象类型
+	TargetID   uint       `gorm:"not null;index:idx_notification_group" json:"target_id"`                                   // 对象ID
+	ActorID    uint       `gorm:"not null" json:"actor_id"`                                                                 // 最近一次触发的用户
+	ActorCount int        `gorm:"not null;default:1" json:"actor_count"`                                                    // 合并的用户数
+	Preview    string     `gorm:"size:200" json:"preview"`                                                                  // 评论等内容摘要
+	ReadAt     *time.Time `gorm:"index" json:"read_at"`                                                                     // 已读时间
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"index:idx_notification_user_updated" json:"updated_at"` // 最近一次合并的时间，用于排序
+
+	Actor User `gorm:"foreignKey:ActorID" json:"-"`
+}
+
+// NotificationActor 合并通知中的触发用户，用于去重和展示最近的几位用户
+type NotificationActor struct {
+	NotificationID uint      `gorm:"primaryKey" json:"notification_id"`
+	ActorID        uint      `gorm:"primaryKey;index" json:"actor_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// NotificationPreference 通知偏好，未设置的类型默认接收
+type NotificationPreference struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	Type      string    `gorm:"primaryKey;size:30" json:"type"`
+	Enabled   bool      `gorm:"not null" json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// TableName 设置表名
+func (NotificationActor) TableName() string {
+	return "notification_actors"
+}
+
+// TableName 设置表名
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
@@ -15,6 +15,8 @@ type Picture struct {
 	URL       string         `gorm:"size:500;not null" json:"url"`  // 图片存储URL
 	UserID    uint           `gorm:"not null;index" json:"user_id"` // 上传用户ID
 	User      User           `gorm:"foreignKey:UserID" json:"user"` // 上传用户
+
+	ModerationStatus string `gorm:"size:20;default:'allow';index" json:"moderation_status"` // 审核状态(allow/hold/hide)
 }
 
 // ArticlePicture 文章图片关联表
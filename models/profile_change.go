@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ProfileChange 个人资料字段修改记录
+type ProfileChange struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index:idx_profile_change_user_field" json:"user_id"`
+	Field     string    `gorm:"size:30;not null;index:idx_profile_change_user_field" json:"field"` // 修改的字段
+	OldValue  string    `gorm:"size:500" json:"old_value"`
+	NewValue  string    `gorm:"size:500" json:"new_value"`
+	IP        string    `gorm:"size:64" json:"ip"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 设置表名
+func (ProfileChange) TableName() string {
+	return "profile_changes"
+}
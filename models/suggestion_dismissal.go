@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SuggestionDismissal 用户在推荐关注中忽略的账号，之后不再推荐
+type SuggestionDismissal struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_dismissal_pair" json:"user_id"`
+	DismissedID uint      `gorm:"not null;uniqueIndex:idx_dismissal_pair" json:"dismissed_id"` // 被忽略的账号
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (SuggestionDismissal) TableName() string {
+	return "suggestion_dismissals"
+}
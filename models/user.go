@@ -16,7 +16,7 @@ type User struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Username string `gorm:"size:50;comment:用户名;default:'' " json:"username"`
-	Password string `gorm:"size:100;comment:密码;default:'' " json:"password"`
+	Password string `gorm:"size:100;comment:密码;default:'' " json:"-"` // 密码哈希，不允许序列化
 
 	Nickname string `gorm:"size:50;comment:昵称" json:"nickname"`
 	Avatar   string `gorm:"size:500;comment:头像URL" json:"avatar"`
@@ -24,10 +24,40 @@ type User struct {
 	Phone    string `gorm:"size:20;uniqueIndex;comment:手机号" json:"phone"`
 	Email    string `gorm:"size:100;uniqueIndex;comment:邮箱" json:"email"`
 
+	EmailVerifiedAt *time.Time `gorm:"comment:邮箱验证时间" json:"email_verified_at"`
+
+	// 个人资料
+	Bio        string     `gorm:"size:500;comment:个人简介" json:"bio"`
+	Birthday   *time.Time `gorm:"type:date;comment:生日" json:"birthday"`
+	Location   string     `gorm:"size:100;comment:所在地" json:"location"`
+	CoverImage string     `gorm:"size:500;comment:主页背景图URL" json:"cover_image"`
+
+	// 两步验证
+	TwoFactorEnabled   bool       `gorm:"default:false;comment:是否开启两步验证" json:"two_factor_enabled"`
+	TwoFactorSecret    string     `gorm:"size:64;comment:TOTP密钥" json:"-"`
+	TwoFactorEnabledAt *time.Time `gorm:"comment:开启两步验证时间" json:"two_factor_enabled_at"`
+
+	// 隐私设置
+	PrivateAccount     bool   `gorm:"default:false;comment:私密账号，仅粉丝可见内容" json:"private_account"`
+	HideFavorites      bool   `gorm:"default:false;comment:隐藏收藏列表" json:"hide_favorites"`
+	HideLikes          bool   `gorm:"default:true;comment:隐藏点赞列表" json:"hide_likes"`
+	HideFollowing      bool   `gorm:"default:false;comment:隐藏关注列表" json:"hide_following"`
+	HideFollowers      bool   `gorm:"default:false;comment:隐藏粉丝列表" json:"hide_followers"`
+	CommentPermission  string `gorm:"size:20;default:'everyone';comment:谁可以评论(everyone/followers/mutuals/nobody)" json:"comment_permission"`
+	MessageMutualsOnly bool   `gorm:"default:false;comment:仅接收互相关注的用户的私信" json:"message_mutuals_only"`
+
+	// 账号注销
+	DeletionScheduledAt *time.Time `gorm:"index;comment:计划注销时间" json:"deletion_scheduled_at"`
+	AnonymizedAt        *time.Time `gorm:"index;comment:注销完成时间" json:"-"`
+
 	// 微信小程序相关字段
-	OpenID     *string `gorm:"size:50;uniqueIndex;comment:微信openid;default:NULL" json:"open_id"` // 改为指针类型
-	UnionID    string  `gorm:"size:50;comment:微信unionid;default:NULL" json:"union_id"`
-	SessionKey string  `gorm:"size:100;comment:微信session_key;default:NULL" json:"session_key"`
+	// 以下字段仅供服务端使用，不允许序列化
+	OpenID     *string `gorm:"size:50;uniqueIndex;comment:微信openid;default:NULL" json:"-"` // 改为指针类型
+	UnionID    string  `gorm:"size:50;comment:微信unionid;default:NULL" json:"-"`
+	SessionKey string  `gorm:"size:100;comment:微信session_key;default:NULL" json:"-"`
+
+	// 绑定的第三方登录账号
+	Identities []UserIdentity `gorm:"foreignKey:UserID" json:"-"`
 
 	// 论坛社交相关字段
 	FollowersCount uint `gorm:"default:0;comment:粉丝数" json:"followers_count"`
@@ -38,6 +68,14 @@ type User struct {
 	Followers []User `gorm:"many2many:user_follows;foreignKey:ID;joinForeignKey:FollowedID;joinReferences:FollowerID" json:"-"`
 }
 
+// 谁可以评论我的文章
+const (
+	CommentPermissionEveryone  = "everyone"  // 所有人
+	CommentPermissionFollowers = "followers" // 关注我的人
+	CommentPermissionMutuals   = "mutuals"   // 互相关注的人
+	CommentPermissionNobody    = "nobody"    // 关闭评论
+)
+
 // TableName 设置表名
 func (User) TableName() string {
 	return "users"
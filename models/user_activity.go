@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// UserActivity 用户活跃记录，每个用户每小时最多一条，用于统计活跃用户
+type UserActivity struct {
+	ID     uint      `gorm:"primarykey" json:"id"`
+	UserID uint      `gorm:"not null;uniqueIndex:idx_user_activity_hour" json:"user_id"`    // 用户ID
+	Hour   time.Time `gorm:"not null;uniqueIndex:idx_user_activity_hour;index" json:"hour"` // 活跃时段（整点）
+}
+
+// TableName 设置表名
+func (UserActivity) TableName() string {
+	return "user_activities"
+}
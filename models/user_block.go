@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserBlock 拉黑关系，双方互相不能关注、评论、点赞和提及
+type UserBlock struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BlockerID uint      `gorm:"not null;uniqueIndex:idx_block_pair" json:"blocker_id"`       // 拉黑者
+	BlockedID uint      `gorm:"not null;uniqueIndex:idx_block_pair;index" json:"blocked_id"` // 被拉黑者
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (UserBlock) TableName() string {
+	return "user_blocks"
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UserIdentity 用户绑定的第三方登录账号，同一平台每个用户只能绑定一个
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;uniqueIndex:idx_identity_user_provider" json:"user_id"`
+	Provider    string     `gorm:"size:30;not null;uniqueIndex:idx_identity_subject;uniqueIndex:idx_identity_user_provider" json:"provider"`
+	Subject     string     `gorm:"size:191;not null;uniqueIndex:idx_identity_subject" json:"-"` // 第三方平台的用户唯一标识
+	Email       string     `gorm:"size:100" json:"email"`
+	Name        string     `gorm:"size:100" json:"name"`
+	AvatarURL   string     `gorm:"size:500" json:"avatar_url"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
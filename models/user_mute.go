@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserMute 静音关系，被静音用户的内容不再出现在静音者的信息流中，对方不会收到通知
+type UserMute struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MuterID   uint      `gorm:"not null;uniqueIndex:idx_mute_pair" json:"muter_id"` // 静音者
+	MutedID   uint      `gorm:"not null;uniqueIndex:idx_mute_pair" json:"muted_id"` // 被静音者
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (UserMute) TableName() string {
+	return "user_mutes"
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UserSession 登录会话，对应一条刷新令牌链
+type UserSession struct {
+	ID          string     `gorm:"primaryKey;size:32" json:"id"`  // 会话ID（令牌中的 sid）
+	UserID      uint       `gorm:"not null;index" json:"user_id"` // 用户ID
+	Device      string     `gorm:"size:100" json:"device"`        // 设备名称
+	IP          string     `gorm:"size:64" json:"ip"`             // 最近一次访问的IP
+	UserAgent   string     `gorm:"size:500" json:"user_agent"`    // 最近一次访问的User-Agent
+	LoginMethod string     `gorm:"size:20" json:"login_method"`   // 登录方式(password/wechat/第三方名称)
+	CreatedAt   time.Time  `json:"created_at"`                    // 登录时间
+	LastSeenAt  time.Time  `json:"last_seen_at"`                  // 最近活跃时间
+	ExpiresAt   time.Time  `gorm:"index" json:"expires_at"`       // 刷新令牌过期时间
+	RevokedAt   *time.Time `gorm:"index" json:"revoked_at"`       // 吊销时间
+}
+
+// TableName 设置表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
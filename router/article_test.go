@@ -0,0 +1,70 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+)
+
+// accessToken 为用户签发新会话的访问令牌
+func accessToken(t *testing.T, userID uint) string {
+	t.Helper()
+	pair, err := utils.GenerateTokenPair(strconv.FormatUint(uint64(userID), 10), utils.RandomID())
+	if err != nil {
+		t.Fatalf("token pair: %v", err)
+	}
+	return pair.AccessToken
+}
+
+// TestUpdateArticleRebuildsMentions 只有作者能修改文章，修改正文后重新保存提及位置，并只通知新增的被提及用户
+func TestUpdateArticleRebuildsMentions(t *testing.T) {
+	data := setupTestEnv(t)
+	r := SetupRouter()
+	path := fmt.Sprintf("/api/article/%d", data.article.ID)
+
+	if w := serve(r, http.MethodPut, path, accessToken(t, data.viewer.ID), `{"content":"改写"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("non-author update: status = %d, want 403", w.Code)
+	}
+
+	content := fmt.Sprintf("新的开头 @[%d] 和 @[%d]", data.stranger.ID, data.viewer.ID)
+	if w := serve(r, http.MethodPut, path, accessToken(t, data.author.ID), `{"content":"`+content+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("author update: status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var mentions []models.Mention
+	global.Db.Where("source_type = ? AND source_id = ?", models.MentionSourceArticle, data.article.ID).
+		Order("`offset`").Find(&mentions)
+	want := utils.ParseMentions(content)
+	if len(mentions) != len(want) {
+		t.Fatalf("mentions = %+v, want %d rows", mentions, len(want))
+	}
+	for i, token := range want {
+		if mentions[i].MentionedID != token.UserID || mentions[i].Offset != token.Offset || mentions[i].Length != token.Length {
+			t.Errorf("mention %d = %+v, want %+v", i, mentions[i], token)
+		}
+	}
+
+	// 通知在审核通过后异步发送
+	mentionNotifications := func(userID uint) int64 {
+		var count int64
+		global.Db.Model(&models.Notification{}).
+			Where("user_id = ? AND type = ?", userID, models.NotificationMention).Count(&count)
+		return count
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for mentionNotifications(data.stranger.ID) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := mentionNotifications(data.stranger.ID); got != 1 {
+		t.Errorf("stranger mention notifications = %d, want 1", got)
+	}
+	if got := mentionNotifications(data.viewer.ID); got != 0 {
+		t.Errorf("viewer was already mentioned, notifications = %d, want 0", got)
+	}
+}
@@ -0,0 +1,250 @@
+package router
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	mockOIDCProvider = "mock"
+	mockOIDCClientID = "greenbook-client"
+	mockOIDCKid      = "mock-key"
+)
+
+// mockOIDCServer 本地模拟的 OpenID Provider，提供发现文档、JWKS 和令牌端点
+type mockOIDCServer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	claims jwt.MapClaims // 令牌端点下一次签发的 ID Token 内容
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	m := &mockOIDCServer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": mockOIDCKid,
+				"alg": "RS256",
+				"n":   encode(key.N.Bytes()),
+				"e":   encode(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") == "" || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request"})
+			return
+		}
+		m.mu.Lock()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, m.claims)
+		m.mu.Unlock()
+		token.Header["kid"] = mockOIDCKid
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			t.Errorf("sign id_token: %v", err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	utils.AuthProviders[mockOIDCProvider] = &utils.OIDCProvider{
+		ProviderName: mockOIDCProvider,
+		Issuer:       m.URL,
+		ClientID:     mockOIDCClientID,
+		ClientSecret: "secret",
+		Client:       m.Client(),
+	}
+	t.Cleanup(func() { delete(utils.AuthProviders, mockOIDCProvider) })
+	return m
+}
+
+// oauthLogin 走完授权地址和回调两步，mutate 可以在签发前修改 ID Token 的内容
+func oauthLogin(t *testing.T, r *gin.Engine, m *mockOIDCServer, subject, email string, verified bool, mutate func(jwt.MapClaims)) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oauth/"+mockOIDCProvider+"/authorize", nil))
+	var begin struct {
+		AuthorizeURL string `json:"authorize_url"`
+		State        string `json:"state"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &begin); err != nil || begin.State == "" {
+		t.Fatalf("authorize: %d %s", w.Code, w.Body.String())
+	}
+	authorizeURL, err := url.Parse(begin.AuthorizeURL)
+	if err != nil {
+		t.Fatalf("parse authorize_url: %v", err)
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            m.URL,
+		"aud":            mockOIDCClientID,
+		"sub":            subject,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          authorizeURL.Query().Get("nonce"),
+		"email":          email,
+		"email_verified": verified,
+		"name":           "OIDC " + subject,
+	}
+	if mutate != nil {
+		mutate(claims)
+	}
+	m.mu.Lock()
+	m.claims = claims
+	m.mu.Unlock()
+
+	body := `{"code":"mock-code","state":"` + begin.State + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/oauth/"+mockOIDCProvider+"/callback", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// loginUserID 从登录响应中取出用户 ID
+func loginUserID(t *testing.T, w *httptest.ResponseRecorder) uint {
+	t.Helper()
+	var resp struct {
+		Token string `json:"token"`
+		User  struct {
+			ID uint `json:"id"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Token == "" || resp.User.ID == 0 {
+		t.Fatalf("login response: %d %s", w.Code, w.Body.String())
+	}
+	return resp.User.ID
+}
+
+func TestOIDCRejectsInvalidIDToken(t *testing.T) {
+	setupTestEnv(t)
+	m := newMockOIDCServer(t)
+	r := SetupRouter()
+
+	tests := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+	}{
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other-client" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"nonce mismatch", func(c jwt.MapClaims) { c["nonce"] = "replayed-nonce" }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if w := oauthLogin(t, r, m, "sub-"+tc.name, "", false, tc.mutate); w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want 401: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	var count int64
+	global.Db.Model(&models.UserIdentity{}).Count(&count)
+	if count != 0 {
+		t.Errorf("identities = %d, want 0", count)
+	}
+}
+
+func TestOIDCLoginCreatesAndMergesUsers(t *testing.T) {
+	data := setupTestEnv(t)
+	m := newMockOIDCServer(t)
+	r := SetupRouter()
+
+	// 首次登录创建新用户，再次登录返回同一用户
+	w := oauthLogin(t, r, m, "sub-new", "new@example.com", true, nil)
+	newID := loginUserID(t, w)
+	if newID == data.viewer.ID || newID == data.author.ID {
+		t.Fatalf("first login returned existing user %d", newID)
+	}
+	if again := loginUserID(t, oauthLogin(t, r, m, "sub-new", "new@example.com", true, nil)); again != newID {
+		t.Errorf("second login user = %d, want %d", again, newID)
+	}
+
+	// 双方邮箱均已验证时合并到已有账号
+	now := time.Now()
+	global.Db.Model(&models.User{}).Where("id = ?", data.viewer.ID).Update("email_verified_at", &now)
+	if id := loginUserID(t, oauthLogin(t, r, m, "sub-viewer", data.viewer.Email, true, nil)); id != data.viewer.ID {
+		t.Errorf("verified email login user = %d, want %d", id, data.viewer.ID)
+	}
+
+	// 第三方未验证邮箱或本站未验证邮箱时都不合并
+	if id := loginUserID(t, oauthLogin(t, r, m, "sub-unverified", data.viewer.Email, false, nil)); id == data.viewer.ID {
+		t.Error("unverified provider email merged into existing account")
+	}
+	if id := loginUserID(t, oauthLogin(t, r, m, "sub-author", data.author.Email, true, nil)); id == data.author.ID {
+		t.Error("provider email merged into account with unverified email")
+	}
+}
+
+func TestUnlinkLastLoginMethod(t *testing.T) {
+	setupTestEnv(t)
+	m := newMockOIDCServer(t)
+	r := SetupRouter()
+
+	userID := loginUserID(t, oauthLogin(t, r, m, "sub-only", "", false, nil))
+	unlink := func() int {
+		pair, err := utils.GenerateTokenPair(strconv.FormatUint(uint64(userID), 10), utils.RandomID())
+		if err != nil {
+			t.Fatalf("token pair: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodDelete, "/api/user/identities/"+mockOIDCProvider, nil)
+		req.Header.Set("Authorization", pair.AccessToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 没有密码且只绑定了一个第三方账号时不能解绑
+	if code := unlink(); code != http.StatusBadRequest {
+		t.Errorf("unlink only login method: status = %d, want 400", code)
+	}
+
+	hash, _ := utils.HassPassword(seedPassword)
+	global.Db.Model(&models.User{}).Where("id = ?", userID).Update("password", hash)
+	if code := unlink(); code != http.StatusOK {
+		t.Errorf("unlink with password set: status = %d, want 200", code)
+	}
+}
@@ -11,6 +11,8 @@ func SetupRouter() *gin.Engine {
 
 	// 添加 CORS 中间件
 	r.Use(middlewares.CorsMiddleware())
+	// 全局限流（按IP）
+	r.Use(middlewares.RateLimit("global"))
 
 	// 图片代理服务（从 MinIO 获取图片）
 	r.GET("/static/images/:filename", controllers.ServeImageFromMinIO)
@@ -18,67 +20,165 @@ func SetupRouter() *gin.Engine {
 	r.Static("/static/css", "./static/css")
 	r.Static("/static/js", "./static/js")
 
+	// 个人数据导出下载（签名链接）
+	r.GET("/api/exports/download", controllers.DownloadDataExport)
+
 	// 认证相关路由组
 	authGroup := r.Group("/api/auth")
 	{
 		authGroup.POST("/login", controllers.Login)              // 网页登录
 		authGroup.POST("/register", controllers.Register)        // 网页注册
 		authGroup.POST("/wechat-login", controllers.WeChatLogin) // 微信登录（新增）
+		authGroup.POST("/refresh", controllers.RefreshToken)     // 刷新令牌
+
+		authGroup.POST("/email/verify", controllers.VerifyEmail)                                            // 邮箱验证
+		authGroup.POST("/email/resend", middlewares.RateLimit("mail"), controllers.ResendVerificationEmail) // 重发验证邮件
+		authGroup.POST("/password/forgot", middlewares.RateLimit("mail"), controllers.ForgotPassword)       // 找回密码
+		authGroup.POST("/2fa/verify", controllers.VerifyTwoFactorLogin)                                     // 两步验证登录
+		authGroup.POST("/password/reset", controllers.ResetPassword)                                        // 重置密码
+
+		authGroup.GET("/oauth/:provider/authorize", controllers.OAuthAuthorize) // 第三方登录授权地址
+		authGroup.POST("/oauth/:provider/callback", controllers.OAuthCallback)  // 第三方登录回调
 	}
 
+	// 实时推送（SSE/WebSocket），浏览器通过 ticket 参数传递一次性票据
+	r.GET("/api/stream", middlewares.StreamAuth(), controllers.Stream)
+
 	// 受保护API路由组（需要JWT认证）
 	apiProtected := r.Group("/api")
 	apiProtected.Use(middlewares.AuthMiddleWare()) // 统一应用认证中间件
 	{
+		apiProtected.POST("/auth/logout", controllers.Logout)               // 退出登录
+		apiProtected.POST("/stream/ticket", controllers.CreateStreamTicket) // 实时推送票据
+
 		userGroup := apiProtected.Group("/user")
 		{
 			userGroup.GET("/info", controllers.GetCurrentUserInfo)
+			userGroup.DELETE("/me", controllers.DeleteAccount)                                        // 申请注销
+			userGroup.POST("/me/cancel-deletion", controllers.CancelAccountDeletion)                  // 撤销注销
+			userGroup.POST("/export", middlewares.RateLimit("export"), controllers.RequestDataExport) // 导出个人数据
+			userGroup.GET("/export", controllers.GetDataExports)                                      // 导出任务列表
 			userGroup.GET("/:id", controllers.GetUserProfile)
-			userGroup.POST("/avatar", controllers.UpdateUserAvatar) // 更新用户头像
+			userGroup.GET("/:id/following", controllers.GetUserFollowingList)                              // 指定用户的关注列表
+			userGroup.GET("/:id/followers", controllers.GetUserFollowersList)                              // 指定用户的粉丝列表
+			userGroup.GET("/:id/mutual-followers", controllers.GetMutualFollowers)                         // 共同关注
+			userGroup.POST("/avatar", middlewares.RateLimit("write"), controllers.UpdateUserAvatar)        // 更新用户头像
+			userGroup.PUT("/password", middlewares.RateLimit("write"), controllers.ChangePassword)         // 修改密码
+			userGroup.PATCH("/profile", middlewares.RateLimit("write"), controllers.UpdateProfile)         // 修改个人资料
+			userGroup.GET("/profile/history", controllers.GetProfileHistory)                               // 资料修改记录
+			userGroup.GET("/privacy", controllers.GetPrivacySettings)                                      // 隐私设置
+			userGroup.PATCH("/privacy", middlewares.RateLimit("write"), controllers.UpdatePrivacySettings) // 修改隐私设置
+			userGroup.GET("/blocks", controllers.GetBlockedUsers)                                          // 拉黑列表
+			userGroup.POST("/:id/block", controllers.BlockUser)                                            // 拉黑用户
+			userGroup.DELETE("/:id/block", controllers.UnblockUser)                                        // 取消拉黑
+			userGroup.GET("/mutes", controllers.GetMutedUsers)                                             // 静音列表
+			userGroup.POST("/:id/mute", controllers.MuteUser)                                              // 静音用户
+			userGroup.DELETE("/:id/mute", controllers.UnmuteUser)                                          // 取消静音
+			userGroup.GET("/suggestions", controllers.GetFollowSuggestions)                                // 推荐关注
+			userGroup.POST("/suggestions/:id/dismiss", controllers.DismissSuggestion)                      // 忽略推荐
+
+			userGroup.GET("/2fa", controllers.GetTwoFactorStatus)                      // 两步验证状态
+			userGroup.POST("/2fa/setup", controllers.SetupTwoFactor)                   // 生成密钥
+			userGroup.GET("/2fa/setup/qr", controllers.GetTwoFactorSetupQRCode)        // 密钥二维码
+			userGroup.POST("/2fa/enable", controllers.EnableTwoFactor)                 // 开启两步验证
+			userGroup.POST("/2fa/disable", controllers.DisableTwoFactor)               // 关闭两步验证
+			userGroup.POST("/2fa/recovery-codes", controllers.RegenerateRecoveryCodes) // 重新生成恢复码
+
+			userGroup.GET("/identities", controllers.GetIdentities)                             // 已绑定的第三方账号
+			userGroup.GET("/identities/:provider/authorize", controllers.LinkIdentityAuthorize) // 绑定第三方账号授权地址
+			userGroup.POST("/identities/:provider", controllers.LinkIdentity)                   // 直接绑定（微信小程序）
+			userGroup.DELETE("/identities/:provider", controllers.UnlinkIdentity)               // 解绑第三方账号
+
+			userGroup.POST("/wechat/profile", middlewares.RateLimit("write"), controllers.RefreshWeChatProfile) // 同步微信昵称头像
+			userGroup.POST("/wechat/phone", middlewares.RateLimit("write"), controllers.RefreshWeChatPhone)     // 同步微信手机号
+
+			userGroup.GET("/sessions", controllers.GetSessions)                        // 登录设备列表
+			userGroup.POST("/sessions/revoke-others", controllers.RevokeOtherSessions) // 下线其他设备
+			userGroup.DELETE("/sessions/:id", controllers.RevokeSession)               // 下线指定设备
 		}
 
 		followGroup := apiProtected.Group("/follow")
 		{
-			followGroup.POST("", controllers.FollowAction)              // 关注/取消关注
-			followGroup.GET("/following", controllers.GetFollowingList) // 关注列表
-			followGroup.GET("/followers", controllers.GetFollowersList) // 粉丝列表
+			followGroup.POST("", middlewares.RateLimit("follow"), controllers.FollowAction) // 关注/取消关注
+			followGroup.GET("/following", controllers.GetFollowingList)                     // 关注列表
+			followGroup.GET("/followers", controllers.GetFollowersList)                     // 粉丝列表
+			followGroup.GET("/requests/incoming", controllers.GetIncomingFollowRequests)    // 收到的关注请求
+			followGroup.GET("/requests/outgoing", controllers.GetOutgoingFollowRequests)    // 发出的关注请求
+			followGroup.POST("/requests/:id/approve", controllers.ApproveFollowRequest)     // 同意关注请求
+			followGroup.POST("/requests/:id/deny", controllers.DenyFollowRequest)           // 拒绝关注请求
+			followGroup.DELETE("/requests/:id", controllers.CancelFollowRequest)            // 撤回关注请求
 		}
 
 		articleGroup := apiProtected.Group("/article")
 		{
-			articleGroup.POST("", controllers.CreateArticle)
+			articleGroup.POST("", middlewares.RateLimit("article"), controllers.CreateArticle)
 			articleGroup.GET("", controllers.GetArticleList)
 			articleGroup.GET("/follow", controllers.GetFollowArticleList)
 			articleGroup.GET("/:id", controllers.GetArticle)
+			articleGroup.PUT("/:id", middlewares.RateLimit("write"), controllers.UpdateArticle)
 		}
 
 		commentGroup := apiProtected.Group("/comment")
 		{
-			commentGroup.POST("/:article_id", controllers.CreateComment)
+			commentGroup.POST("/:article_id", middlewares.RateLimit("comment"), controllers.CreateComment)
 		}
 
 		likeGroup := apiProtected.Group("/like")
 		{
-			likeGroup.POST("/:article_id", controllers.ArticleToggleLike) // 点赞/取消点赞
-			likeGroup.POST("/comment/:comment_id", controllers.CommentToggleLike)
+			likeGroup.POST("/:article_id", middlewares.RateLimit("like"), controllers.ArticleToggleLike) // 点赞/取消点赞
+			likeGroup.POST("/comment/:comment_id", middlewares.RateLimit("like"), controllers.CommentToggleLike)
 		}
 
 		favoriteGroup := apiProtected.Group("/favorite")
 		{
-			favoriteGroup.POST("/:article_id", controllers.ArticleToggleFavorite) // 收藏/取消收藏
+			favoriteGroup.POST("/:article_id", middlewares.RateLimit("like"), controllers.ArticleToggleFavorite) // 收藏/取消收藏
 		}
 
 		photoGroup := apiProtected.Group("/picture")
 		{
-			photoGroup.POST("/upload", controllers.UploadPicture)
-			photoGroup.POST("/upload/multipart", controllers.UploadPictureMultipart)
+			photoGroup.POST("/upload", middlewares.RateLimit("upload"), controllers.UploadPicture)
+			photoGroup.POST("/upload/multipart", middlewares.RateLimit("upload"), controllers.UploadPictureMultipart)
+		}
+
+		tagGroup := apiProtected.Group("/tag")
+		{
+			tagGroup.GET("/following", controllers.GetFollowingTags)                                   // 关注的标签
+			tagGroup.POST("/:id/follow", middlewares.RateLimit("follow"), controllers.TagToggleFollow) // 关注/取消关注标签
+		}
+
+		announcementGroup := apiProtected.Group("/announcements")
+		{
+			announcementGroup.GET("", controllers.GetAnnouncements)                   // 未读公告
+			announcementGroup.POST("/read-all", controllers.MarkAllAnnouncementsRead) // 全部已读
+			announcementGroup.POST("/:id/read", controllers.MarkAnnouncementRead)     // 标记已读
+		}
+
+		notificationGroup := apiProtected.Group("/notifications")
+		{
+			notificationGroup.GET("", controllers.GetNotifications)                          // 通知列表
+			notificationGroup.GET("/unread-count", controllers.GetUnreadNotificationCount)   // 未读数
+			notificationGroup.POST("/read-all", controllers.MarkAllNotificationsRead)        // 全部已读
+			notificationGroup.POST("/:id/read", controllers.MarkNotificationRead)            // 标记已读
+			notificationGroup.GET("/preferences", controllers.GetNotificationPreferences)    // 通知偏好
+			notificationGroup.PUT("/preferences", controllers.UpdateNotificationPreferences) // 修改通知偏好
+		}
+
+		messageGroup := apiProtected.Group("/messages")
+		{
+			messageGroup.POST("", middlewares.RateLimit("write"), controllers.SendMessage)       // 发送私信
+			messageGroup.DELETE("/:id", controllers.DeleteMessage)                               // 删除消息（仅自己）
+			messageGroup.GET("/unread-count", controllers.GetUnreadMessageCount)                 // 未读私信数
+			messageGroup.GET("/conversations", controllers.GetConversations)                     // 会话列表
+			messageGroup.GET("/conversations/:id/messages", controllers.GetConversationMessages) // 会话消息
+			messageGroup.POST("/conversations/:id/read", controllers.MarkConversationRead)       // 标记已读
 		}
 
 		searchGroup := apiProtected.Group("/search")
 		{
-			searchGroup.GET("/articles", controllers.SearchArticles) // 搜索文章
-			searchGroup.GET("/users", controllers.SearchUsers)       // 搜索用户
-			searchGroup.GET("/tags", controllers.SearchTags)         // 搜索标签
+			searchGroup.GET("/articles", controllers.SearchArticles)        // 搜索文章
+			searchGroup.GET("/users", controllers.SearchUsers)              // 搜索用户
+			searchGroup.GET("/tags", controllers.SearchTags)                // 搜索标签
+			searchGroup.GET("/mentions", controllers.GetMentionSuggestions) // 提及用户自动补全
 		}
 	}
 
@@ -86,15 +186,34 @@ func SetupRouter() *gin.Engine {
 	admin := r.Group("/admin")
 	{
 		admin.POST("/login", controllers.AdminLogin)
-		
-		// 管理员保护路由（简化版，实际应该有JWT验证）
+
+		// 管理员保护路由，需要登录后签发的管理员令牌
 		adminProtected := admin.Group("/")
+		adminProtected.Use(middlewares.AdminAuthMiddleWare())
 		{
 			adminProtected.GET("/users", controllers.AdminGetUserList)
+			adminProtected.GET("/users/export", controllers.AdminExportUsers)
+			adminProtected.POST("/users/bulk", controllers.AdminBulkUsers)
 			adminProtected.DELETE("/users/:id", controllers.AdminDeleteUser)
 			adminProtected.GET("/articles", controllers.AdminGetArticleList)
+			adminProtected.GET("/articles/export", controllers.AdminExportArticles)
+			adminProtected.POST("/articles/bulk", controllers.AdminBulkArticles)
 			adminProtected.DELETE("/articles/:id", controllers.AdminDeleteArticle)
+			adminProtected.DELETE("/comments/:id", controllers.AdminDeleteComment)
 			adminProtected.GET("/statistics", controllers.GetStatistics)
+			adminProtected.GET("/moderation", controllers.AdminGetModerationQueue)
+			adminProtected.POST("/moderation/:type/:id", controllers.AdminReviewContent)
+
+			// 公告
+			adminProtected.GET("/announcements", controllers.AdminGetAnnouncements)
+			adminProtected.POST("/announcements", controllers.AdminCreateAnnouncement)
+			adminProtected.PUT("/announcements/:id", controllers.AdminUpdateAnnouncement)
+			adminProtected.DELETE("/announcements/:id", controllers.AdminDeleteAnnouncement)
+
+			// 回收站
+			adminProtected.GET("/trash/:type", controllers.AdminGetTrash)
+			adminProtected.POST("/trash/:type/:id/restore", controllers.AdminRestoreTrash)
+			adminProtected.DELETE("/trash/:type/:id", controllers.AdminPurgeTrash)
 		}
 	}
 
@@ -0,0 +1,428 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/appabin/greenbook/config"
+	"github.com/appabin/greenbook/global"
+	"github.com/appabin/greenbook/models"
+	"github.com/appabin/greenbook/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"golang.org/x/net/websocket"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 种子用户的敏感字段，任何接口的响应中都不应出现
+const (
+	seedPassword        = "password-123456"
+	seedSessionKey      = "wx-session-key-do-not-leak"
+	seedTwoFactorSecret = "JBSWY3DPEHPK3PXPDONOTLEAK"
+	seedOpenID          = "wx-openid-do-not-leak"
+)
+
+// leakMarkers 响应中出现即视为泄露的内容：bcrypt 哈希前缀、内部字段名和种子数据中的敏感值
+var leakMarkers = []string{
+	"$2a$", "session_key", "two_factor_secret", "open_id",
+	seedSessionKey, seedTwoFactorSecret, seedOpenID,
+}
+
+// seedData 测试数据的 ID
+type seedData struct {
+	viewer, author, stranger models.User
+	article                  models.Article
+	comment                  models.Comment
+	followRequest            models.FollowRequest
+	conversation             models.Conversation
+	notification             models.Notification
+}
+
+// setupTestEnv 使用临时 SQLite 和 miniredis 替代 MySQL、Redis，MinIO 指向不可达地址
+func setupTestEnv(t *testing.T) *seedData {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	config.AppConfig = &config.Config{}
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000&_journal_mode=WAL"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := config.AutoMigrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	global.Db = db
+
+	mr := miniredis.RunT(t)
+	global.RedisDB = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	global.MinIOConf = &global.MinIOConfig{Endpoint: "127.0.0.1:1", BucketName: "test"}
+	global.MinIOClient, err = minio.New(global.MinIOConf.Endpoint, &minio.Options{
+		Creds: credentials.NewStaticV4("test", "test-secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("minio client: %v", err)
+	}
+
+	if err := utils.InitJWT(utils.JWTSettings{
+		Issuer:    "greenbook-test",
+		ActiveKid: "test",
+		Keys:      []utils.JWTKeyConfig{{Kid: "test", Alg: "HS256", Secret: strings.Repeat("k", 32)}},
+	}); err != nil {
+		t.Fatalf("init jwt: %v", err)
+	}
+
+	return seed(t)
+}
+
+func seedUser(t *testing.T, name string) models.User {
+	t.Helper()
+	hash, err := utils.HassPassword(seedPassword)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	openID := seedOpenID + "-" + name
+	user := models.User{
+		Username:          name,
+		Password:          hash,
+		Nickname:          name,
+		Email:             name + "@example.com",
+		Phone:             "1380000" + strconv.Itoa(len(name)) + name[:1],
+		TwoFactorSecret:   seedTwoFactorSecret,
+		OpenID:            &openID,
+		UnionID:           "union-" + name,
+		SessionKey:        seedSessionKey,
+		CommentPermission: models.CommentPermissionEveryone,
+	}
+	if err := global.Db.Create(&user).Error; err != nil {
+		t.Fatalf("create user %s: %v", name, err)
+	}
+	return user
+}
+
+func seed(t *testing.T) *seedData {
+	t.Helper()
+	data := &seedData{
+		viewer:   seedUser(t, "viewer"),
+		author:   seedUser(t, "author"),
+		stranger: seedUser(t, "stranger"),
+	}
+	now := time.Now()
+
+	data.article = models.Article{
+		Title:    "测试文章",
+		Content:  "正文 @[" + strconv.Itoa(int(data.viewer.ID)) + "]",
+		AuthorID: data.author.ID,
+		Tags:     []models.Tag{{Name: "测试"}},
+	}
+	data.comment = models.Comment{Content: "评论", UserID: data.author.ID}
+	data.followRequest = models.FollowRequest{RequesterID: data.stranger.ID, TargetID: data.viewer.ID, Status: models.FollowRequestPending}
+	data.conversation = models.Conversation{UserLowID: data.viewer.ID, UserHighID: data.author.ID, LastMessageAt: &now}
+	records := []interface{}{
+		&models.UserFollow{FollowerID: data.viewer.ID, FollowedID: data.author.ID},
+		&models.UserFollow{FollowerID: data.author.ID, FollowedID: data.viewer.ID},
+		&data.article,
+		&data.followRequest,
+		&data.conversation,
+	}
+	for _, record := range records {
+		if err := global.Db.Create(record).Error; err != nil {
+			t.Fatalf("seed %T: %v", record, err)
+		}
+	}
+
+	data.comment.ArticleID = data.article.ID
+	data.notification = models.Notification{
+		UserID:     data.viewer.ID,
+		Type:       models.NotificationComment,
+		TargetType: models.NotificationTargetArticle,
+		TargetID:   data.article.ID,
+		ActorID:    data.author.ID,
+		Preview:    "评论",
+	}
+	records = []interface{}{
+		&data.comment,
+		&data.notification,
+		&models.ConversationMember{ConversationID: data.conversation.ID, UserID: data.viewer.ID, PeerID: data.author.ID, UnreadCount: 1, LastMessageAt: &now},
+		&models.ConversationMember{ConversationID: data.conversation.ID, UserID: data.author.ID, PeerID: data.viewer.ID, LastMessageAt: &now},
+		&models.Message{ConversationID: data.conversation.ID, SenderID: data.author.ID, Type: models.MessageText, Content: "你好"},
+		&models.Mention{SourceType: models.MentionSourceArticle, SourceID: data.article.ID, ArticleID: data.article.ID,
+			MentionerID: data.author.ID, MentionedID: data.viewer.ID, Offset: 3, Length: 4},
+	}
+	for _, record := range records {
+		if err := global.Db.Create(record).Error; err != nil {
+			t.Fatalf("seed %T: %v", record, err)
+		}
+	}
+	return data
+}
+
+// routePath 用种子数据替换路由中的参数
+func routePath(route gin.RouteInfo, data *seedData) string {
+	id := func(v uint) string { return strconv.FormatUint(uint64(v), 10) }
+	params := map[string]string{
+		":article_id": id(data.article.ID),
+		":comment_id": id(data.comment.ID),
+		":provider":   "wechat",
+		":filename":   "missing.jpg",
+	}
+	switch {
+	case strings.HasPrefix(route.Path, "/api/user/"), strings.HasPrefix(route.Path, "/admin/users/"):
+		params[":id"] = id(data.author.ID)
+	case strings.HasPrefix(route.Path, "/api/article/"), strings.HasPrefix(route.Path, "/admin/articles/"):
+		params[":id"] = id(data.article.ID)
+	case strings.HasPrefix(route.Path, "/api/follow/requests/"):
+		params[":id"] = id(data.followRequest.ID)
+	case strings.HasPrefix(route.Path, "/api/messages/conversations/"):
+		params[":id"] = id(data.conversation.ID)
+	case strings.HasPrefix(route.Path, "/api/notifications/"):
+		params[":id"] = id(data.notification.ID)
+	case strings.HasPrefix(route.Path, "/admin/trash/"), strings.HasPrefix(route.Path, "/admin/moderation/"):
+		params[":type"] = "article"
+		params[":id"] = id(data.article.ID)
+	default:
+		params[":id"] = id(data.comment.ID)
+	}
+
+	segments := strings.Split(route.Path, "/")
+	for i, segment := range segments {
+		if value, ok := params[segment]; ok {
+			segments[i] = value
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// serve 发送 JSON 请求
+func serve(r *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestRoutesDoNotLeakSecrets 以已登录用户和管理员身份调用所有路由，检查响应中没有密码哈希、微信 session_key 等字段
+func TestRoutesDoNotLeakSecrets(t *testing.T) {
+	data := setupTestEnv(t)
+	r := SetupRouter()
+
+	adminToken, err := utils.GenerateAdminToken("admin", false)
+	if err != nil {
+		t.Fatalf("admin token: %v", err)
+	}
+
+	// 返回用户信息的写接口使用合法的请求体，并且必须成功，其余写接口只发送空对象
+	loginBody := `{"username":"viewer","password":"` + seedPassword + `"}`
+	validBodies := map[string]func() string{
+		"POST /api/auth/login": func() string { return loginBody },
+		"POST /api/auth/register": func() string {
+			return `{"username":"newcomer","password":"` + seedPassword + `","nickname":"newcomer","email":"newcomer@example.com","phone":"13900000000"}`
+		},
+		"POST /api/auth/refresh": func() string {
+			w := serve(r, http.MethodPost, "/api/auth/login", "", loginBody)
+			var resp struct {
+				RefreshToken string `json:"refresh_token"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.RefreshToken == "" {
+				t.Fatalf("login for refresh: %d %s", w.Code, w.Body.String())
+			}
+			return `{"refresh_token":"` + resp.RefreshToken + `"}`
+		},
+		"PATCH /api/user/profile": func() string { return `{"nickname":"新昵称","bio":"简介"}` },
+		"POST /api/follow": func() string {
+			return `{"user_id":` + strconv.FormatUint(uint64(data.stranger.ID), 10) + `}`
+		},
+		"POST /api/follow/requests/:id/approve": func() string { return "" },
+		"POST /api/article":                     func() string { return `{"title":"新文章","content":"正文","tags":["测试"]}` },
+		"POST /api/comment/:article_id":         func() string { return `{"content":"新评论"}` },
+	}
+
+	// 先调用只读接口，再调用合法请求体的写接口，删除类接口放在最后，避免提前删掉种子数据
+	routes := r.Routes()
+	sort.SliceStable(routes, func(i, j int) bool {
+		rank := func(route gin.RouteInfo) int {
+			_, valid := validBodies[route.Method+" "+route.Path]
+			switch {
+			case strings.HasPrefix(route.Path, "/admin"):
+				return 3
+			case route.Method == http.MethodGet:
+				return 0
+			case valid:
+				return 1
+			default:
+				return 2
+			}
+		}
+		return rank(routes[i]) < rank(routes[j])
+	})
+
+	statuses := map[string]int{}
+	for _, route := range routes {
+		// 静态文件和长连接推送不返回数据库中的内容
+		if strings.Contains(route.Path, "*") || route.Path == "/api/stream" {
+			continue
+		}
+
+		key := route.Method + " " + route.Path
+		path := routePath(route, data)
+		body := ""
+		if validBody, ok := validBodies[key]; ok {
+			body = validBody()
+		} else if route.Method != http.MethodGet && route.Method != http.MethodDelete {
+			body = "{}"
+		}
+		token := adminToken
+		if !strings.HasPrefix(route.Path, "/admin") {
+			// 每次请求使用新会话，退出登录等接口不影响后续请求
+			pair, err := utils.GenerateTokenPair(strconv.FormatUint(uint64(data.viewer.ID), 10), utils.RandomID())
+			if err != nil {
+				t.Fatalf("token pair: %v", err)
+			}
+			token = pair.AccessToken
+		}
+
+		w := serve(r, route.Method, path, token, body)
+		statuses[key] = w.Code
+		if _, ok := validBodies[key]; ok && (w.Code < 200 || w.Code >= 300) {
+			t.Errorf("%s %s = %d, want 2xx: %s", route.Method, path, w.Code, w.Body.String())
+		}
+
+		responseBody := w.Body.String()
+		for _, marker := range leakMarkers {
+			if strings.Contains(responseBody, marker) {
+				t.Errorf("%s %s (%d) leaks %q: %s", route.Method, path, w.Code, marker, responseBody)
+			}
+		}
+	}
+
+	for key := range validBodies {
+		if _, ok := statuses[key]; !ok {
+			t.Errorf("%s is not registered", key)
+		}
+	}
+
+	// 确认种子数据生效，主要的读取接口确实返回了用户信息
+	for _, key := range []string{
+		"GET /api/user/info",
+		"GET /api/user/:id",
+		"GET /api/article",
+		"GET /api/article/:id",
+		"GET /api/follow/followers",
+		"GET /api/messages/conversations",
+		"GET /api/notifications",
+		"GET /admin/users",
+	} {
+		if code, ok := statuses[key]; !ok || code != http.StatusOK {
+			t.Errorf("%s = %d, want 200", key, code)
+		}
+	}
+}
+
+// TestAdminRequiresTwoFactorClaim 开启管理员两步验证后，未通过两步验证签发的令牌不能访问管理接口
+func TestAdminRequiresTwoFactorClaim(t *testing.T) {
+	setupTestEnv(t)
+	config.AppConfig.TwoFactor.RequireForAdmin = true
+	r := SetupRouter()
+
+	for _, tc := range []struct {
+		twoFactor bool
+		want      int
+	}{
+		{twoFactor: false, want: http.StatusUnauthorized},
+		{twoFactor: true, want: http.StatusOK},
+	} {
+		token, err := utils.GenerateAdminToken("admin", tc.twoFactor)
+		if err != nil {
+			t.Fatalf("admin token: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("twoFactor=%v: status = %d, want %d", tc.twoFactor, w.Code, tc.want)
+		}
+	}
+}
+
+// TestStreamTicket 推送票据只能使用一次，WebSocket 握手拒绝其他网站的 Origin
+func TestStreamTicket(t *testing.T) {
+	data := setupTestEnv(t)
+	server := httptest.NewServer(SetupRouter())
+	defer server.Close()
+
+	newTicket := func() string {
+		pair, err := utils.GenerateTokenPair(strconv.FormatUint(uint64(data.viewer.ID), 10), utils.RandomID())
+		if err != nil {
+			t.Fatalf("token pair: %v", err)
+		}
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/stream/ticket", nil)
+		req.Header.Set("Authorization", pair.AccessToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request ticket: %v", err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Ticket string `json:"ticket"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Ticket == "" {
+			t.Fatalf("ticket response: status %d, %v", resp.StatusCode, err)
+		}
+		return body.Ticket
+	}
+
+	// SSE：第一次使用成功，再次使用被拒绝
+	ticket := newTicket()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/stream?ticket="+url.QueryEscape(ticket), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("first use: status = %d, want 200", resp.StatusCode)
+	}
+	resp, err = http.Get(server.URL + "/api/stream?ticket=" + url.QueryEscape(ticket))
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("reuse: status = %d, want 401", resp.StatusCode)
+	}
+
+	// WebSocket：同源可以连接，其他来源被拒绝
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/stream?ticket="
+	ws, err := websocket.Dial(wsURL+url.QueryEscape(newTicket()), "", server.URL)
+	if err != nil {
+		t.Fatalf("same origin dial: %v", err)
+	}
+	ws.Close()
+	if ws, err := websocket.Dial(wsURL+url.QueryEscape(newTicket()), "", "https://evil.example"); err == nil {
+		ws.Close()
+		t.Error("cross-origin dial succeeded, want rejected")
+	}
+}
@@ -1,7 +1,12 @@
 package utils
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -9,48 +14,308 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// 令牌类型
+const (
+	TokenTypeAccess        = "access"
+	TokenTypeRefresh       = "refresh"
+	TokenTypeEmailVerify   = "email_verify"   // 邮箱验证链接
+	TokenTypePasswordReset = "password_reset" // 找回密码链接
+	TokenTypeTwoFactor     = "2fa_challenge"  // 登录第二步的挑战令牌
+	TokenTypeDataExport    = "data_export"    // 个人数据下载链接
+	TokenTypeAdmin         = "admin"          // 管理员后台令牌
+	TokenTypeStream        = "stream"         // 建立实时推送连接的一次性票据
+)
+
 func HassPassword(pwd string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(pwd), 12)
 	return string(hash), err
 }
 
-func GenerateJWT(userID string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID":	userID,
-		"exp":      time.Now().Add(time.Hour * 72).Unix(),
-	})
-	SignedToken, err := token.SignedString([]byte("secret"))
-	return "Bearer " + SignedToken, err
-}
-
 func CheckPassword(password string, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// JWTKeyConfig 单个签名密钥的配置
+type JWTKeyConfig struct {
+	Kid            string // 密钥ID，写入令牌头部的 kid
+	Alg            string // HS256、RS256 或 EdDSA
+	Secret         string // HS256 密钥
+	PrivateKeyFile string // RS256/EdDSA 私钥（PEM）文件，仅用于验证的旧密钥可以不配置
+	PublicKeyFile  string // RS256/EdDSA 公钥（PEM）文件，为空时由私钥推导
+}
+
+// JWTSettings 令牌签发配置
+type JWTSettings struct {
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	AdminTokenTTL   time.Duration
+	ActiveKid       string // 当前用于签发的密钥，其余密钥仅用于验证已签发的令牌
+	Keys            []JWTKeyConfig
+}
+
+// jwtKey 已加载的签名密钥
+type jwtKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+var (
+	jwtKeys   = map[string]*jwtKey{}
+	activeKey *jwtKey
+	jwtIssuer = "greenbook"
+
+	// AccessTokenTTL 访问令牌有效期
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL 刷新令牌有效期
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// AdminTokenTTL 管理员令牌有效期，过期后需重新登录
+	AdminTokenTTL = 2 * time.Hour
+)
+
+// TokenClaims 令牌声明
+type TokenClaims struct {
+	UserID    string `json:"userID"`
+	Type      string `json:"typ"`
+	SessionID string `json:"sid"`           // 登录会话ID，同一会话内轮换的令牌共享
+	Binding   string `json:"bnd,omitempty"` // 一次性令牌绑定的数据（邮箱、密码指纹等）
+	TwoFactor bool   `json:"tfa,omitempty"` // 管理员令牌签发前是否已通过两步验证
+	jwt.StandardClaims
+}
+
+// TokenPair 一次签发的访问令牌和刷新令牌
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // 访问令牌有效期（秒）
+	SessionID    string
+	RefreshID    string // 刷新令牌的 jti
+}
+
+// loadJWTKey 根据配置加载密钥
+func loadJWTKey(cfg JWTKeyConfig) (*jwtKey, error) {
+	if cfg.Kid == "" {
+		return nil, errors.New("jwt key kid is required")
+	}
+
+	key := &jwtKey{kid: cfg.Kid}
+	switch cfg.Alg {
+	case "HS256":
+		if len(cfg.Secret) < 32 {
+			return nil, fmt.Errorf("jwt key %s: HS256 secret must be at least 32 bytes", cfg.Kid)
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(cfg.Secret)
+		key.verifyKey = []byte(cfg.Secret)
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+		if cfg.PrivateKeyFile != "" {
+			data, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("jwt key %s: %v", cfg.Kid, err)
+			}
+			key.signKey = privateKey
+			key.verifyKey = &privateKey.PublicKey
+		}
+		if cfg.PublicKeyFile != "" {
+			data, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			publicKey, err := jwt.ParseRSAPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("jwt key %s: %v", cfg.Kid, err)
+			}
+			key.verifyKey = publicKey
+		}
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+		if cfg.PrivateKeyFile != "" {
+			data, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			privateKey, err := jwt.ParseEdPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("jwt key %s: %v", cfg.Kid, err)
+			}
+			edKey, ok := privateKey.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("jwt key %s: not an ed25519 private key", cfg.Kid)
+			}
+			key.signKey = edKey
+			key.verifyKey = edKey.Public()
+		}
+		if cfg.PublicKeyFile != "" {
+			data, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			publicKey, err := jwt.ParseEdPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("jwt key %s: %v", cfg.Kid, err)
+			}
+			key.verifyKey = publicKey
+		}
+	default:
+		return nil, fmt.Errorf("jwt key %s: unsupported alg %q", cfg.Kid, cfg.Alg)
+	}
+
+	if key.verifyKey == nil {
+		return nil, fmt.Errorf("jwt key %s: no key material configured", cfg.Kid)
+	}
+	return key, nil
+}
+
+// InitJWT 加载签名密钥和令牌有效期
+func InitJWT(settings JWTSettings) error {
+	keys := make(map[string]*jwtKey, len(settings.Keys))
+	for _, cfg := range settings.Keys {
+		key, err := loadJWTKey(cfg)
+		if err != nil {
+			return err
+		}
+		if _, exists := keys[key.kid]; exists {
+			return fmt.Errorf("duplicate jwt key kid: %s", key.kid)
+		}
+		keys[key.kid] = key
+	}
+
+	active, ok := keys[settings.ActiveKid]
+	if !ok {
+		return fmt.Errorf("active jwt key %q is not configured", settings.ActiveKid)
+	}
+	if active.signKey == nil {
+		return fmt.Errorf("active jwt key %q has no private key", settings.ActiveKid)
+	}
+
+	jwtKeys = keys
+	activeKey = active
+	if settings.Issuer != "" {
+		jwtIssuer = settings.Issuer
+	}
+	if settings.AccessTokenTTL > 0 {
+		AccessTokenTTL = settings.AccessTokenTTL
+	}
+	if settings.RefreshTokenTTL > 0 {
+		RefreshTokenTTL = settings.RefreshTokenTTL
+	}
+	if settings.AdminTokenTTL > 0 {
+		AdminTokenTTL = settings.AdminTokenTTL
+	}
+	return nil
+}
+
+// RandomID 生成随机ID，用于 jti 和会话ID
+func RandomID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// signToken 使用当前密钥签发令牌，补全 jti、签发者和有效期
+func signToken(claims TokenClaims, ttl time.Duration) (string, string, error) {
+	if activeKey == nil {
+		return "", "", errors.New("jwt keys are not initialized")
+	}
+
+	now := time.Now()
+	jti := RandomID()
+	claims.StandardClaims = jwt.StandardClaims{
+		Id:        jti,
+		Issuer:    jwtIssuer,
+		Subject:   claims.UserID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+	token := jwt.NewWithClaims(activeKey.method, claims)
+	token.Header["kid"] = activeKey.kid
+
+	signed, err := token.SignedString(activeKey.signKey)
+	return signed, jti, err
+}
+
+// GenerateTokenPair 签发访问令牌和刷新令牌，sessionID 为空时开启新会话
+func GenerateTokenPair(userID, sessionID string) (*TokenPair, error) {
+	if sessionID == "" {
+		sessionID = RandomID()
+	}
+
+	accessToken, _, err := signToken(TokenClaims{UserID: userID, Type: TokenTypeAccess, SessionID: sessionID}, AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshID, err := signToken(TokenClaims{UserID: userID, Type: TokenTypeRefresh, SessionID: sessionID}, RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  "Bearer " + accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(AccessTokenTTL.Seconds()),
+		SessionID:    sessionID,
+		RefreshID:    refreshID,
+	}, nil
+}
+
+// GenerateAdminToken 签发管理员令牌，UserID 声明为管理员用户名，twoFactor 表示登录时已校验两步验证码
+func GenerateAdminToken(username string, twoFactor bool) (string, error) {
+	token, _, err := signToken(TokenClaims{UserID: username, Type: TokenTypeAdmin, SessionID: RandomID(), TwoFactor: twoFactor}, AdminTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+// ParseToken 验证令牌签名、有效期和类型
+func ParseToken(tokenString, tokenType string) (*TokenClaims, error) {
+	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+	if tokenString == "" {
+		return nil, errors.New("empty token string")
+	}
+
+	var claims TokenClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := jwtKeys[kid]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if claims.Type != tokenType {
+		return nil, errors.New("unexpected token type")
+	}
+	if claims.UserID == "" {
+		return nil, errors.New("invalid userID claim")
+	}
+	return &claims, nil
+}
+
+// ParseJWT 解析访问令牌并返回用户ID
 func ParseJWT(tokenString string) (string, error) {
-    tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-    if tokenString == "" {
-        return "", errors.New("empty token string")
-    }
-
-    token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-            return nil, errors.New("unexpected signing method")
-        }
-        return []byte("secret"), nil // 建议替换为环境变量配置的密钥
-    })
-
-    if err != nil {
-        return "", err // 返回具体解析错误
-    }
-
-    if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-        userID, ok := claims["userID"].(string) // 提取userID
-        if !ok {
-            return "", errors.New("invalid userID claim")
-        }
-        return userID, nil
-    }
-
-    return "", errors.New("invalid token")
+	claims, err := ParseToken(tokenString, TokenTypeAccess)
+	if err != nil {
+		return "", err
+	}
+	return claims.UserID, nil
 }
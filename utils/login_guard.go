@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/go-redis/redis"
+)
+
+// LoginGuardSettings 登录防爆破配置
+type LoginGuardSettings struct {
+	Window             time.Duration // 失败次数统计的滑动窗口
+	MaxFailuresPerUser int           // 窗口内同一用户名允许的失败次数
+	MaxFailuresPerIP   int           // 窗口内同一IP允许的失败次数
+	BaseLockout        time.Duration // 首次锁定时长，之后每次翻倍
+	MaxLockout         time.Duration // 锁定时长上限
+	CaptchaAfter       int           // 窗口内失败达到该次数后要求验证码，0 表示不启用
+	RegisterWindow     time.Duration // 注册频率统计窗口
+	RegisterMaxPerIP   int           // 窗口内同一IP允许的注册次数，0 表示不限制
+}
+
+// LoginGuard 当前生效的登录防爆破配置
+var LoginGuard = LoginGuardSettings{
+	Window:             15 * time.Minute,
+	MaxFailuresPerUser: 5,
+	MaxFailuresPerIP:   20,
+	BaseLockout:        time.Minute,
+	MaxLockout:         time.Hour,
+	CaptchaAfter:       3,
+	RegisterWindow:     time.Hour,
+	RegisterMaxPerIP:   5,
+}
+
+// lockoutLevelTTL 锁定次数的记忆时长，超过后锁定时长重新从基础值开始
+const lockoutLevelTTL = 24 * time.Hour
+
+func failureKey(scope, kind, id string) string {
+	return fmt.Sprintf("login:fail:%s:%s:%s", scope, kind, id)
+}
+
+func lockKey(scope, kind, id string) string {
+	return fmt.Sprintf("login:lock:%s:%s:%s", scope, kind, id)
+}
+
+func lockLevelKey(scope, kind, id string) string {
+	return fmt.Sprintf("login:lock:level:%s:%s:%s", scope, kind, id)
+}
+
+// SlidingWindowHit 在滑动窗口中记录一次事件，返回窗口内的事件总数
+func SlidingWindowHit(key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	pipe := global.RedisDB.TxPipeline()
+	pipe.ZRemRangeByScore(key, "-inf", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+	pipe.ZAdd(key, redis.Z{Score: float64(now.UnixNano()), Member: RandomID()})
+	count := pipe.ZCard(key)
+	pipe.Expire(key, window)
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// SlidingWindowCount 返回滑动窗口内的事件总数
+func SlidingWindowCount(key string, window time.Duration) (int64, error) {
+	min := strconv.FormatInt(time.Now().Add(-window).UnixNano(), 10)
+	return global.RedisDB.ZCount(key, min, "+inf").Result()
+}
+
+// LoginLockRemaining 返回 IP 或用户名仍被锁定的剩余时间，未锁定时返回 0
+func LoginLockRemaining(scope, ip, username string) (time.Duration, error) {
+	var remaining time.Duration
+	for _, key := range []string{lockKey(scope, "ip", ip), lockKey(scope, "user", username)} {
+		ttl, err := global.RedisDB.TTL(key).Result()
+		if err != nil {
+			return 0, err
+		}
+		if ttl > remaining {
+			remaining = ttl
+		}
+	}
+	return remaining, nil
+}
+
+// LoginFailureCount 返回窗口内 IP 与用户名失败次数的较大值
+func LoginFailureCount(scope, ip, username string) (int64, error) {
+	ipCount, err := SlidingWindowCount(failureKey(scope, "ip", ip), LoginGuard.Window)
+	if err != nil {
+		return 0, err
+	}
+	userCount, err := SlidingWindowCount(failureKey(scope, "user", username), LoginGuard.Window)
+	if err != nil {
+		return 0, err
+	}
+	if ipCount > userCount {
+		return ipCount, nil
+	}
+	return userCount, nil
+}
+
+// lockout 锁定 IP 或用户名，锁定时长随连续锁定次数指数增长
+func lockout(scope, kind, id string) (time.Duration, error) {
+	level, err := global.RedisDB.Incr(lockLevelKey(scope, kind, id)).Result()
+	if err != nil {
+		return 0, err
+	}
+	global.RedisDB.Expire(lockLevelKey(scope, kind, id), lockoutLevelTTL)
+
+	duration := LoginGuard.BaseLockout
+	for i := int64(1); i < level && duration < LoginGuard.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > LoginGuard.MaxLockout {
+		duration = LoginGuard.MaxLockout
+	}
+
+	pipe := global.RedisDB.TxPipeline()
+	pipe.Set(lockKey(scope, kind, id), "1", duration)
+	// 锁定后清空窗口，解锁时重新计数
+	pipe.Del(failureKey(scope, kind, id))
+	_, err = pipe.Exec()
+	return duration, err
+}
+
+// RecordLoginFailure 记录一次登录失败，达到阈值时锁定并返回锁定时长
+func RecordLoginFailure(scope, ip, username string) (failures int64, locked time.Duration, err error) {
+	limits := []struct {
+		kind  string
+		id    string
+		limit int
+	}{
+		{"ip", ip, LoginGuard.MaxFailuresPerIP},
+		{"user", username, LoginGuard.MaxFailuresPerUser},
+	}
+
+	for _, item := range limits {
+		count, err := SlidingWindowHit(failureKey(scope, item.kind, item.id), LoginGuard.Window)
+		if err != nil {
+			return 0, 0, err
+		}
+		if count > failures {
+			failures = count
+		}
+		if item.limit > 0 && count >= int64(item.limit) {
+			duration, err := lockout(scope, item.kind, item.id)
+			if err != nil {
+				return 0, 0, err
+			}
+			if duration > locked {
+				locked = duration
+			}
+		}
+	}
+	return failures, locked, nil
+}
+
+// ResetLoginFailures 登录成功后清除该用户名的失败记录和锁定等级
+func ResetLoginFailures(scope, username string) error {
+	return global.RedisDB.Del(
+		failureKey(scope, "user", username),
+		lockLevelKey(scope, "user", username),
+	).Err()
+}
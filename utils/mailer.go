@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mail 待发送的邮件
+type Mail struct {
+	To      string
+	Subject string
+	Text    string // 纯文本正文
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Name() string
+	Send(ctx context.Context, mail Mail) error
+}
+
+// buildMessage 生成 RFC 5322 格式的邮件内容
+func buildMessage(from string, mail Mail) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + mail.To + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", mail.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(mail.Text, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// validateRecipient 防止邮件头注入
+func validateRecipient(mail Mail) error {
+	if strings.ContainsAny(mail.To, "\r\n") || strings.ContainsAny(mail.Subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+	return nil
+}
+
+// SMTPMailer 通过 SMTP 服务器发送邮件，服务器支持时自动使用 STARTTLS
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Name() string { return "smtp" }
+
+func (m *SMTPMailer) Send(ctx context.Context, mail Mail) error {
+	if err := validateRecipient(mail); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	addr := net.JoinHostPort(m.Host, fmt.Sprintf("%d", m.Port))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, m.From, []string{mail.To}, buildMessage(m.From, mail))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FileMailer 将邮件写入本地目录（.eml），用于本地开发和测试
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Name() string { return "file" }
+
+func (m *FileMailer) Send(ctx context.Context, mail Mail) error {
+	if err := validateRecipient(mail); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), RandomID()[:8])
+	return os.WriteFile(filepath.Join(m.Dir, name), buildMessage(m.From, mail), 0o644)
+}
+
+// LogMailer 仅将邮件内容打印到日志
+type LogMailer struct{}
+
+func (LogMailer) Name() string { return "log" }
+
+func (LogMailer) Send(ctx context.Context, mail Mail) error {
+	log.Printf("mail to=%s subject=%q\n%s", mail.To, mail.Subject, mail.Text)
+	return nil
+}
+
+// MailSender 当前使用的邮件发送实现，默认输出到日志
+var MailSender Mailer = LogMailer{}
+
+// MailerConfig 邮件发送配置
+type MailerConfig struct {
+	Provider  string // smtp、file 或 log
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	From      string
+	OutboxDir string
+}
+
+// NewMailer 根据配置创建邮件发送实现，provider 为空时输出到日志
+func NewMailer(cfg MailerConfig) (Mailer, error) {
+	switch cfg.Provider {
+	case "", "log":
+		return LogMailer{}, nil
+	case "file":
+		if cfg.OutboxDir == "" {
+			return nil, fmt.Errorf("mail outbox_dir is required for file provider")
+		}
+		return &FileMailer{Dir: cfg.OutboxDir, From: cfg.From}, nil
+	case "smtp":
+		if cfg.Host == "" || cfg.From == "" {
+			return nil, fmt.Errorf("mail host and from are required for smtp provider")
+		}
+		if cfg.Port == 0 {
+			cfg.Port = 587
+		}
+		return &SMTPMailer{
+			Host:     cfg.Host,
+			Port:     cfg.Port,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			From:     cfg.From,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail provider: %s", cfg.Provider)
+	}
+}
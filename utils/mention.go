@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MentionToken 文本中的一处 @提及，Offset 和 Length 按字符计算
+type MentionToken struct {
+	Offset   int
+	Length   int
+	UserID   uint   // @[id] 形式
+	Nickname string // @昵称 形式，昵称到空白或标点为止
+}
+
+// mentionPattern 匹配 @[123] 或 @昵称，昵称最多 20 个字符
+var mentionPattern = regexp.MustCompile(`@(?:\[([0-9]{1,10})\]|([^\s@\[\]，。！？、；：,.!?;:()（）"'“”‘’<>《》]{1,20}))`)
+
+// ParseMentions 解析文本中的提及，邮箱地址等 @ 前紧跟英文字母或数字的不算提及
+func ParseMentions(text string) []MentionToken {
+	var tokens []MentionToken
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		if start > 0 {
+			previous, _ := utf8.DecodeLastRuneInString(text[:start])
+			if previous < utf8.RuneSelf && (unicode.IsLetter(previous) || unicode.IsDigit(previous)) {
+				continue
+			}
+		}
+
+		token := MentionToken{
+			Offset: utf8.RuneCountInString(text[:start]),
+			Length: utf8.RuneCountInString(text[start:end]),
+		}
+		if match[2] >= 0 {
+			id, err := strconv.ParseUint(text[match[2]:match[3]], 10, 32)
+			if err != nil || id == 0 {
+				continue
+			}
+			token.UserID = uint(id)
+		} else {
+			token.Nickname = text[match[4]:match[5]]
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ModerationResult 内容审核结果
+type ModerationResult struct {
+	Labels []string           `json:"labels"` // 命中的标签
+	Scores map[string]float64 `json:"scores"` // 各标签得分(0~1)
+	Action string             `json:"action"` // 审核服务直接给出的结论（可选）
+}
+
+// MaxScore 返回所有标签中的最高得分
+func (r *ModerationResult) MaxScore() float64 {
+	var max float64
+	for _, score := range r.Scores {
+		if score > max {
+			max = score
+		}
+	}
+	return max
+}
+
+// Moderator 内容审核接口，文本与图片分别审核
+type Moderator interface {
+	Name() string
+	ModerateText(ctx context.Context, text string) (*ModerationResult, error)
+	ModerateImage(ctx context.Context, data []byte, contentType string) (*ModerationResult, error)
+}
+
+// NoopModerator 默认审核实现，全部放行
+type NoopModerator struct{}
+
+func (NoopModerator) Name() string { return "noop" }
+
+func (NoopModerator) ModerateText(ctx context.Context, text string) (*ModerationResult, error) {
+	return &ModerationResult{Scores: map[string]float64{}}, nil
+}
+
+func (NoopModerator) ModerateImage(ctx context.Context, data []byte, contentType string) (*ModerationResult, error) {
+	return &ModerationResult{Scores: map[string]float64{}}, nil
+}
+
+// HTTPModerator 调用本地模型服务的审核实现
+//
+// 请求: POST {Endpoint}，body 为 {"type":"text","text":"..."} 或
+// {"type":"image","content_type":"image/jpeg","data":"<base64>"}
+// 响应: {"labels":["porn"],"scores":{"porn":0.93},"action":"hide"}，action 可省略
+type HTTPModerator struct {
+	Endpoint string
+	Client   *http.Client
+}
+
+// moderationRequest 发送给审核服务的请求体
+type moderationRequest struct {
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Data        string `json:"data,omitempty"`
+}
+
+// NewHTTPModerator 创建HTTP审核客户端
+func NewHTTPModerator(endpoint string, timeout time.Duration) *HTTPModerator {
+	return &HTTPModerator{
+		Endpoint: endpoint,
+		Client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (m *HTTPModerator) Name() string { return "http" }
+
+func (m *HTTPModerator) ModerateText(ctx context.Context, text string) (*ModerationResult, error) {
+	return m.do(ctx, moderationRequest{Type: "text", Text: text})
+}
+
+func (m *HTTPModerator) ModerateImage(ctx context.Context, data []byte, contentType string) (*ModerationResult, error) {
+	return m.do(ctx, moderationRequest{
+		Type:        "image",
+		ContentType: contentType,
+		Data:        base64.StdEncoding.EncodeToString(data),
+	})
+}
+
+func (m *HTTPModerator) do(ctx context.Context, payload moderationRequest) (*ModerationResult, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation service returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result ModerationResult
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	if result.Scores == nil {
+		result.Scores = map[string]float64{}
+	}
+	return &result, nil
+}
+
+// ContentModerator 当前使用的审核实现，默认不做任何拦截
+var ContentModerator Moderator = NoopModerator{}
+
+// NewModerator 根据配置创建审核实现，provider 为空或 noop 时返回 NoopModerator
+func NewModerator(provider, endpoint string, timeout time.Duration) (Moderator, error) {
+	switch provider {
+	case "", "noop":
+		return NoopModerator{}, nil
+	case "http":
+		if endpoint == "" {
+			return nil, fmt.Errorf("moderation endpoint is required for http provider")
+		}
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		return NewHTTPModerator(endpoint, timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown moderation provider: %s", provider)
+	}
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newStubModerationServer 模拟审核服务，记录收到的请求并返回固定结果
+func newStubModerationServer(t *testing.T, status int, response string, received *moderationRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		if received != nil {
+			if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+				t.Errorf("decode request: %v", err)
+			}
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPModeratorText(t *testing.T) {
+	var received moderationRequest
+	server := newStubModerationServer(t, http.StatusOK,
+		`{"labels":["spam"],"scores":{"spam":0.82,"porn":0.1},"action":"hold"}`, &received)
+
+	result, err := NewHTTPModerator(server.URL, time.Second).ModerateText(context.Background(), "买买买")
+	if err != nil {
+		t.Fatalf("ModerateText: %v", err)
+	}
+	if received.Type != "text" || received.Text != "买买买" {
+		t.Errorf("request = %+v, want text payload", received)
+	}
+	if result.Action != "hold" || len(result.Labels) != 1 || result.Labels[0] != "spam" {
+		t.Errorf("result = %+v", result)
+	}
+	if got := result.MaxScore(); got != 0.82 {
+		t.Errorf("MaxScore = %v, want 0.82", got)
+	}
+}
+
+func TestHTTPModeratorImage(t *testing.T) {
+	var received moderationRequest
+	server := newStubModerationServer(t, http.StatusOK, `{"labels":[]}`, &received)
+
+	data := []byte{0xff, 0xd8, 0xff}
+	result, err := NewHTTPModerator(server.URL, time.Second).ModerateImage(context.Background(), data, "image/jpeg")
+	if err != nil {
+		t.Fatalf("ModerateImage: %v", err)
+	}
+	if received.Type != "image" || received.ContentType != "image/jpeg" {
+		t.Errorf("request = %+v, want image payload", received)
+	}
+	if received.Data != base64.StdEncoding.EncodeToString(data) {
+		t.Errorf("data = %q, want base64 of image", received.Data)
+	}
+	// 服务未返回得分时补全为空表，避免调用方判空
+	if result.Scores == nil || result.MaxScore() != 0 {
+		t.Errorf("scores = %v, want empty map", result.Scores)
+	}
+}
+
+func TestHTTPModeratorErrors(t *testing.T) {
+	server := newStubModerationServer(t, http.StatusInternalServerError, `model not loaded`, nil)
+	if _, err := NewHTTPModerator(server.URL, time.Second).ModerateText(context.Background(), "x"); err == nil {
+		t.Error("expected error for non-200 response")
+	}
+
+	server = newStubModerationServer(t, http.StatusOK, `not json`, nil)
+	if _, err := NewHTTPModerator(server.URL, time.Second).ModerateText(context.Background(), "x"); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	if _, err := NewHTTPModerator(slow.URL, 50*time.Millisecond).ModerateText(context.Background(), "x"); err == nil {
+		t.Error("expected timeout error")
+	}
+}
+
+func TestNewModerator(t *testing.T) {
+	for _, provider := range []string{"", "noop"} {
+		moderator, err := NewModerator(provider, "", 0)
+		if err != nil || moderator.Name() != "noop" {
+			t.Errorf("NewModerator(%q) = %v, %v; want noop", provider, moderator, err)
+		}
+	}
+	if _, err := NewModerator("http", "", 0); err == nil {
+		t.Error("expected error when http endpoint is missing")
+	}
+	if moderator, err := NewModerator("http", "http://127.0.0.1:9/moderate", 0); err != nil || moderator.Name() != "http" {
+		t.Errorf("NewModerator(http) = %v, %v", moderator, err)
+	}
+	if _, err := NewModerator("unknown", "", 0); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
@@ -0,0 +1,298 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ExternalIdentity 第三方登录返回的账号信息
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string // 第三方平台的用户唯一标识
+	Email         string
+	EmailVerified bool
+	Name          string
+	AvatarURL     string
+	UnionID       string // 微信 unionid
+	SessionKey    string // 微信 session_key
+}
+
+// AuthExchange 授权码换取账号信息时的参数
+type AuthExchange struct {
+	Code         string
+	RedirectURI  string
+	Nonce        string // OIDC nonce
+	CodeVerifier string // PKCE
+}
+
+// AuthProvider 第三方登录接口
+type AuthProvider interface {
+	Name() string
+	Exchange(ctx context.Context, req AuthExchange) (*ExternalIdentity, error)
+}
+
+// RedirectAuthProvider 需要跳转到第三方授权页的登录方式（OAuth2/OIDC）
+type RedirectAuthProvider interface {
+	AuthProvider
+	AuthCodeURL(state, nonce, redirectURI, codeChallenge string) (string, error)
+}
+
+// AuthProviders 已启用的第三方登录方式，按名称索引
+var AuthProviders = map[string]AuthProvider{}
+
+// PKCEChallenge 根据 code_verifier 计算 S256 code_challenge
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// httpClientOrDefault 未注入 HTTP 客户端时使用带超时的默认客户端
+func httpClientOrDefault(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// oauthToken 令牌端点的响应
+type oauthToken struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeAuthCode 使用授权码换取访问令牌
+func exchangeAuthCode(ctx context.Context, client *http.Client, tokenURL, clientID, clientSecret string, req AuthExchange) (*oauthToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", req.Code)
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+	if req.RedirectURI != "" {
+		form.Set("redirect_uri", req.RedirectURI)
+	}
+	if req.CodeVerifier != "" {
+		form.Set("code_verifier", req.CodeVerifier)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := httpClientOrDefault(client).Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var token oauthToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, string(body))
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("token endpoint error: %s %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned %d", resp.StatusCode)
+	}
+	return &token, nil
+}
+
+// fetchJSON 携带访问令牌请求 JSON 接口，数字按原样保留避免 ID 精度丢失
+func fetchJSON(ctx context.Context, client *http.Client, endpoint, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := httpClientOrDefault(client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", endpoint, resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// claimString 读取字符串或数字类型的字段
+func claimString(data map[string]interface{}, key string) string {
+	if key == "" {
+		return ""
+	}
+	switch v := data[key].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+// claimBool 读取布尔字段，兼容字符串形式的 "true"
+func claimBool(data map[string]interface{}, key string) bool {
+	switch v := data[key].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// OAuth2Provider 通用 OAuth2 登录，通过配置的端点和字段映射获取用户信息
+type OAuth2Provider struct {
+	ProviderName string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scopes       []string
+	Client       *http.Client
+
+	// 用户信息字段映射
+	SubjectField       string
+	EmailField         string
+	EmailVerifiedField string // 为空时视为邮箱未验证
+	NameField          string
+	AvatarField        string
+
+	// EmailsURL GitHub 风格的邮箱列表接口，用于获取已验证的主邮箱
+	EmailsURL string
+}
+
+func (p *OAuth2Provider) Name() string { return p.ProviderName }
+
+func (p *OAuth2Provider) AuthCodeURL(state, nonce, redirectURI, codeChallenge string) (string, error) {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientID)
+	params.Set("redirect_uri", redirectURI)
+	params.Set("state", state)
+	if len(p.Scopes) > 0 {
+		params.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	if codeChallenge != "" {
+		params.Set("code_challenge", codeChallenge)
+		params.Set("code_challenge_method", "S256")
+	}
+	return appendQuery(p.AuthURL, params), nil
+}
+
+func (p *OAuth2Provider) Exchange(ctx context.Context, req AuthExchange) (*ExternalIdentity, error) {
+	token, err := exchangeAuthCode(ctx, p.Client, p.TokenURL, p.ClientID, p.ClientSecret, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var info map[string]interface{}
+	if err := fetchJSON(ctx, p.Client, p.UserInfoURL, token.AccessToken, &info); err != nil {
+		return nil, err
+	}
+
+	identity := &ExternalIdentity{
+		Provider:      p.ProviderName,
+		Subject:       claimString(info, p.SubjectField),
+		Email:         claimString(info, p.EmailField),
+		EmailVerified: claimBool(info, p.EmailVerifiedField),
+		Name:          claimString(info, p.NameField),
+		AvatarURL:     claimString(info, p.AvatarField),
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("userinfo response has no subject")
+	}
+	if identity.Name == "" {
+		identity.Name = claimString(info, "login")
+	}
+
+	if p.EmailsURL != "" {
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if err := fetchJSON(ctx, p.Client, p.EmailsURL, token.AccessToken, &emails); err == nil {
+			for _, item := range emails {
+				if item.Primary && item.Verified {
+					identity.Email = item.Email
+					identity.EmailVerified = true
+					break
+				}
+			}
+		}
+	}
+	return identity, nil
+}
+
+// NewGitHubProvider 创建 GitHub 登录，baseURL/apiURL 为空时使用 github.com
+func NewGitHubProvider(clientID, clientSecret, baseURL, apiURL string, client *http.Client) *OAuth2Provider {
+	if baseURL == "" {
+		baseURL = "https://github.com"
+	}
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	apiURL = strings.TrimRight(apiURL, "/")
+
+	return &OAuth2Provider{
+		ProviderName: "github",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		AuthURL:      baseURL + "/login/oauth/authorize",
+		TokenURL:     baseURL + "/login/oauth/access_token",
+		UserInfoURL:  apiURL + "/user",
+		EmailsURL:    apiURL + "/user/emails",
+		Scopes:       []string{"read:user", "user:email"},
+		Client:       client,
+		SubjectField: "id",
+		EmailField:   "email",
+		NameField:    "name",
+		AvatarField:  "avatar_url",
+	}
+}
+
+// appendQuery 在已有查询参数的地址后追加参数
+func appendQuery(endpoint string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	return endpoint + separator + params.Encode()
+}
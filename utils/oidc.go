@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// oidcDiscovery OpenID Provider 元数据
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey JWKS 中的单个公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwksRefreshInterval 遇到未知 kid 时重新拉取 JWKS 的最小间隔
+const jwksRefreshInterval = time.Minute
+
+// OIDCProvider 标准 OpenID Connect 登录，端点通过 {Issuer}/.well-known/openid-configuration 发现
+type OIDCProvider struct {
+	ProviderName string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Client       *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+func (p *OIDCProvider) Name() string { return p.ProviderName }
+
+// discover 获取并缓存 Provider 元数据
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var doc oidcDiscovery
+	endpoint := strings.TrimRight(p.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := fetchJSON(ctx, p.Client, endpoint, "", &doc); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(doc.Issuer, "/") != strings.TrimRight(p.Issuer, "/") {
+		return nil, fmt.Errorf("oidc issuer mismatch: %s", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document is incomplete")
+	}
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+func (p *OIDCProvider) AuthCodeURL(state, nonce, redirectURI, codeChallenge string) (string, error) {
+	doc, err := p.discover(context.Background())
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientID)
+	params.Set("redirect_uri", redirectURI)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	if codeChallenge != "" {
+		params.Set("code_challenge", codeChallenge)
+		params.Set("code_challenge_method", "S256")
+	}
+	return appendQuery(doc.AuthorizationEndpoint, params), nil
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, req AuthExchange) (*ExternalIdentity, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := exchangeAuthCode(ctx, p.Client, doc.TokenEndpoint, p.ClientID, p.ClientSecret, req)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	claims, err := p.verifyIDToken(ctx, doc, token.IDToken, req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &ExternalIdentity{
+		Provider:      p.ProviderName,
+		Subject:       claimString(claims, "sub"),
+		Email:         claimString(claims, "email"),
+		EmailVerified: claimBool(claims, "email_verified"),
+		Name:          claimString(claims, "name"),
+		AvatarURL:     claimString(claims, "picture"),
+	}
+
+	// ID Token 中没有邮箱时从 userinfo 补充，sub 必须一致
+	if identity.Email == "" && doc.UserInfoEndpoint != "" {
+		var info map[string]interface{}
+		if err := fetchJSON(ctx, p.Client, doc.UserInfoEndpoint, token.AccessToken, &info); err == nil &&
+			claimString(info, "sub") == identity.Subject {
+			identity.Email = claimString(info, "email")
+			identity.EmailVerified = claimBool(info, "email_verified")
+			if identity.Name == "" {
+				identity.Name = claimString(info, "name")
+			}
+			if identity.AvatarURL == "" {
+				identity.AvatarURL = claimString(info, "picture")
+			}
+		}
+	}
+	return identity, nil
+}
+
+// verifyIDToken 校验 ID Token 的签名、签发者、受众、有效期和 nonce
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, doc *oidcDiscovery, rawToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.Alg() {
+		case "RS256", "ES256":
+		default:
+			return nil, fmt.Errorf("unexpected id_token alg %s", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, doc, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if claimString(claims, "iss") != doc.Issuer {
+		return nil, errors.New("id_token issuer mismatch")
+	}
+	if !audienceContains(claims["aud"], p.ClientID) {
+		return nil, errors.New("id_token audience mismatch")
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("id_token has no exp")
+	}
+	if nonce != "" && claimString(claims, "nonce") != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+	if claimString(claims, "sub") == "" {
+		return nil, errors.New("id_token has no sub")
+	}
+	return claims, nil
+}
+
+// audienceContains aud 可能是字符串或字符串数组
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// publicKey 按 kid 查找签名公钥，找不到时重新拉取 JWKS（支持 Provider 轮换密钥）
+func (p *OIDCProvider) publicKey(ctx context.Context, doc *oidcDiscovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if p.keys != nil && time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown id_token kid %q", kid)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := fetchJSON(ctx, p.Client, doc.JWKSURI, "", &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, item := range set.Keys {
+		key, err := parseJSONWebKey(item)
+		if err != nil {
+			continue
+		}
+		keys[item.Kid] = key
+	}
+	p.keys = keys
+	p.keysFetched = time.Now()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown id_token kid %q", kid)
+}
+
+// parseJSONWebKey 解析 RSA 或 P-256 公钥
+func parseJSONWebKey(key jsonWebKey) (interface{}, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch key.Kty {
+	case "RSA":
+		n, err := decode(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(key.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if key.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", key.Crv)
+		}
+		x, err := decode(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", key.Kty)
+}
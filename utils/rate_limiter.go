@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/go-redis/redis"
+)
+
+// 限流维度
+const (
+	RateLimitByUser = "user" // 已登录按用户，未登录退化为IP
+	RateLimitByIP   = "ip"
+)
+
+// RateLimitPolicy 令牌桶限流策略
+type RateLimitPolicy struct {
+	Limit  int           // 每个周期补充的令牌数
+	Period time.Duration // 补充周期
+	Burst  int           // 桶容量，允许的突发请求数
+	KeyBy  string        // user 或 ip
+}
+
+// RateLimitResult 一次限流判定的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int           // 桶容量
+	Remaining  int           // 剩余令牌数
+	Reset      time.Duration // 令牌桶补满所需时间
+	RetryAfter time.Duration // 被拒绝时距离下一个令牌的时间
+}
+
+var (
+	// RateLimitEnabled 是否启用限流
+	RateLimitEnabled = true
+	// RateLimitPolicies 按名称配置的限流策略
+	RateLimitPolicies = map[string]RateLimitPolicy{}
+)
+
+// tokenBucketScript 令牌桶算法，时间取自 Redis 保证多实例一致
+// KEYS[1] 桶; ARGV[1] 每毫秒补充的令牌数; ARGV[2] 桶容量; ARGV[3] 本次消耗
+// 返回 {是否允许, 剩余令牌, 重试等待毫秒, 补满所需毫秒}
+var tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+else
+	retry = math.ceil((cost - tokens) / rate)
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate) + 1000)
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+// AllowRate 从指定的令牌桶中取一个令牌
+func AllowRate(key string, policy RateLimitPolicy) (*RateLimitResult, error) {
+	if policy.Limit <= 0 || policy.Period <= 0 {
+		return nil, fmt.Errorf("invalid rate limit policy")
+	}
+	burst := policy.Burst
+	if burst <= 0 {
+		burst = policy.Limit
+	}
+	ratePerMs := float64(policy.Limit) / float64(policy.Period/time.Millisecond)
+
+	values, err := tokenBucketScript.Run(global.RedisDB, []string{key}, ratePerMs, burst, 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	items, ok := values.([]interface{})
+	if !ok || len(items) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	nums := make([]int64, 4)
+	for i, item := range items {
+		n, ok := item.(int64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected rate limit script result: %v", values)
+		}
+		nums[i] = n
+	}
+
+	return &RateLimitResult{
+		Allowed:    nums[0] == 1,
+		Limit:      burst,
+		Remaining:  int(nums[1]),
+		RetryAfter: time.Duration(nums[2]) * time.Millisecond,
+		Reset:      time.Duration(nums[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+	"github.com/go-redis/redis"
+)
+
+// StreamEvent 推送给客户端的实时事件
+type StreamEvent struct {
+	ID      string          `json:"id"`                 // 历史记录中的消息ID，断线重连时作为 Last-Event-ID
+	Channel string          `json:"channel"`            // user:<id>、article:<id> 或 author:<id>
+	Type    string          `json:"type"`               // 事件类型
+	ActorID uint            `json:"actor_id,omitempty"` // 触发事件的用户，连接据此过滤拉黑和静音的用户
+	Data    json.RawMessage `json:"data"`
+}
+
+// 实时推送使用的 Redis 键，StartStreamHub 启动时按配置设置
+var (
+	streamPubSubChannel       = "stream:events"
+	streamHistoryKey          = "stream:history"
+	streamHistorySize   int64 = 10000
+)
+
+// streamIDPattern Redis Stream 消息ID格式
+var streamIDPattern = regexp.MustCompile(`^[0-9]+-[0-9]+$`)
+
+// 频道命名
+func UserStreamChannel(userID uint) string       { return fmt.Sprintf("user:%d", userID) }
+func ArticleStreamChannel(articleID uint) string { return fmt.Sprintf("article:%d", articleID) }
+func AuthorStreamChannel(authorID uint) string   { return fmt.Sprintf("author:%d", authorID) }
+
+// PublishStreamEvent 写入历史记录并通过 Redis 发布，所有实例上订阅了该频道的连接都会收到
+func PublishStreamEvent(channel, eventType string, actorID uint, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	id, err := global.RedisDB.XAdd(&redis.XAddArgs{
+		Stream:       streamHistoryKey,
+		MaxLenApprox: streamHistorySize,
+		ID:           "*",
+		Values: map[string]interface{}{
+			"channel": channel,
+			"type":    eventType,
+			"actor":   actorID,
+			"data":    string(payload),
+		},
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	message, err := json.Marshal(StreamEvent{ID: id, Channel: channel, Type: eventType, ActorID: actorID, Data: payload})
+	if err != nil {
+		return err
+	}
+	return global.RedisDB.Publish(streamPubSubChannel, string(message)).Err()
+}
+
+// StreamEventsSince 读取 lastID 之后、match 返回 true 的历史事件，最多 limit 条，用于断线续传
+func StreamEventsSince(lastID string, limit int, match func(channel string) bool) ([]StreamEvent, error) {
+	events := make([]StreamEvent, 0)
+	if !streamIDPattern.MatchString(lastID) {
+		return events, nil
+	}
+
+	start := lastID
+	for len(events) < limit {
+		messages, err := global.RedisDB.XRangeN(streamHistoryKey, start, "+", 500).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, message := range messages {
+			// XRANGE 包含起始ID，跳过已经收到的消息
+			if message.ID == start {
+				continue
+			}
+			event := streamEventFromValues(message.ID, message.Values)
+			if match(event.Channel) {
+				events = append(events, event)
+				if len(events) >= limit {
+					break
+				}
+			}
+		}
+		if len(messages) < 500 {
+			break
+		}
+		start = messages[len(messages)-1].ID
+	}
+	return events, nil
+}
+
+func streamEventFromValues(id string, values map[string]interface{}) StreamEvent {
+	event := StreamEvent{ID: id}
+	event.Channel, _ = values["channel"].(string)
+	event.Type, _ = values["type"].(string)
+	if actor, ok := values["actor"].(string); ok {
+		actorID, _ := strconv.ParseUint(actor, 10, 32)
+		event.ActorID = uint(actorID)
+	}
+	if data, ok := values["data"].(string); ok {
+		event.Data = json.RawMessage(data)
+	}
+	return event
+}
+
+// StreamSubscriber 一个客户端连接的订阅
+type StreamSubscriber struct {
+	Events chan StreamEvent
+
+	mu       sync.RWMutex
+	channels map[string]bool
+}
+
+// Subscribe 订阅频道
+func (s *StreamSubscriber) Subscribe(channel string) {
+	s.mu.Lock()
+	s.channels[channel] = true
+	s.mu.Unlock()
+}
+
+// Unsubscribe 取消订阅频道
+func (s *StreamSubscriber) Unsubscribe(channel string) {
+	s.mu.Lock()
+	delete(s.channels, channel)
+	s.mu.Unlock()
+}
+
+// Subscribed 是否订阅了频道
+func (s *StreamSubscriber) Subscribed(channel string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.channels[channel]
+}
+
+// Count 已订阅的频道数
+func (s *StreamSubscriber) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.channels)
+}
+
+// Close 连接断开时移除订阅
+func (s *StreamSubscriber) Close() {
+	streamSubscribers.Delete(s)
+}
+
+// streamSubscribers 本实例上的全部连接
+var streamSubscribers sync.Map
+
+// NewStreamSubscriber 注册一个连接，buffer 为未发送事件的缓冲数
+func NewStreamSubscriber(buffer int, channels ...string) *StreamSubscriber {
+	s := &StreamSubscriber{Events: make(chan StreamEvent, buffer), channels: map[string]bool{}}
+	for _, channel := range channels {
+		s.channels[channel] = true
+	}
+	streamSubscribers.Store(s, true)
+	return s
+}
+
+// dispatchStreamEvent 分发给本实例上订阅了该频道的连接
+func dispatchStreamEvent(event StreamEvent) {
+	streamSubscribers.Range(func(key, _ interface{}) bool {
+		s := key.(*StreamSubscriber)
+		if !s.Subscribed(event.Channel) {
+			return true
+		}
+		select {
+		case s.Events <- event:
+		default:
+			// 客户端处理过慢时丢弃，重连后可通过 Last-Event-ID 补齐
+		}
+		return true
+	})
+}
+
+// StartStreamHub 订阅 Redis 频道并把事件分发给本实例的连接，连接断开后自动重新订阅
+func StartStreamHub(pubsubChannel, historyKey string, historySize int64) {
+	if pubsubChannel != "" {
+		streamPubSubChannel = pubsubChannel
+	}
+	if historyKey != "" {
+		streamHistoryKey = historyKey
+	}
+	if historySize > 0 {
+		streamHistorySize = historySize
+	}
+
+	go func() {
+		for {
+			pubsub := global.RedisDB.Subscribe(streamPubSubChannel)
+			for {
+				message, err := pubsub.ReceiveMessage()
+				if err != nil {
+					log.Printf("实时推送订阅中断: %v", err)
+					break
+				}
+				var event StreamEvent
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					continue
+				}
+				dispatchStreamEvent(event)
+			}
+			pubsub.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+}
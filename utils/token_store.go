@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/appabin/greenbook/global"
+)
+
+// ErrRefreshTokenReused 已轮换过的刷新令牌被再次使用，整个会话会被吊销
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// consumeRefreshScript 当会话当前的刷新令牌与传入的 jti 一致时删除并返回 1，否则返回 0
+const consumeRefreshScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 1
+end
+return 0
+`
+
+func denylistKey(jti string) string {
+	return fmt.Sprintf("jwt:denylist:%s", jti)
+}
+
+func revokedSessionKey(sessionID string) string {
+	return fmt.Sprintf("jwt:session:revoked:%s", sessionID)
+}
+
+func sessionRefreshKey(sessionID string) string {
+	return fmt.Sprintf("jwt:session:refresh:%s", sessionID)
+}
+
+// RevokeToken 将令牌加入黑名单，直到其自然过期
+func RevokeToken(claims *TokenClaims) error {
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	return global.RedisDB.Set(denylistKey(claims.Id), "1", ttl).Err()
+}
+
+// RevokeSession 吊销整个登录会话，该会话签发的访问令牌和刷新令牌全部失效
+func RevokeSession(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	pipe := global.RedisDB.TxPipeline()
+	pipe.Set(revokedSessionKey(sessionID), "1", RefreshTokenTTL)
+	pipe.Del(sessionRefreshKey(sessionID))
+	_, err := pipe.Exec()
+	return err
+}
+
+// IsTokenRevoked 检查令牌本身或其所属会话是否已被吊销
+func IsTokenRevoked(claims *TokenClaims) (bool, error) {
+	keys := []string{denylistKey(claims.Id)}
+	if claims.SessionID != "" {
+		keys = append(keys, revokedSessionKey(claims.SessionID))
+	}
+	count, err := global.RedisDB.Exists(keys...).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// StoreRefreshToken 记录会话当前有效的刷新令牌
+func StoreRefreshToken(pair *TokenPair) error {
+	return global.RedisDB.Set(sessionRefreshKey(pair.SessionID), pair.RefreshID, RefreshTokenTTL).Err()
+}
+
+// ConsumeRefreshToken 校验刷新令牌是否为会话当前有效的令牌，并使其失效
+// 若令牌已被轮换过（重放），吊销整个会话并返回 ErrRefreshTokenReused
+func ConsumeRefreshToken(claims *TokenClaims) error {
+	revoked, err := IsTokenRevoked(claims)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return errors.New("refresh token revoked")
+	}
+
+	// 比较并删除需要原子执行，避免并发刷新时同一令牌被使用两次
+	consumed, err := global.RedisDB.Eval(consumeRefreshScript, []string{sessionRefreshKey(claims.SessionID)}, claims.Id).Int()
+	if err != nil {
+		return err
+	}
+	if consumed == 0 {
+		RevokeSession(claims.SessionID)
+		return ErrRefreshTokenReused
+	}
+
+	return RevokeToken(claims)
+}
+
+// ErrActionTokenUsed 一次性令牌已被使用或已作废
+var ErrActionTokenUsed = errors.New("action token already used")
+
+func actionTokenKey(jti string) string {
+	return fmt.Sprintf("jwt:action:%s", jti)
+}
+
+// GenerateActionToken 签发一次性令牌（邮箱验证、找回密码），binding 用于绑定签发时的状态
+func GenerateActionToken(userID uint, tokenType, binding string, ttl time.Duration) (string, error) {
+	token, jti, err := signToken(TokenClaims{
+		UserID:  fmt.Sprintf("%d", userID),
+		Type:    tokenType,
+		Binding: binding,
+	}, ttl)
+	if err != nil {
+		return "", err
+	}
+	if err := global.RedisDB.Set(actionTokenKey(jti), "1", ttl).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeActionToken 验证一次性令牌并使其失效，同一令牌只能成功使用一次
+func ConsumeActionToken(token, tokenType string) (*TokenClaims, error) {
+	claims, err := ParseToken(token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := global.RedisDB.Del(actionTokenKey(claims.Id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, ErrActionTokenUsed
+	}
+	return claims, nil
+}
+
+// StreamTicketTTL 实时推送票据的有效期
+const StreamTicketTTL = 30 * time.Second
+
+// GenerateStreamTicket 签发建立实时推送连接的一次性票据。EventSource 和 WebSocket 无法设置请求头，
+// 票据只能放在查询参数中，有效期短且只能使用一次，即使被记录在访问日志中也无法重放；
+// 票据沿用访问令牌的会话，会话被吊销后票据同时失效
+func GenerateStreamTicket(userID uint, sessionID string) (string, error) {
+	token, jti, err := signToken(TokenClaims{
+		UserID:    fmt.Sprintf("%d", userID),
+		Type:      TokenTypeStream,
+		SessionID: sessionID,
+	}, StreamTicketTTL)
+	if err != nil {
+		return "", err
+	}
+	if err := global.RedisDB.Set(actionTokenKey(jti), "1", StreamTicketTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ValidateActionToken 验证一次性令牌但不使其失效，用于允许多次尝试的场景
+func ValidateActionToken(token, tokenType string) (*TokenClaims, error) {
+	claims, err := ParseToken(token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := global.RedisDB.Exists(actionTokenKey(claims.Id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, ErrActionTokenUsed
+	}
+	return claims, nil
+}
+
+// RevokeActionToken 使一次性令牌失效，返回是否由本次调用作废（并发使用时只有一方成功）
+func RevokeActionToken(claims *TokenClaims) (bool, error) {
+	deleted, err := global.RedisDB.Del(actionTokenKey(claims.Id)).Result()
+	return deleted > 0, err
+}
+
+// PasswordFingerprint 密码哈希的指纹，修改密码后之前签发的找回密码令牌随之失效
+func PasswordFingerprint(passwordHash string) string {
+	sum := sha256.Sum256([]byte(passwordHash))
+	return hex.EncodeToString(sum[:8])
+}
@@ -1,14 +1,16 @@
 package utils
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 // WeChatSessionResponse 微信会话响应结构
@@ -20,57 +22,206 @@ type WeChatSessionResponse struct {
 	ErrMsg     string `json:"errmsg"`
 }
 
-// GetWeChatSession 通过code获取微信会话信息
-func GetWeChatSession(appID, appSecret, code string) (*WeChatSessionResponse, error) {
-	url := fmt.Sprintf("https://api.weixin.qq.com/sns/jscode2session?appid=%s&secret=%s&js_code=%s&grant_type=authorization_code",
-		appID, appSecret, code)
+// wechatSessionURL 微信官方 code2session 接口
+const wechatSessionURL = "https://api.weixin.qq.com/sns/jscode2session"
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// WeChatMiniProvider 微信小程序登录，通过 code2session 获取 openid
+type WeChatMiniProvider struct {
+	AppID           string
+	AppSecret       string
+	SessionURL      string        // 为空时使用微信官方地址，测试时可指向本地模拟服务
+	WatermarkMaxAge time.Duration // 加密数据水印的有效期，0 表示不校验时间戳
+	Client          *http.Client  // 为空时使用 WeChatHTTPClient
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// WeChatHTTPClient 调用微信接口默认使用的 HTTP 客户端，为空时使用带超时的默认客户端，
+// 测试时可替换为指向模拟服务的客户端
+var WeChatHTTPClient *http.Client
+
+func (p *WeChatMiniProvider) Name() string { return "wechat" }
+
+// Session 通过code获取微信会话信息
+func (p *WeChatMiniProvider) Session(ctx context.Context, code string) (*WeChatSessionResponse, error) {
+	endpoint := p.SessionURL
+	if endpoint == "" {
+		endpoint = wechatSessionURL
 	}
+	params := url.Values{}
+	params.Set("appid", p.AppID)
+	params.Set("secret", p.AppSecret)
+	params.Set("js_code", code)
+	params.Set("grant_type", "authorization_code")
 
+	client := p.Client
+	if client == nil {
+		client = WeChatHTTPClient
+	}
 	var sessionRes WeChatSessionResponse
-	if err := json.Unmarshal(body, &sessionRes); err != nil {
+	if err := fetchJSON(ctx, client, appendQuery(endpoint, params), "", &sessionRes); err != nil {
 		return nil, err
 	}
-
 	if sessionRes.ErrCode != 0 {
 		return nil, errors.New(sessionRes.ErrMsg)
 	}
-
+	if sessionRes.OpenID == "" {
+		return nil, errors.New("code2session response has no openid")
+	}
 	return &sessionRes, nil
 }
 
-// DecryptWeChatData 解密微信加密数据
-func DecryptWeChatData(sessionKey, encryptedData, iv string) (map[string]interface{}, error) {
-	// Base64解码
-	key, _ := base64.StdEncoding.DecodeString(sessionKey)
-	cipherText, _ := base64.StdEncoding.DecodeString(encryptedData)
-	ivBytes, _ := base64.StdEncoding.DecodeString(iv)
+func (p *WeChatMiniProvider) Exchange(ctx context.Context, req AuthExchange) (*ExternalIdentity, error) {
+	session, err := p.Session(ctx, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalIdentity{
+		Provider:   p.Name(),
+		Subject:    session.OpenID,
+		UnionID:    session.UnionID,
+		SessionKey: session.SessionKey,
+	}, nil
+}
 
-	block, err := aes.NewCipher(key)
+// GetWeChatSession 通过code获取微信会话信息，HTTP 客户端见 WeChatHTTPClient
+func GetWeChatSession(appID, appSecret, code string) (*WeChatSessionResponse, error) {
+	provider := &WeChatMiniProvider{AppID: appID, AppSecret: appSecret}
+	return provider.Session(context.Background(), code)
+}
+
+// 微信加密数据解密错误
+var (
+	ErrWeChatInvalidBase64     = errors.New("wechat: invalid base64 input")
+	ErrWeChatInvalidKey        = errors.New("wechat: session key must be 16 bytes")
+	ErrWeChatInvalidIV         = errors.New("wechat: iv must be 16 bytes")
+	ErrWeChatInvalidCiphertext = errors.New("wechat: ciphertext length is not a positive multiple of the block size")
+	ErrWeChatInvalidPadding    = errors.New("wechat: invalid PKCS#7 padding")
+	ErrWeChatInvalidPayload    = errors.New("wechat: decrypted payload is not valid JSON")
+	ErrWeChatWatermarkAppID    = errors.New("wechat: watermark appid mismatch")
+	ErrWeChatWatermarkExpired  = errors.New("wechat: watermark timestamp out of range")
+)
+
+// watermarkClockSkew 水印时间戳允许超前服务器时间的范围
+const watermarkClockSkew = time.Minute
+
+// WeChatWatermark 加密数据中的水印，用于校验数据来源和时效
+type WeChatWatermark struct {
+	AppID     string `json:"appid"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// WeChatPhoneInfo getPhoneNumber 解密后的手机号信息
+type WeChatPhoneInfo struct {
+	PhoneNumber     string          `json:"phoneNumber"`
+	PurePhoneNumber string          `json:"purePhoneNumber"`
+	CountryCode     string          `json:"countryCode"`
+	Watermark       WeChatWatermark `json:"watermark"`
+}
+
+// WeChatUserInfo getUserProfile 解密后的用户信息
+type WeChatUserInfo struct {
+	OpenID    string          `json:"openId"`
+	UnionID   string          `json:"unionId"`
+	NickName  string          `json:"nickName"`
+	AvatarURL string          `json:"avatarUrl"`
+	Gender    uint8           `json:"gender"`
+	Watermark WeChatWatermark `json:"watermark"`
+}
+
+// decodeWeChatBase64 解码单个字段，失败时返回 ErrWeChatInvalidBase64
+func decodeWeChatBase64(field, value string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrWeChatInvalidBase64, field)
+	}
+	return data, nil
+}
+
+// decryptWeChatPayload AES-128-CBC 解密并校验 PKCS#7 填充
+func decryptWeChatPayload(sessionKey, encryptedData, iv string) ([]byte, error) {
+	key, err := decodeWeChatBase64("session_key", sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := decodeWeChatBase64("encryptedData", encryptedData)
+	if err != nil {
+		return nil, err
+	}
+	ivBytes, err := decodeWeChatBase64("iv", iv)
 	if err != nil {
 		return nil, err
 	}
 
-	mode := cipher.NewCBCDecrypter(block, ivBytes)
-	mode.CryptBlocks(cipherText, cipherText)
+	if len(key) != aes.BlockSize {
+		return nil, ErrWeChatInvalidKey
+	}
+	if len(ivBytes) != aes.BlockSize {
+		return nil, ErrWeChatInvalidIV
+	}
+	if len(cipherText) == 0 || len(cipherText)%aes.BlockSize != 0 {
+		return nil, ErrWeChatInvalidCiphertext
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrWeChatInvalidKey
+	}
+	plain := make([]byte, len(cipherText))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plain, cipherText)
+
+	// 去除填充，填充的每个字节都必须等于填充长度
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, ErrWeChatInvalidPadding
+	}
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return nil, ErrWeChatInvalidPadding
+		}
+	}
+	return plain[:len(plain)-pad], nil
+}
 
-	// 去除填充
-	pad := int(cipherText[len(cipherText)-1])
-	decrypted := cipherText[:len(cipherText)-pad]
+// DecryptWeChatData 解密微信加密数据，不校验水印
+func DecryptWeChatData(sessionKey, encryptedData, iv string) (map[string]interface{}, error) {
+	decrypted, err := decryptWeChatPayload(sessionKey, encryptedData, iv)
+	if err != nil {
+		return nil, err
+	}
 
 	var result map[string]interface{}
 	if err := json.Unmarshal(decrypted, &result); err != nil {
-		return nil, err
+		return nil, ErrWeChatInvalidPayload
 	}
 
 	return result, nil
 }
+
+// DecryptData 解密微信加密数据到 v，并校验水印中的 appid 和时间戳
+func (p *WeChatMiniProvider) DecryptData(sessionKey, encryptedData, iv string, v interface{}) error {
+	decrypted, err := decryptWeChatPayload(sessionKey, encryptedData, iv)
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		Watermark WeChatWatermark `json:"watermark"`
+	}
+	if err := json.Unmarshal(decrypted, &envelope); err != nil {
+		return ErrWeChatInvalidPayload
+	}
+	if envelope.Watermark.AppID != p.AppID {
+		return ErrWeChatWatermarkAppID
+	}
+	if p.WatermarkMaxAge > 0 {
+		issuedAt := time.Unix(envelope.Watermark.Timestamp, 0)
+		now := time.Now()
+		if issuedAt.Before(now.Add(-p.WatermarkMaxAge)) || issuedAt.After(now.Add(watermarkClockSkew)) {
+			return ErrWeChatWatermarkExpired
+		}
+	}
+
+	if err := json.Unmarshal(decrypted, v); err != nil {
+		return ErrWeChatInvalidPayload
+	}
+	return nil
+}
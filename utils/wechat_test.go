@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testWeChatKey = []byte("0123456789abcdef")
+	testWeChatIV  = []byte("fedcba9876543210")
+)
+
+// encryptWeChatRaw 按微信的方式 AES-128-CBC 加密，不做填充，plain 的长度必须是块大小的整数倍
+func encryptWeChatRaw(t *testing.T, plain []byte) string {
+	t.Helper()
+	block, err := aes.NewCipher(testWeChatKey)
+	if err != nil {
+		t.Fatalf("new cipher: %v", err)
+	}
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, testWeChatIV).CryptBlocks(out, plain)
+	return base64.StdEncoding.EncodeToString(out)
+}
+
+// encryptWeChatData 使用 PKCS#7 填充后加密
+func encryptWeChatData(t *testing.T, plain []byte) string {
+	t.Helper()
+	pad := aes.BlockSize - len(plain)%aes.BlockSize
+	return encryptWeChatRaw(t, append(plain, bytes.Repeat([]byte{byte(pad)}, pad)...))
+}
+
+func b64(data []byte) string { return base64.StdEncoding.EncodeToString(data) }
+
+func TestDecryptWeChatPayloadErrors(t *testing.T) {
+	key, iv := b64(testWeChatKey), b64(testWeChatIV)
+	valid := encryptWeChatData(t, []byte(`{"a":1}`))
+
+	// 最后一个字节分别为 0、大于块大小和与填充长度不一致
+	zeroPad := append(bytes.Repeat([]byte{'x'}, 15), 0)
+	largePad := append(bytes.Repeat([]byte{'x'}, 15), 17)
+	mixedPad := append(bytes.Repeat([]byte{'x'}, 13), 1, 3, 3)
+
+	tests := []struct {
+		name          string
+		key, iv, data string
+		want          error
+	}{
+		{"key not base64", "%%%", iv, valid, ErrWeChatInvalidBase64},
+		{"iv not base64", key, "%%%", valid, ErrWeChatInvalidBase64},
+		{"data not base64", key, iv, "%%%", ErrWeChatInvalidBase64},
+		{"short key", b64(testWeChatKey[:8]), iv, valid, ErrWeChatInvalidKey},
+		{"short iv", key, b64(testWeChatIV[:8]), valid, ErrWeChatInvalidIV},
+		{"empty ciphertext", key, iv, "", ErrWeChatInvalidCiphertext},
+		{"shorter than a block", key, iv, b64(make([]byte, 8)), ErrWeChatInvalidCiphertext},
+		{"not a multiple of block size", key, iv, b64(make([]byte, 20)), ErrWeChatInvalidCiphertext},
+		{"zero padding", key, iv, encryptWeChatRaw(t, zeroPad), ErrWeChatInvalidPadding},
+		{"padding larger than block", key, iv, encryptWeChatRaw(t, largePad), ErrWeChatInvalidPadding},
+		{"inconsistent padding", key, iv, encryptWeChatRaw(t, mixedPad), ErrWeChatInvalidPadding},
+		{"payload not json", key, iv, encryptWeChatData(t, []byte("not json")), ErrWeChatInvalidPayload},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := DecryptWeChatData(tc.key, tc.data, tc.iv); !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	result, err := DecryptWeChatData(key, valid, iv)
+	if err != nil || result["a"] != float64(1) {
+		t.Errorf("DecryptWeChatData = %v, %v", result, err)
+	}
+}
+
+func TestWeChatDecryptDataWatermark(t *testing.T) {
+	provider := &WeChatMiniProvider{AppID: "wx-app", WatermarkMaxAge: 5 * time.Minute}
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name      string
+		appID     string
+		timestamp int64
+		maxAge    time.Duration
+		want      error
+	}{
+		{"valid", "wx-app", now, provider.WatermarkMaxAge, nil},
+		{"wrong appid", "wx-other", now, provider.WatermarkMaxAge, ErrWeChatWatermarkAppID},
+		{"old timestamp", "wx-app", now - 600, provider.WatermarkMaxAge, ErrWeChatWatermarkExpired},
+		{"future timestamp", "wx-app", now + 600, provider.WatermarkMaxAge, ErrWeChatWatermarkExpired},
+		{"age not checked", "wx-app", now - 600, 0, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, _ := json.Marshal(WeChatPhoneInfo{
+				PhoneNumber: "+86 13800000000",
+				Watermark:   WeChatWatermark{AppID: tc.appID, Timestamp: tc.timestamp},
+			})
+			p := *provider
+			p.WatermarkMaxAge = tc.maxAge
+
+			var info WeChatPhoneInfo
+			err := p.DecryptData(b64(testWeChatKey), encryptWeChatData(t, payload), b64(testWeChatIV), &info)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			if err == nil && info.PhoneNumber != "+86 13800000000" {
+				t.Errorf("phone = %q", info.PhoneNumber)
+			}
+		})
+	}
+}
+
+// newStubCode2SessionServer 模拟 code2session 接口，校验请求参数并返回固定结果
+func newStubCode2SessionServer(t *testing.T, response string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("appid") != "wx-app" || query.Get("secret") != "wx-secret" ||
+			query.Get("js_code") != "code-1" || query.Get("grant_type") != "authorization_code" {
+			t.Errorf("query = %v", query)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWeChatSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantErr  string
+	}{
+		{"success", `{"openid":"o-1","session_key":"sk","unionid":"u-1"}`, ""},
+		{"errcode", `{"errcode":40029,"errmsg":"invalid code"}`, "invalid code"},
+		{"missing openid", `{"session_key":"sk"}`, "no openid"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newStubCode2SessionServer(t, tc.response)
+			provider := &WeChatMiniProvider{AppID: "wx-app", AppSecret: "wx-secret", SessionURL: server.URL, Client: server.Client()}
+
+			session, err := provider.Session(context.Background(), "code-1")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Session: %v", err)
+			}
+			if session.OpenID != "o-1" || session.SessionKey != "sk" || session.UnionID != "u-1" {
+				t.Errorf("session = %+v", session)
+			}
+		})
+	}
+}
+
+// redirectTransport 把所有请求转发到模拟服务
+type redirectTransport struct{ target *url.URL }
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestGetWeChatSessionUsesHTTPClient(t *testing.T) {
+	server := newStubCode2SessionServer(t, `{"openid":"o-1","session_key":"sk"}`)
+	target, _ := url.Parse(server.URL)
+
+	previous := WeChatHTTPClient
+	WeChatHTTPClient = &http.Client{Transport: redirectTransport{target: target}, Timeout: time.Second}
+	defer func() { WeChatHTTPClient = previous }()
+
+	session, err := GetWeChatSession("wx-app", "wx-secret", "code-1")
+	if err != nil || session.OpenID != "o-1" {
+		t.Errorf("GetWeChatSession = %+v, %v", session, err)
+	}
+}